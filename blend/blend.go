@@ -0,0 +1,122 @@
+// Package blend composites the shapes of the picture demos onto their
+// pictures in ways other than drawing one color over another, like adding the
+// colors up, which changes the kind of pictures the shapes can make
+package blend
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Mode is how the color of a shape is combined with the picture under it
+type Mode uint8
+
+const (
+	// Normal draws the color over the picture, as image/draw does
+	Normal Mode = iota
+	// Add adds the color to the picture, so shapes that overlap get lighter
+	Add
+	// Multiply multiplies the picture by the color, so shapes that overlap
+	// get darker
+	Multiply
+	// Screen multiplies the inverse of the picture by the inverse of the
+	// color, lightening it less than Add does and never past white
+	Screen
+)
+
+// Modes are all the modes, in order
+var Modes = []Mode{Normal, Add, Multiply, Screen}
+
+var names = [...]string{"normal", "add", "multiply", "screen"}
+
+// String is the name of the mode
+func (m Mode) String() string {
+	if int(m) < len(names) {
+		return names[m]
+	}
+	return fmt.Sprintf("Mode(%d)", m)
+}
+
+// Set sets the mode by its name, so it can be a flag
+func (m *Mode) Set(s string) error {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "additive" {
+		s = "add"
+	}
+	for i, name := range names {
+		if s == name {
+			*m = Mode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("no blend mode called %q, only %s", s, strings.Join(names[:], ", "))
+}
+
+// MarshalText writes the mode as its name, as in the parameters of a job
+func (m Mode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText reads the mode from its name
+func (m *Mode) UnmarshalText(text []byte) error {
+	return m.Set(string(text))
+}
+
+// Pixel blends the color onto the pixel p, its 4 bytes of an image.RGBA. The
+// color is premultiplied by its alpha, as color.RGBA is, and counts as much as
+// the coverage, from 0 to 0xffff, so an anti-aliased edge blends a little.
+// The alpha of the pixel is always that of the color drawn over it
+func Pixel(p []byte, c color.RGBA, coverage uint32, m Mode) {
+	sr, sg, sb, sa := c.RGBA()
+	sr, sg, sb, sa = sr*coverage/0xffff, sg*coverage/0xffff, sb*coverage/0xffff, sa*coverage/0xffff
+	p = p[:4:4]
+	da := uint32(p[3]) * 0x101
+	for i, s := range [3]uint32{sr, sg, sb} {
+		d := uint32(p[i]) * 0x101
+		var v uint32
+		switch m {
+		case Add:
+			v = d + s
+		case Multiply:
+			v = s*d/0xffff + s*(0xffff-da)/0xffff + d*(0xffff-sa)/0xffff
+		case Screen:
+			v = s + d - s*d/0xffff
+		default:
+			v = s + d*(0xffff-sa)/0xffff
+		}
+		p[i] = uint8(min(v, 0xffff) >> 8)
+	}
+	p[3] = uint8(min(sa+da*(0xffff-sa)/0xffff, 0xffff) >> 8)
+}
+
+// Layer is a scratch picture the size of the pictures that each shape is drawn
+// onto alone, in opaque white, so the alpha of each pixel is how much of it
+// the shape covers, anti-aliased edges and all. The shape is then blended onto
+// the picture through it, and the layer is cleared for the next
+type Layer struct {
+	// Mask is what the shape is drawn onto
+	Mask *image.RGBA
+}
+
+// NewLayer is a clear layer of the size
+func NewLayer(r image.Rectangle) *Layer {
+	return &Layer{Mask: image.NewRGBA(r)}
+}
+
+// Blend blends the color onto dst through the layer, only in the rectangle,
+// which is around the shape drawn onto it, and clears the rectangle
+func (l *Layer) Blend(dst *image.RGBA, r image.Rectangle, c color.RGBA, m Mode) {
+	r = r.Intersect(dst.Rect).Intersect(l.Mask.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i, j := dst.PixOffset(r.Min.X, y), l.Mask.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i, j = x+1, i+4, j+4 {
+			mask := l.Mask.Pix[j : j+4 : j+4]
+			if mask[3] != 0 {
+				Pixel(dst.Pix[i:i+4], c, uint32(mask[3])*0x101, m)
+				mask[0], mask[1], mask[2], mask[3] = 0, 0, 0, 0
+			}
+		}
+	}
+}
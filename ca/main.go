@@ -0,0 +1,321 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each bit of the rule table
+var MutationRate = 0.02
+
+// PopSize is the size of the population
+var PopSize = 100
+
+// PoolSize is the max size of the pool
+var PoolSize = 20
+
+// Generations is the number of generations to evolve
+var Generations = 100
+
+// Radius is how many cells on either side of a cell its next state depends
+// on, so the rule table has 2^(2*Radius+1) entries
+var Radius = 3
+
+// Width is the number of cells in the lattice, an odd number so there is
+// always a majority
+var Width = 149
+
+// Trials is the number of random lattices each rule is tested on every
+// generation
+var Trials = 100
+
+func main() {
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	flag.IntVar(&Trials, "trials", Trials, "number of random lattices each rule is tested on every generation")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	population := createPopulation()
+
+	var bestOrganism Organism
+	for generation := 1; generation <= Generations; generation++ {
+		// the fitness is noisy, so every generation all the rules, including
+		// the ones kept from the last generation, are tested on the same new
+		// set of lattices
+		lattices := randomLattices(Trials)
+		evaluate(population, lattices)
+		bestOrganism = getBest(population)
+		sofar := time.Since(start)
+		fmt.Printf("Time taken so far: %s | generation: %d | fitness: %.2f | rule: %s\n", sofar, generation, bestOrganism.Fitness, bestOrganism.hex())
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism)
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nBest rule classified %.0f%% of the lattices right:\n  %s\n", bestOrganism.Fitness*100, bestOrganism.hex())
+	fmt.Println("\nHow it classifies a random lattice:")
+	fmt.Print(bestOrganism.spaceTime(randomLattices(1)[0], 60))
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// random lattices, with the density of the ones spread evenly between 0 and
+// 1 so the easy and hard cases are equally likely
+func randomLattices(n int) [][]bool {
+	lattices := make([][]bool, n)
+	for i := range lattices {
+		density := rng.Float64()
+		lattices[i] = make([]bool, Width)
+		for j := range lattices[i] {
+			lattices[i][j] = rng.Float64() < density
+		}
+	}
+	return lattices
+}
+
+// work out the fitness of the population in parallel across the CPUs
+func evaluate(population []Organism, lattices [][]bool) {
+	workers := runtime.NumCPU()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(population); i += workers {
+				population[i].calcFitness(lattices)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness > population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	spread := top[0].Fitness - top[PoolSize].Fitness
+	if spread == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with up to 100 copies of the best
+	for i := 0; i < len(top)-1; i++ {
+		num := int(100 * (top[i].Fitness - top[PoolSize].Fitness) / spread)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation, which is
+// evaluated at the start of the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best rule, to be tested again
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation() (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism()
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness > population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a rule table, the next state of a cell for every possible
+// neighborhood, which is read as a binary number
+type Organism struct {
+	DNA     []bool
+	Fitness float64
+}
+
+// creates an organism with a random rule table. Like the lattices, the
+// density of the ones is spread evenly
+func createOrganism() (organism Organism) {
+	density := rng.Float64()
+	rule := make([]bool, 1<<(2*Radius+1))
+	for i := range rule {
+		rule[i] = rng.Float64() < density
+	}
+	return Organism{
+		DNA:     rule,
+		Fitness: 0,
+	}
+}
+
+// the lattice after one step of the rule, wrapping around at the edges,
+// returning the number of cells set. The padded slice is scratch space for
+// the cells with the wrapped around neighbors on either side
+func (o *Organism) step(cells, next, padded []bool) (ones int) {
+	n := len(cells)
+	copy(padded, cells[n-Radius:])
+	copy(padded[Radius:], cells)
+	copy(padded[Radius+n:], cells[:Radius])
+	mask := len(o.DNA) - 1
+	// the neighborhood of the first cell, updated as it slides along
+	index := 0
+	for _, c := range padded[:2*Radius] {
+		index <<= 1
+		if c {
+			index |= 1
+		}
+	}
+	for i, c := range padded[2*Radius:] {
+		index = (index << 1) & mask
+		if c {
+			index |= 1
+		}
+		next[i] = o.DNA[index]
+		if next[i] {
+			ones++
+		}
+	}
+	return
+}
+
+// run the rule on the lattice for 2 times the width steps, and return true
+// if it ended with all the cells set to the majority of the start
+func (o *Organism) classify(lattice []bool) bool {
+	ones := 0
+	for _, c := range lattice {
+		if c {
+			ones++
+		}
+	}
+	majority := ones*2 > len(lattice)
+	cells := append([]bool(nil), lattice...)
+	next := make([]bool, len(cells))
+	padded := make([]bool, len(cells)+2*Radius)
+	for t := 0; t < 2*len(cells); t++ {
+		ones := o.step(cells, next, padded)
+		cells, next = next, cells
+		// stop early once all the cells are the same and stay that way
+		if (ones == 0 && !o.DNA[0]) || (ones == len(cells) && o.DNA[len(o.DNA)-1]) {
+			break
+		}
+	}
+	for _, c := range cells {
+		if c != majority {
+			return false
+		}
+	}
+	return true
+}
+
+// calculates the fitness of the Organism, which is the fraction of the
+// lattices it classifies right
+func (o *Organism) calcFitness(lattices [][]bool) {
+	right := 0
+	for _, lattice := range lattices {
+		if o.classify(lattice) {
+			right++
+		}
+	}
+	o.Fitness = float64(right) / float64(len(lattices))
+}
+
+// the rule table as hexadecimal, the usual way of writing it
+func (o *Organism) hex() string {
+	var sb strings.Builder
+	for i := 0; i < len(o.DNA); i += 4 {
+		nibble := 0
+		for j := 0; j < 4; j++ {
+			nibble <<= 1
+			if o.DNA[i+j] {
+				nibble |= 1
+			}
+		}
+		fmt.Fprintf(&sb, "%x", nibble)
+	}
+	return sb.String()
+}
+
+// the first steps of the rule on the lattice, one line for every step
+func (o *Organism) spaceTime(lattice []bool, steps int) string {
+	var sb strings.Builder
+	cells := append([]bool(nil), lattice...)
+	next := make([]bool, len(cells))
+	padded := make([]bool, len(cells)+2*Radius)
+	for t := 0; t < steps; t++ {
+		for _, c := range cells {
+			if c {
+				sb.WriteString("#")
+			} else {
+				sb.WriteString(".")
+			}
+		}
+		sb.WriteString("\n")
+		o.step(cells, next, padded)
+		cells, next = next, cells
+	}
+	return sb.String()
+}
+
+// crosses over 2 Organisms
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]bool, len(d1.DNA)),
+		Fitness: 0,
+	}
+	mid := rng.Intn(len(d1.DNA))
+	for i := 0; i < len(d1.DNA); i++ {
+		if i > mid {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism by flipping bits
+func (o *Organism) mutate() {
+	for i := 0; i < len(o.DNA); i++ {
+		if rng.Float64() < MutationRate {
+			o.DNA[i] = !o.DNA[i]
+		}
+	}
+}
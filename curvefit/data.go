@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// Point is a data point to fit the curve to
+type Point struct {
+	X float64
+	Y float64
+}
+
+// noisy points along y = 0.5x^3 - 2x^2 + x + 3, used when there is no file
+func sampleData() (points []Point) {
+	for x := -3.0; x <= 3; x += 0.2 {
+		y := 0.5*x*x*x - 2*x*x + x + 3 + rng.NormFloat64()
+		points = append(points, Point{X: x, Y: y})
+	}
+	return
+}
+
+// read the points from a CSV file, with an x and y in each row, skipping the
+// header row if there is one
+func readData(filePath string) (points []Point, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return
+	}
+	for n, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: need an x and y", n+1)
+		}
+		x, xerr := strconv.ParseFloat(strings.TrimSpace(record[0]), 64)
+		y, yerr := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if xerr != nil || yerr != nil {
+			if n == 0 {
+				// the header
+				continue
+			}
+			return nil, fmt.Errorf("line %d: cannot parse x and y", n+1)
+		}
+		points = append(points, Point{X: x, Y: y})
+	}
+	return
+}
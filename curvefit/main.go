@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sausheong/ga/optimize"
+	"github.com/sausheong/ga/preview"
+	"github.com/sausheong/ga/rng"
+)
+
+// PopSize is the size of the population
+var PopSize = 50
+
+// Generations is the number of generations to evolve
+var Generations = 1000
+
+// Degree is the degree of the polynomial to fit
+var Degree = 3
+
+// Range is the range each coefficient is searched in, from -Range to Range
+var Range = 10.0
+
+// Strategy is the way the coefficients are evolved, either de for
+// differential evolution or cmaes for CMA-ES
+var Strategy = "de"
+
+func main() {
+	dataFile := flag.String("data", "", "CSV file with an x and y on each line")
+	flag.IntVar(&Degree, "degree", Degree, "degree of the polynomial")
+	flag.Float64Var(&Range, "range", Range, "largest size of the coefficients")
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	flag.StringVar(&Strategy, "strategy", Strategy, "de for differential evolution or cmaes for CMA-ES")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	points := sampleData()
+	if *dataFile != "" {
+		var err error
+		points, err = readData(*dataFile)
+		if err != nil {
+			fmt.Println("Cannot read data:", err)
+			return
+		}
+	}
+	if len(points) == 0 {
+		fmt.Println("Cannot fit curve: no data")
+		return
+	}
+
+	// the genome is the coefficients, from the constant term up, and the
+	// fitness is how far the curve is from the points
+	problem := optimize.Problem{
+		F:          func(coefficients []float64) float64 { return meanSquaredError(coefficients, points) },
+		Dimensions: Degree + 1,
+		Min:        -Range,
+		Max:        Range,
+	}
+	var optimizer optimize.Optimizer
+	switch Strategy {
+	case "de":
+		optimizer = optimize.NewDE(problem, PopSize)
+	case "cmaes":
+		optimizer = optimize.NewCMAES(problem, 0)
+	default:
+		fmt.Println("Cannot find strategy:", Strategy)
+		return
+	}
+
+	for generation := 1; generation <= Generations; generation++ {
+		best, fitness := optimizer.Step()
+		if generation%100 == 0 || generation == Generations {
+			sofar := time.Since(start)
+			fmt.Printf("\nTime taken so far: %s | generation: %d | mean squared error: %g\ny = %s\n", sofar, generation, fitness, polynomial(best))
+			img := plot(points, best)
+			save("./fit.png", img)
+			preview.Print(img)
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// the value of the polynomial at x, using Horner's method
+func evaluate(coefficients []float64, x float64) (y float64) {
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = y*x + coefficients[i]
+	}
+	return
+}
+
+// the mean of the squared distances between the curve and the points
+func meanSquaredError(coefficients []float64, points []Point) (mse float64) {
+	for _, p := range points {
+		d := evaluate(coefficients, p.X) - p.Y
+		mse += d * d
+	}
+	return mse / float64(len(points))
+}
+
+// the polynomial written out, with the highest power first
+func polynomial(coefficients []float64) string {
+	terms := make([]string, 0, len(coefficients))
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		switch i {
+		case 0:
+			terms = append(terms, fmt.Sprintf("%.4f", coefficients[i]))
+		case 1:
+			terms = append(terms, fmt.Sprintf("%.4fx", coefficients[i]))
+		default:
+			terms = append(terms, fmt.Sprintf("%.4fx^%d", coefficients[i], i))
+		}
+	}
+	return strings.Replace(strings.Join(terms, " + "), "+ -", "- ", -1)
+}
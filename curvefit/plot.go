@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/llgcode/draw2d/draw2dkit"
+)
+
+// PlotWidth is the width of the plot
+var PlotWidth = 500
+
+// PlotHeight is the height of the plot
+var PlotHeight = 350
+
+// plot the data points and the curve of the polynomial through them
+func plot(points []Point, coefficients []float64) *image.RGBA {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	// leave some room above and below the data for the curve
+	pad := math.Max((maxY-minY)*0.1, 1)
+	minY, maxY = minY-pad, maxY+pad
+	margin := 10.0
+	point := func(x, y float64) (float64, float64) {
+		px := margin + (x-minX)/math.Max(maxX-minX, 1e-9)*(float64(PlotWidth)-2*margin)
+		py := float64(PlotHeight) - margin - (y-minY)/(maxY-minY)*(float64(PlotHeight)-2*margin)
+		return px, py
+	}
+
+	dest := image.NewRGBA(image.Rect(0, 0, PlotWidth, PlotHeight))
+	gc := draw2dimg.NewGraphicContext(dest)
+	gc.SetFillColor(color.White)
+	draw2dkit.Rectangle(gc, 0, 0, float64(PlotWidth), float64(PlotHeight))
+	gc.Fill()
+
+	// the axes, if they are in the plot
+	gc.SetStrokeColor(color.RGBA{200, 200, 200, 255})
+	gc.SetLineWidth(1)
+	if minX <= 0 && maxX >= 0 {
+		gc.MoveTo(point(0, minY))
+		gc.LineTo(point(0, maxY))
+		gc.Stroke()
+	}
+	if minY <= 0 && maxY >= 0 {
+		gc.MoveTo(point(minX, 0))
+		gc.LineTo(point(maxX, 0))
+		gc.Stroke()
+	}
+
+	gc.SetFillColor(color.RGBA{255, 0, 0, 255})
+	for _, p := range points {
+		x, y := point(p.X, p.Y)
+		draw2dkit.Circle(gc, x, y, 3)
+		gc.Fill()
+	}
+
+	// the curve, clipped to the plot
+	gc.SetStrokeColor(color.RGBA{0, 0, 255, 255})
+	gc.SetLineWidth(2)
+	steps := PlotWidth / 2
+	for i := 0; i <= steps; i++ {
+		x := minX + (maxX-minX)*float64(i)/float64(steps)
+		y := math.Max(minY, math.Min(maxY, evaluate(coefficients, x)))
+		if i == 0 {
+			gc.MoveTo(point(x, y))
+		} else {
+			gc.LineTo(point(x, y))
+		}
+	}
+	gc.Stroke()
+	return dest
+}
+
+// save the image
+func save(filePath string, img image.Image) {
+	imgFile, err := os.Create(filePath)
+	if err != nil {
+		fmt.Println("Cannot create file:", err)
+		return
+	}
+	defer imgFile.Close()
+	png.Encode(imgFile, img)
+}
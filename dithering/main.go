@@ -0,0 +1,272 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/preview"
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation
+var MutationRate = 0.001
+
+// PopSize is the size of the population
+var PopSize = 200
+
+// PoolSize is the max size of the pool
+var PoolSize = 30
+
+// BlurRadius is the radius of the box blur used to compare the dithered image
+// with the target, which is roughly how far away the eye sees it from
+var BlurRadius = 2
+
+// FitnessLimit is the fitness of the evolved image we are satisfied with
+var FitnessLimit int64 = 1500
+
+// Generations is the most generations to evolve, or 0 to evolve until the
+// fitness is under FitnessLimit
+var Generations = 0
+
+func main() {
+	targetFile := flag.String("target", "./ml.png", "target image to dither")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve, 0 to evolve until the fitness limit")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	target := blur(grayscale(load(*targetFile)))
+	preview.Print(target)
+	population := createPopulation(target)
+
+	found := false
+	generation := 0
+	for !found && (Generations == 0 || generation < Generations) {
+		generation++
+		bestOrganism := getBest(population)
+		if bestOrganism.Fitness < FitnessLimit {
+			found = true
+		} else {
+			pool := createPool(population, target)
+			population = naturalSelection(pool, population, target)
+			if generation%100 == 0 {
+				sofar := time.Since(start)
+				fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | pool size: %d", sofar, generation, bestOrganism.Fitness, len(pool))
+				save("./evolved.png", bestOrganism.image(target.Rect))
+				fmt.Println()
+				preview.Print(bestOrganism.image(target.Rect))
+			}
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// save the image
+func save(filePath string, img image.Image) {
+	imgFile, err := os.Create(filePath)
+	if err != nil {
+		fmt.Println("Cannot create file:", err)
+		return
+	}
+	defer imgFile.Close()
+	png.Encode(imgFile, img)
+}
+
+// load the image
+func load(filePath string) image.Image {
+	imgFile, err := os.Open(filePath)
+	if err != nil {
+		fmt.Println("Cannot read file:", err)
+		os.Exit(1)
+	}
+	defer imgFile.Close()
+
+	img, _, err := image.Decode(imgFile)
+	if err != nil {
+		fmt.Println("Cannot decode file:", err)
+		os.Exit(1)
+	}
+	return img
+}
+
+// convert the image to grayscale
+func grayscale(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return gray
+}
+
+// box blur the image, which is the low-pass filter that makes a pattern of
+// black and white dots look like shades of gray
+func blur(img *image.Gray) *image.Gray {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	blurred := image.NewGray(img.Rect)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum, n := 0, 0
+			for dy := -BlurRadius; dy <= BlurRadius; dy++ {
+				for dx := -BlurRadius; dx <= BlurRadius; dx++ {
+					if x+dx >= 0 && x+dx < w && y+dy >= 0 && y+dy < h {
+						sum += int(img.Pix[(y+dy)*img.Stride+x+dx])
+						n++
+					}
+				}
+			}
+			blurred.Pix[y*blurred.Stride+x] = uint8(sum / n)
+		}
+	}
+	return blurred
+}
+
+// difference between 2 images
+func diff(a, b *image.Gray) int64 {
+	var d int64
+	for i := 0; i < len(a.Pix); i++ {
+		x := int64(a.Pix[i]) - int64(b.Pix[i])
+		d += x * x
+	}
+	return int64(math.Sqrt(float64(d)))
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism, target *image.Gray) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation
+	for i := 0; i < len(top)-1; i++ {
+		num := (top[PoolSize].Fitness - top[i].Fitness)
+		for n := int64(0); n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, target *image.Gray) []Organism {
+	next := make([]Organism, len(population))
+
+	for i := 0; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.calcFitness(target)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(target *image.Gray) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(target)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a dithered image, a bit for every pixel that is either white
+// (true) or black (false)
+type Organism struct {
+	DNA     []bool
+	Fitness int64
+}
+
+// creates an organism with random bits
+func createOrganism(target *image.Gray) (organism Organism) {
+	bits := make([]bool, target.Rect.Dx()*target.Rect.Dy())
+	for i := 0; i < len(bits); i++ {
+		bits[i] = rng.Intn(2) == 0
+	}
+	organism = Organism{
+		DNA:     bits,
+		Fitness: 0,
+	}
+	organism.calcFitness(target)
+	return
+}
+
+// the black and white image of the organism
+func (o *Organism) image(rect image.Rectangle) *image.Gray {
+	img := image.NewGray(rect)
+	for i, white := range o.DNA {
+		if white {
+			img.Pix[i] = 255
+		}
+	}
+	return img
+}
+
+// calculates the fitness of the Organism, comparing its blurred image with
+// the blurred target
+func (o *Organism) calcFitness(target *image.Gray) {
+	o.Fitness = diff(blur(o.image(target.Rect)), target)
+}
+
+// crosses over 2 Organisms
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]bool, len(d1.DNA)),
+		Fitness: 0,
+	}
+	mid := rng.Intn(len(d1.DNA))
+	for i := 0; i < len(d1.DNA); i++ {
+		if i > mid {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism by flipping bits
+func (o *Organism) mutate() {
+	for i := 0; i < len(o.DNA); i++ {
+		if rng.Float64() < MutationRate {
+			o.DNA[i] = !o.DNA[i]
+		}
+	}
+}
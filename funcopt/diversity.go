@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// DiversitySample is the most organisms the diversity of the population is
+// worked out from, as comparing every pair of a large population is slow
+var DiversitySample = 20
+
+// RestartDiversity is the diversity below which the population has converged
+// and is started again from random organisms, keeping only the best, or 0 to
+// never restart
+var RestartDiversity = 0.0
+
+// Distance is the Euclidean distance between the points of 2 organisms
+func (o Organism) Distance(other Organism) float64 {
+	d := 0.0
+	for i := range o.DNA {
+		d += (o.DNA[i] - other.DNA[i]) * (o.DNA[i] - other.DNA[i])
+	}
+	return math.Sqrt(d)
+}
+
+// the diversity of the population, the mean distance between each pair of a
+// random sample of organisms, as a fraction of the range of the variables so
+// it means the same for every function
+func diversity(population []Organism, function Function) float64 {
+	order := rng.Perm(len(population))
+	sample := order[:min(DiversitySample, len(order))]
+	if len(sample) < 2 {
+		return 0
+	}
+	sum, pairs := 0.0, 0
+	for i := 0; i < len(sample); i++ {
+		for j := i + 1; j < len(sample); j++ {
+			sum += population[sample[i]].Distance(population[sample[j]])
+			pairs++
+		}
+	}
+	return sum / float64(pairs) / (function.Max - function.Min)
+}
+
+// start the population again from random organisms, keeping the best
+func restart(population []Organism, function Function) []Organism {
+	next := createPopulation(function)
+	next[0] = getBest(population)
+	return next
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// an external function is worked out by another program, so it can be written
+// in any language or call a simulator. The program is sent a line of JSON for
+// each point, like
+//
+//	{"x": [0.5, -1.2]}
+//
+// and answers each with a line of JSON with the value of the function at the
+// point, or with why it couldn't work it out
+//
+//	{"fitness": 1.69}
+//	{"error": "simulator crashed"}
+//
+// The program is either run by the demo, talking over its stdin and stdout,
+// or is already listening on a Unix socket
+type external struct {
+	in      io.WriteCloser
+	out     *bufio.Scanner
+	cmd     *exec.Cmd
+	request request
+	// err is the first error, after which every point is as bad as can be
+	err error
+}
+
+// request is a point sent to the external function
+type request struct {
+	X []float64 `json:"x"`
+}
+
+// response is the answer of the external function
+type response struct {
+	Fitness *float64 `json:"fitness"`
+	Error   string   `json:"error"`
+}
+
+// start the command, which is split into the program and its arguments at
+// spaces, and talk to it over its stdin and stdout
+func startExternal(command string) (*external, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &external{in: in, out: bufio.NewScanner(out), cmd: cmd}, nil
+}
+
+// connect to a program listening on the Unix socket
+func dialExternal(socket string) (*external, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	return &external{in: conn, out: bufio.NewScanner(conn)}, nil
+}
+
+// F is the value of the function at the point x, as worked out by the
+// external program
+func (e *external) F(x []float64) float64 {
+	if e.err != nil {
+		return math.Inf(1)
+	}
+	fitness, err := e.evaluate(x)
+	if err != nil {
+		e.err = err
+		return math.Inf(1)
+	}
+	return fitness
+}
+
+// send the point and read the answer
+func (e *external) evaluate(x []float64) (float64, error) {
+	e.request.X = x
+	data, err := json.Marshal(e.request)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = e.in.Write(append(data, '\n')); err != nil {
+		return 0, err
+	}
+	if !e.out.Scan() {
+		if e.out.Err() != nil {
+			return 0, e.out.Err()
+		}
+		return 0, fmt.Errorf("no answer")
+	}
+	var r response
+	if err = json.Unmarshal(e.out.Bytes(), &r); err != nil {
+		return 0, fmt.Errorf("cannot read answer %q: %v", e.out.Text(), err)
+	}
+	if r.Error != "" {
+		return 0, fmt.Errorf("%s", r.Error)
+	}
+	if r.Fitness == nil {
+		return 0, fmt.Errorf("no fitness in answer %q", e.out.Text())
+	}
+	return *r.Fitness, nil
+}
+
+// stop talking to the program, waiting for it to finish if the demo ran it
+func (e *external) Close() error {
+	err := e.in.Close()
+	if e.cmd != nil {
+		if werr := e.cmd.Wait(); err == nil {
+			err = werr
+		}
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import "math"
+
+// Function is a benchmark function to minimize, with the range each
+// variable is searched in
+type Function struct {
+	Name string
+	Min  float64
+	Max  float64
+	F    func(x []float64) float64
+	// Batch, if it's set, works out the function at many points at once,
+	// which is faster when every call is a round trip to another machine
+	Batch func(xs [][]float64) []float64
+}
+
+// the benchmark functions, all with a minimum of 0
+var functions = map[string]Function{
+	"sphere":     {"sphere", -5.12, 5.12, sphere, nil},
+	"rastrigin":  {"rastrigin", -5.12, 5.12, rastrigin, nil},
+	"ackley":     {"ackley", -32.768, 32.768, ackley, nil},
+	"rosenbrock": {"rosenbrock", -2.048, 2.048, rosenbrock, nil},
+}
+
+// the sum of the squares, the simplest bowl, with the minimum at 0
+func sphere(x []float64) (sum float64) {
+	for _, xi := range x {
+		sum += xi * xi
+	}
+	return
+}
+
+// a bowl covered in a grid of local minima, with the global minimum at 0
+func rastrigin(x []float64) float64 {
+	sum := 10 * float64(len(x))
+	for _, xi := range x {
+		sum += xi*xi - 10*math.Cos(2*math.Pi*xi)
+	}
+	return sum
+}
+
+// an almost flat plain with many small dips and a deep hole at 0
+func ackley(x []float64) float64 {
+	n := float64(len(x))
+	squares, cosines := 0.0, 0.0
+	for _, xi := range x {
+		squares += xi * xi
+		cosines += math.Cos(2 * math.Pi * xi)
+	}
+	return -20*math.Exp(-0.2*math.Sqrt(squares/n)) - math.Exp(cosines/n) + 20 + math.E
+}
+
+// a long curved valley, easy to find but hard to follow to the minimum at 1
+func rosenbrock(x []float64) (sum float64) {
+	for i := 0; i < len(x)-1; i++ {
+		a, b := x[i+1]-x[i]*x[i], 1-x[i]
+		sum += 100*a*a + b*b
+	}
+	return
+}
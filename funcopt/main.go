@@ -0,0 +1,521 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sausheong/ga/optimize"
+	"github.com/sausheong/ga/remote"
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each variable
+var MutationRate = 0.1
+
+// MutationSize is the standard deviation of Gaussian mutation, as a fraction
+// of the range of the variables
+var MutationSize = 0.05
+
+// Replacement is how the children replace the population, either
+// generational, where the children are the next generation, or crowding,
+// where each child competes with the parent most like it
+var Replacement = "generational"
+
+// Selection is how the parents are picked, either fitness, from the best
+// PoolSize organisms, boltzmann, with a temperature that cools over the run,
+// or truncation, evenly from the best of the population
+var Selection = "fitness"
+
+// SelfAdaptive is whether each organism carries its own mutation rate and
+// size, which are mutated along with it, so they evolve to suit the function
+// and the stage of the run, as in evolution strategies
+var SelfAdaptive = false
+
+// Mutation is the mutation operator, either gaussian or polynomial
+var Mutation = "polynomial"
+
+// DistributionIndex is the distribution index of polynomial mutation and
+// simulated binary crossover. The larger it is, the closer children are to
+// their parents
+var DistributionIndex = 20.0
+
+// PopSize is the size of the population
+var PopSize = 100
+
+// PoolSize is the max size of the pool
+var PoolSize = 20
+
+// Generations is the most generations to evolve
+var Generations = 2000
+
+// MaxEvaluations is the most times the function is evaluated, or 0 for no
+// limit, which is a fairer budget than generations for comparing strategies
+// that evaluate a different number of points each generation
+var MaxEvaluations = 0
+
+// Dimensions is the number of variables of the function
+var Dimensions = 10
+
+// FitnessLimit is the value of the function we are satisfied with
+var FitnessLimit = 1e-6
+
+// Strategy is the way the function is minimized, either ga for the genetic
+// algorithm, de for differential evolution or cmaes for CMA-ES
+var Strategy = "ga"
+
+func main() {
+	name := flag.String("function", "rastrigin", "function to minimize, one of "+strings.Join(functionNames(), ", "))
+	scriptFile := flag.String("script", "", "file of a fitness script to minimize instead of a function")
+	command := flag.String("fitness-command", "", "command of a program that works out the function over its stdin and stdout")
+	socket := flag.String("fitness-socket", "", "Unix socket of a program that works out the function")
+	evaluator := flag.String("fitness-grpc", "", "address of a gRPC evaluator that works out the function")
+	minimum := flag.Float64("min", -10, "least value of the variables of an external or remote function")
+	maximum := flag.Float64("max", 10, "greatest value of the variables of an external or remote function")
+	flag.IntVar(&Dimensions, "dimensions", Dimensions, "number of variables")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve")
+	flag.IntVar(&MaxEvaluations, "max-evals", MaxEvaluations, "most evaluations of the function, 0 for no limit")
+	flag.StringVar(&Mutation, "mutation", Mutation, "mutation operator, gaussian or polynomial")
+	flag.StringVar(&Replacement, "replacement", Replacement, "replacement strategy, generational or crowding")
+	flag.StringVar(&Selection, "selection", Selection, "selection, fitness, boltzmann or truncation")
+	temperature := flag.Float64("temperature", 1, "starting temperature of boltzmann selection")
+	cooling := flag.Float64("cooling", 0.99, "what the temperature of boltzmann selection is multiplied by every generation")
+	survivors := flag.Float64("survivors", 0.2, "fraction of the population that breeds with truncation selection")
+	flag.Float64Var(&RestartDiversity, "restart-diversity", RestartDiversity, "diversity below which the population is started again, 0 to never restart")
+	flag.BoolVar(&SelfAdaptive, "self-adaptive", SelfAdaptive, "evolve the mutation rate and size of each organism along with it")
+	flag.StringVar(&Strategy, "strategy", Strategy, "ga for the genetic algorithm, de for differential evolution or cmaes for CMA-ES")
+	variant := flag.String("de-variant", "rand/1/bin", "differential evolution variant, rand/1/bin, best/1/bin or current-to-best/1/bin")
+	scale := flag.Float64("de-scale", 0.5, "differential evolution scale of the difference, F")
+	cr := flag.Float64("de-cr", 0.9, "differential evolution crossover rate, CR")
+	lambda := flag.Int("cmaes-lambda", 0, "CMA-ES samples per generation, by default 4 + 3 ln(dimensions)")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	function, ok := functions[*name]
+	// the error of an external or remote function, which stops the run
+	failed := func() error { return nil }
+	if *scriptFile != "" {
+		var err error
+		function, err = loadScript(*scriptFile)
+		if err != nil {
+			fmt.Println("Cannot load script:", err)
+			return
+		}
+	} else if *command != "" || *socket != "" {
+		var ext *external
+		var err error
+		if *command != "" {
+			ext, err = startExternal(*command)
+		} else {
+			ext, err = dialExternal(*socket)
+		}
+		if err != nil {
+			fmt.Println("Cannot start external function:", err)
+			return
+		}
+		defer ext.Close()
+		if *minimum >= *maximum {
+			fmt.Println("Cannot search the range:", *minimum, "to", *maximum)
+			return
+		}
+		function = Function{Name: "external", Min: *minimum, Max: *maximum, F: ext.F}
+		failed = func() error { return ext.err }
+	} else if *evaluator != "" {
+		if *minimum >= *maximum {
+			fmt.Println("Cannot search the range:", *minimum, "to", *maximum)
+			return
+		}
+		r := &remoteFunction{client: remote.NewClient(*evaluator)}
+		function = Function{Name: "remote", Min: *minimum, Max: *maximum, F: r.F, Batch: r.Batch}
+		failed = func() error { return r.err }
+	} else if !ok {
+		fmt.Println("Cannot find function:", *name)
+		return
+	}
+	if Mutation != "gaussian" && Mutation != "polynomial" {
+		fmt.Println("Cannot find mutation:", Mutation)
+		return
+	}
+	if Replacement != "generational" && Replacement != "crowding" {
+		fmt.Println("Cannot find replacement:", Replacement)
+		return
+	}
+	var selector Selector
+	switch Selection {
+	case "fitness":
+		selector = FitnessSelector{}
+	case "boltzmann":
+		selector = BoltzmannSelector{Temperature: *temperature, Cooling: *cooling}
+	case "truncation":
+		selector = TruncationSelector{Fraction: *survivors}
+	default:
+		fmt.Println("Cannot find selection:", Selection)
+		return
+	}
+
+	// count every evaluation, whichever strategy makes it
+	evaluations := 0
+	f := function.F
+	function.F = func(x []float64) float64 {
+		evaluations++
+		return f(x)
+	}
+	if batch := function.Batch; batch != nil {
+		function.Batch = func(xs [][]float64) []float64 {
+			evaluations += len(xs)
+			return batch(xs)
+		}
+	}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	problem := optimize.Problem{F: function.F, Dimensions: Dimensions, Min: function.Min, Max: function.Max}
+	var optimizer optimize.Optimizer
+	switch Strategy {
+	case "ga":
+		optimizer = &geneticAlgorithm{function: function, selector: selector, population: createPopulation(function)}
+	case "de":
+		if *variant != "rand/1/bin" && *variant != "best/1/bin" && *variant != "current-to-best/1/bin" {
+			fmt.Println("Cannot find differential evolution variant:", *variant)
+			return
+		}
+		de := optimize.NewDE(problem, PopSize)
+		de.Variant, de.Scale, de.CR = *variant, *scale, *cr
+		optimizer = de
+	case "cmaes":
+		optimizer = optimize.NewCMAES(problem, *lambda)
+	default:
+		fmt.Println("Cannot find strategy:", Strategy)
+		return
+	}
+	if err := failed(); err != nil {
+		fmt.Println("Cannot evaluate function:", err)
+		return
+	}
+
+	generation := 0
+	var best []float64
+	var fitness float64
+	for generation < Generations && (MaxEvaluations == 0 || evaluations < MaxEvaluations) {
+		generation++
+		best, fitness = optimizer.Step()
+		if err := failed(); err != nil {
+			fmt.Println("Cannot evaluate function:", err)
+			return
+		}
+		if fitness < FitnessLimit {
+			break
+		}
+		if generation%100 == 0 {
+			mean, sd := stats(optimizer.Fitnesses())
+			rate := float64(evaluations) / time.Since(start).Seconds()
+			fmt.Printf("generation: %d | evaluations: %d (%.0f/s) | best: %g | mean: %g | std dev: %g", generation, evaluations, rate, fitness, mean, sd)
+			if ga, ok := optimizer.(*geneticAlgorithm); ok {
+				fmt.Printf(" | diversity: %.3g", diversity(ga.population, function))
+				if SelfAdaptive {
+					r, s := ga.mutation()
+					fmt.Printf(" | mutation rate: %.3f | mutation size: %.2g", r, s)
+				}
+			}
+			fmt.Println()
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nBest after %d generations and %d evaluations: %g\nat %.6f\n", generation, evaluations, fitness, best)
+	if ga, ok := optimizer.(*geneticAlgorithm); ok && RestartDiversity > 0 {
+		fmt.Printf("\nRestarts: %d\n", ga.restarts)
+	}
+	fmt.Printf("\nEvaluations per second: %.0f\n", float64(evaluations)/elapsed.Seconds())
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// the names of the functions, sorted
+func functionNames() (names []string) {
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// the mean and standard deviation of the fitness of the population
+func stats(fitnesses []float64) (mean, sd float64) {
+	for _, f := range fitnesses {
+		mean += f
+	}
+	mean /= float64(len(fitnesses))
+	for _, f := range fitnesses {
+		sd += (f - mean) * (f - mean)
+	}
+	sd = math.Sqrt(sd / float64(len(fitnesses)))
+	return
+}
+
+// the genetic algorithm, as an optimizer like the other strategies
+type geneticAlgorithm struct {
+	function   Function
+	selector   Selector
+	population []Organism
+	generation int
+	restarts   int
+}
+
+// Step evolves the next generation
+func (g *geneticAlgorithm) Step() ([]float64, float64) {
+	g.generation++
+	if Replacement == "crowding" {
+		g.population = crowding(g.population, g.function)
+	} else {
+		pool := g.selector.Pool(g.population, g.generation)
+		g.population = naturalSelection(pool, g.population, getBest(g.population), g.function)
+	}
+	if RestartDiversity > 0 && diversity(g.population, g.function) < RestartDiversity {
+		g.population = restart(g.population, g.function)
+		g.restarts++
+	}
+	best := getBest(g.population)
+	return best.DNA, best.Fitness
+}
+
+// Fitnesses are the fitness of every organism in the population
+func (g *geneticAlgorithm) Fitnesses() []float64 {
+	fitnesses := make([]float64, len(g.population))
+	for i, o := range g.population {
+		fitnesses[i] = o.Fitness
+	}
+	return fitnesses
+}
+
+// the mean mutation rate and size of the population
+func (g *geneticAlgorithm) mutation() (rate, size float64) {
+	for _, o := range g.population {
+		rate += o.MutationRate
+		size += o.MutationSize
+	}
+	n := float64(len(g.population))
+	return rate / n, size / n
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	// and the same when the spread isn't a number, as when the function has
+	// no value at some of the points
+	spread := top[PoolSize].Fitness - top[0].Fitness
+	if spread == 0 || math.IsNaN(spread) || math.IsInf(spread, 0) {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with up to 100 copies of the best
+	for i := 0; i < len(top)-1; i++ {
+		num := int(100 * (top[PoolSize].Fitness - top[i].Fitness) / spread)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, function Function) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best organism so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b, function)
+		child.mutate(function)
+
+		next[i] = child
+	}
+	calcFitnesses(next[1:], function)
+	return next
+}
+
+// deterministic crowding, where the population is paired up at random and
+// each pair has 2 children. Each child competes with the parent closest to it
+// and takes its place if it is at least as good, so the population can hold
+// on to several minimums at once instead of crowding into one
+func crowding(population []Organism, function Function) []Organism {
+	next := make([]Organism, len(population))
+	copy(next, population)
+	order := rng.Perm(len(next))
+	// the children of every pair, worked out together
+	children := make([]Organism, len(order)/2*2)
+	for i := 0; i+1 < len(order); i += 2 {
+		p1, p2 := next[order[i]], next[order[i+1]]
+		children[i], children[i+1] = crossover(p1, p2, function), crossover(p1, p2, function)
+		children[i].mutate(function)
+		children[i+1].mutate(function)
+	}
+	calcFitnesses(children, function)
+	for i := 0; i+1 < len(order); i += 2 {
+		p1, p2 := &next[order[i]], &next[order[i+1]]
+		c1, c2 := children[i], children[i+1]
+		if p1.Distance(c1)+p2.Distance(c2) > p1.Distance(c2)+p2.Distance(c1) {
+			c1, c2 = c2, c1
+		}
+		if c1.Fitness <= p1.Fitness {
+			*p1 = c1
+		}
+		if c2.Fitness <= p2.Fitness {
+			*p2 = c2
+		}
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(function Function) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(function)
+	}
+	calcFitnesses(population, function)
+	return
+}
+
+// Get the best organism, the one with the lowest value
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a point, with a real number for every variable of the function
+type Organism struct {
+	DNA     []float64
+	Fitness float64
+	// MutationRate and MutationSize are the organism's own, used instead of
+	// the package ones when it is self-adaptive
+	MutationRate float64
+	MutationSize float64
+}
+
+// creates an organism at a random point in the range of the function, without
+// its fitness
+func createOrganism(function Function) (organism Organism) {
+	organism = Organism{
+		DNA:          make([]float64, Dimensions),
+		Fitness:      0,
+		MutationRate: MutationRate,
+		MutationSize: MutationSize,
+	}
+	for i := range organism.DNA {
+		organism.DNA[i] = function.Min + rng.Float64()*(function.Max-function.Min)
+	}
+	return
+}
+
+// calculates the fitness of the Organism, which is the value of the function
+func (o *Organism) calcFitness(function Function) {
+	o.Fitness = function.F(o.DNA)
+}
+
+// calculates the fitness of the organisms, all at once if the function can
+// work out a batch
+func calcFitnesses(organisms []Organism, function Function) {
+	if function.Batch == nil {
+		for i := range organisms {
+			organisms[i].calcFitness(function)
+		}
+		return
+	}
+	xs := make([][]float64, len(organisms))
+	for i, o := range organisms {
+		xs[i] = o.DNA
+	}
+	for i, fitness := range function.Batch(xs) {
+		organisms[i].Fitness = fitness
+	}
+}
+
+// crosses over 2 Organisms with simulated binary crossover, which spreads
+// the child around the parents much like one point crossover does for bits
+func crossover(d1 Organism, d2 Organism, function Function) Organism {
+	child := Organism{
+		DNA:     make([]float64, len(d1.DNA)),
+		Fitness: 0,
+		// the geometric mean, as the mutation parameters change by factors
+		MutationRate: math.Sqrt(d1.MutationRate * d2.MutationRate),
+		MutationSize: math.Sqrt(d1.MutationSize * d2.MutationSize),
+	}
+	for i := range d1.DNA {
+		u := rng.Float64()
+		beta := math.Pow(2*u, 1/(DistributionIndex+1))
+		if u > 0.5 {
+			beta = math.Pow(1/(2*(1-u)), 1/(DistributionIndex+1))
+		}
+		x := 0.5 * ((1+beta)*d1.DNA[i] + (1-beta)*d2.DNA[i])
+		if rng.Intn(2) == 0 {
+			x = 0.5 * ((1-beta)*d1.DNA[i] + (1+beta)*d2.DNA[i])
+		}
+		child.DNA[i] = clamp(x, function.Min, function.Max)
+	}
+	return child
+}
+
+// mutate the Organism, moving some of the variables a little
+func (o *Organism) mutate(function Function) {
+	rate, size := MutationRate, MutationSize
+	if SelfAdaptive {
+		o.adapt()
+		rate, size = o.MutationRate, o.MutationSize
+	}
+	width := function.Max - function.Min
+	for i := range o.DNA {
+		if rng.Float64() >= rate {
+			continue
+		}
+		if Mutation == "gaussian" {
+			o.DNA[i] += rng.NormFloat64() * size * width
+		} else {
+			// polynomial mutation, which makes small moves much more likely
+			// than large ones
+			u := rng.Float64()
+			delta := math.Pow(2*u, 1/(DistributionIndex+1)) - 1
+			if u >= 0.5 {
+				delta = 1 - math.Pow(2*(1-u), 1/(DistributionIndex+1))
+			}
+			o.DNA[i] += delta * width
+		}
+		o.DNA[i] = clamp(o.DNA[i], function.Min, function.Max)
+	}
+}
+
+// mutate the organism's own mutation rate and size first, by a log-normal
+// factor so they are as likely to halve as to double, before they are used
+// to mutate it
+func (o *Organism) adapt() {
+	tau := 1 / math.Sqrt(2*float64(len(o.DNA)))
+	o.MutationSize = clamp(o.MutationSize*math.Exp(tau*rng.NormFloat64()), 1e-9, 1)
+	// the rate changes its odds rather than itself, so it stays below 1
+	odds := o.MutationRate / (1 - o.MutationRate) * math.Exp(tau*rng.NormFloat64())
+	o.MutationRate = clamp(odds/(1+odds), 1/float64(len(o.DNA))/10, 1)
+}
+
+// keep x within min and max
+func clamp(x, min, max float64) float64 {
+	return math.Max(min, math.Min(max, x))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math"
+
+	"github.com/sausheong/ga/remote"
+)
+
+// a remote function is worked out by an evaluator on another machine, a batch
+// at a time, over gRPC
+type remoteFunction struct {
+	client *remote.Client
+	// err is the first error, after which every point is as bad as can be
+	err error
+}
+
+// Batch is the value of the function at every point
+func (r *remoteFunction) Batch(xs [][]float64) []float64 {
+	fitnesses := make([]float64, len(xs))
+	if r.err == nil {
+		genomes := make([]remote.Genome, len(xs))
+		for i, x := range xs {
+			genomes[i] = remote.Genome{Values: x}
+		}
+		var answer []float64
+		answer, r.err = r.client.EvaluateBatch(genomes)
+		if r.err == nil {
+			return answer
+		}
+	}
+	for i := range fitnesses {
+		fitnesses[i] = math.Inf(1)
+	}
+	return fitnesses
+}
+
+// F is the value of the function at the point x, a batch of 1
+func (r *remoteFunction) F(x []float64) float64 {
+	return r.Batch([][]float64{x})[0]
+}
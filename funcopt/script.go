@@ -0,0 +1,521 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// a fitness script is a function to minimize written in a small language, so
+// a new function can be tried without recompiling. Each line sets a name to
+// the value of an expression, and the value of f is the fitness of a point,
+// for example
+//
+//	# the Styblinski-Tang function, moved so its minimum is 0
+//	min = -5
+//	max = 5
+//	f = sum(i, 0, n-1, x[i]^4 - 16*x[i]^2 + 5*x[i]) / 2 + 39.16616570377142*n
+//
+// x[i] is the ith variable counting from 0, n is the number of variables and
+// pi and e are what they are. Expressions have + - * / % and ^ for power, the
+// functions abs, sqrt, exp, log, sin, cos, tan, floor, min and max, and
+// sum(i, from, to, expr) and prod(i, from, to, expr), which add up or multiply
+// expr for i from from to to. min and max are the range each variable is
+// searched in, -10 and 10 if they're not set, and can't use x. Everything
+// after a # is a comment
+
+// MaxLoop is the most times a sum or prod goes round, so a script with a
+// loop that would take hours stops instead
+var MaxLoop = 1000000
+
+// loadScript reads the fitness script in the file as a function
+func loadScript(file string) (function Function, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	s := &script{slots: map[string]int{"n": 0, "pi": 1, "e": 2}, size: 3}
+	bounds := map[string]expr{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(text, "=")
+		name = strings.TrimSpace(name)
+		if !ok || (name != "min" && name != "max" && !isName(name)) {
+			err = fmt.Errorf("line %d: want name = expression", line)
+			return
+		}
+		var e expr
+		e, err = s.parse(value)
+		if err != nil {
+			err = fmt.Errorf("line %d: %v", line, err)
+			return
+		}
+		if name == "min" || name == "max" {
+			bounds[name] = e
+			continue
+		}
+		if _, ok := s.slots[name]; !ok {
+			s.slots[name] = s.slot()
+		}
+		s.lines = append(s.lines, assignment{s.slots[name], e})
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	result, ok := s.slots["f"]
+	if !ok {
+		err = fmt.Errorf("no value of f")
+		return
+	}
+
+	function = Function{Name: file, Min: -10, Max: 10}
+	for name, bound := range map[string]*float64{"min": &function.Min, "max": &function.Max} {
+		if e, ok := bounds[name]; ok {
+			// min and max are worked out before there are any variables
+			var values []float64
+			values, err = s.run(nil, float64(Dimensions), e)
+			*bound = values[0]
+			if err != nil {
+				err = fmt.Errorf("%s: %v", name, err)
+				return
+			}
+		}
+	}
+	if function.Min >= function.Max {
+		err = fmt.Errorf("min must be less than max")
+		return
+	}
+	function.F = func(x []float64) float64 {
+		slots, _ := s.run(x, float64(len(x)), nil)
+		// a point where the script has no value, like sqrt(-1) or 1/0, is
+		// as far from the minimum as can be, rather than a NaN that can't be
+		// compared or a -Inf that beats every other point
+		if f := slots[result]; !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+		return math.Inf(1)
+	}
+	// try it in the middle of the range, to find the mistakes that only show
+	// when it's run, like a variable after the last
+	middle := make([]float64, Dimensions)
+	for i := range middle {
+		middle[i] = (function.Min + function.Max) / 2
+	}
+	_, err = s.run(middle, float64(Dimensions), nil)
+	return
+}
+
+// script is a parsed fitness script
+type script struct {
+	// the slot of each name, the place its value is kept while the script runs
+	slots map[string]int
+	// size is the number of slots, which is more than the names as the
+	// counters of loops have slots of their own
+	size  int
+	lines []assignment
+}
+
+// a new slot
+func (s *script) slot() int {
+	s.size++
+	return s.size - 1
+}
+
+// assignment sets a slot to the value of an expression
+type assignment struct {
+	slot int
+	e    expr
+}
+
+// env is what a script sees while it runs
+type env struct {
+	x     []float64
+	slots []float64
+	err   error
+}
+
+// expr is an expression compiled to a function
+type expr func(v *env) float64
+
+// run the script at the point x with n variables, returning the value of
+// every slot, or if only is given, the value of only instead
+func (s *script) run(x []float64, n float64, only expr) ([]float64, error) {
+	v := &env{x: x, slots: make([]float64, s.size)}
+	v.slots[0], v.slots[1], v.slots[2] = n, math.Pi, math.E
+	if only != nil {
+		return []float64{only(v)}, v.err
+	}
+	for _, a := range s.lines {
+		v.slots[a.slot] = a.e(v)
+	}
+	return v.slots, v.err
+}
+
+// parse an expression
+func (s *script) parse(text string) (expr, error) {
+	p := &parser{script: s, tokens: tokenize(text)}
+	e, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "" {
+		return nil, fmt.Errorf("unexpected %q", p.peek())
+	}
+	return e, nil
+}
+
+// split an expression into numbers, names and symbols
+func tokenize(text string) (tokens []string) {
+	for i := 0; i < len(text); {
+		c := rune(text[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(text) && (unicode.IsDigit(rune(text[j])) || text[j] == '.') {
+				j++
+			}
+			// an exponent, like 1e-6
+			if j < len(text) && (text[j] == 'e' || text[j] == 'E') {
+				k := j + 1
+				if k < len(text) && (text[k] == '-' || text[k] == '+') {
+					k++
+				}
+				if k < len(text) && unicode.IsDigit(rune(text[k])) {
+					for j = k; j < len(text) && unicode.IsDigit(rune(text[j])); j++ {
+					}
+				}
+			}
+			tokens = append(tokens, text[i:j])
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(text) && (unicode.IsLetter(rune(text[j])) || unicode.IsDigit(rune(text[j])) || text[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, text[i:j])
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return
+}
+
+// is it a name that can be set
+func isName(s string) bool {
+	tokens := tokenize(s)
+	if len(tokens) != 1 || !(unicode.IsLetter(rune(s[0])) || s[0] == '_') {
+		return false
+	}
+	_, builtin := functions1[s]
+	return !builtin && s != "x" && s != "n" && s != "pi" && s != "e" &&
+		s != "sum" && s != "prod" && s != "min" && s != "max"
+}
+
+// the functions of 1 argument
+var functions1 = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"floor": math.Floor,
+}
+
+// parser parses an expression by recursive descent, compiling it as it goes
+type parser struct {
+	script *script
+	tokens []string
+	pos    int
+}
+
+// the next token, or "" at the end
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// move past the next token, which must be want
+func (p *parser) expect(want string) error {
+	if p.peek() != want {
+		if p.peek() == "" {
+			return fmt.Errorf("want %q at the end", want)
+		}
+		return fmt.Errorf("want %q, not %q", want, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+// expression = term {("+" | "-") term}
+func (p *parser) expression() (expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.peek()
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "+" {
+			left = func(v *env) float64 { return l(v) + right(v) }
+		} else {
+			left = func(v *env) float64 { return l(v) - right(v) }
+		}
+	}
+	return left, nil
+}
+
+// term = unary {("*" | "/" | "%") unary}
+func (p *parser) term() (expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" || p.peek() == "%" {
+		op := p.peek()
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		switch op {
+		case "*":
+			left = func(v *env) float64 { return l(v) * right(v) }
+		case "/":
+			left = func(v *env) float64 { return l(v) / right(v) }
+		default:
+			left = func(v *env) float64 { return math.Mod(l(v), right(v)) }
+		}
+	}
+	return left, nil
+}
+
+// unary = "-" unary | power
+func (p *parser) unary() (expr, error) {
+	if p.peek() == "-" {
+		p.pos++
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(v *env) float64 { return -e(v) }, nil
+	}
+	return p.power()
+}
+
+// power = primary ["^" unary], so 2^3^2 is 2^9 and -2^2 is -4
+func (p *parser) power() (expr, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "^" {
+		return base, nil
+	}
+	p.pos++
+	exponent, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return func(v *env) float64 {
+		b, e := base(v), exponent(v)
+		// multiplying is much faster than math.Pow for the usual squares
+		switch e {
+		case 2:
+			return b * b
+		case 3:
+			return b * b * b
+		case 4:
+			return b * b * b * b
+		}
+		return math.Pow(b, e)
+	}, nil
+}
+
+// primary = number | "(" expression ")" | "x" "[" expression "]" |
+// function "(" arguments ")" | name
+func (p *parser) primary() (expr, error) {
+	token := p.peek()
+	if token == "" {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	p.pos++
+	switch {
+	case token == "(":
+		e, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", token)
+		}
+		return func(*env) float64 { return value }, nil
+	case token == "x":
+		return p.variable()
+	case token == "sum" || token == "prod":
+		return p.loop(token)
+	case token == "min" || token == "max":
+		return p.minMax(token)
+	}
+	if f, ok := functions1[token]; ok {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		e, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return func(v *env) float64 { return f(e(v)) }, p.expect(")")
+	}
+	slot, ok := p.script.slots[token]
+	if !ok {
+		return nil, fmt.Errorf("unknown name %q", token)
+	}
+	return func(v *env) float64 { return v.slots[slot] }, nil
+}
+
+// x[i], the ith variable
+func (p *parser) variable() (expr, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	index, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return func(v *env) float64 {
+		i := int(index(v))
+		if i < 0 || i >= len(v.x) {
+			if v.err == nil {
+				if v.x == nil {
+					v.err = fmt.Errorf("cannot use x")
+				} else {
+					v.err = fmt.Errorf("x[%d] is outside x[0] to x[%d]", i, len(v.x)-1)
+				}
+			}
+			return 0
+		}
+		return v.x[i]
+	}, p.expect("]")
+}
+
+// sum(i, from, to, expr) or prod(i, from, to, expr), with i set to each
+// whole number from from to to in turn
+func (p *parser) loop(op string) (expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	name := p.peek()
+	if !isName(name) {
+		return nil, fmt.Errorf("want the name of the counter of %s, not %q", op, name)
+	}
+	p.pos++
+	if err := p.expect(","); err != nil {
+		return nil, err
+	}
+	from, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(","); err != nil {
+		return nil, err
+	}
+	to, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(","); err != nil {
+		return nil, err
+	}
+	// the counter is seen only inside the loop
+	outer, shadowed := p.script.slots[name]
+	slot := p.script.slot()
+	p.script.slots[name] = slot
+	body, err := p.expression()
+	if shadowed {
+		p.script.slots[name] = outer
+	} else {
+		delete(p.script.slots, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func(v *env) float64 {
+		total := 0.0
+		if op == "prod" {
+			total = 1
+		}
+		first, last := from(v), to(v)
+		// the negation also catches a NaN
+		if !(last-first < float64(MaxLoop)) {
+			if v.err == nil {
+				v.err = fmt.Errorf("%s from %v to %v goes round more than %d times", op, first, last, MaxLoop)
+			}
+			return math.NaN()
+		}
+		for i := int(first); i <= int(last); i++ {
+			v.slots[slot] = float64(i)
+			if op == "prod" {
+				total *= body(v)
+			} else {
+				total += body(v)
+			}
+		}
+		return total
+	}, p.expect(")")
+}
+
+// min(a, b, ...) or max(a, b, ...)
+func (p *parser) minMax(op string) (expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []expr
+	for {
+		e, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, e)
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	pick := math.Min
+	if op == "max" {
+		pick = math.Max
+	}
+	return func(v *env) float64 {
+		value := args[0](v)
+		for _, a := range args[1:] {
+			value = pick(value, a(v))
+		}
+		return value
+	}, p.expect(")")
+}
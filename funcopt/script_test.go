@@ -0,0 +1,161 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// load the lines as a fitness script
+func load(t *testing.T, lines ...string) (Function, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "test.fit")
+	err := os.WriteFile(file, []byte(strings.Join(lines, "\n")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loadScript(file)
+}
+
+// the value of f at the point
+func value(t *testing.T, f string, x ...float64) float64 {
+	t.Helper()
+	function, err := load(t, "f = "+f)
+	if err != nil {
+		t.Fatalf("f = %s: %v", f, err)
+	}
+	return function.F(x)
+}
+
+func TestScriptPrecedence(t *testing.T) {
+	for f, want := range map[string]float64{
+		"-2^2":        -4,
+		"2^3^2":       512,
+		"(2^3)^2":     64,
+		"2^-1":        0.5,
+		"1 + 2 * 3":   7,
+		"(1 + 2) * 3": 9,
+		"7 - 2 - 1":   4,
+		"12 / 2 / 3":  2,
+		"-3 % 2":      -1,
+		"--2":         2,
+	} {
+		if got := value(t, f); got != want {
+			t.Errorf("%s is %v, want %v", f, got, want)
+		}
+	}
+}
+
+func TestScriptLoops(t *testing.T) {
+	for f, want := range map[string]float64{
+		"sum(i, 1, 4, i)":                10,
+		"prod(i, 1, 4, i)":               24,
+		"sum(i, 1, 1, i)":                1,
+		"sum(i, 3, 2, i)":                0,
+		"prod(i, 3, 2, i)":               1,
+		"sum(i, 0, n-1, x[i])":           6,
+		"sum(i, 0, 2, sum(j, 0, i, 1))":  6,
+		"sum(i, 0, 1, prod(i, 1, 3, i))": 12,
+	} {
+		if got := value(t, f, 1, 2, 3); got != want {
+			t.Errorf("%s is %v, want %v", f, got, want)
+		}
+	}
+}
+
+// a loop that goes round more than MaxLoop times stops with no value
+func TestScriptMaxLoop(t *testing.T) {
+	defer func(n int) { MaxLoop = n }(MaxLoop)
+	MaxLoop = 10
+	if got := value(t, "sum(i, 1, 10, 1)"); got != 10 {
+		t.Errorf("sum of 10 is %v, want 10", got)
+	}
+	_, err := load(t, "f = sum(i, 0, 10, 1)")
+	if err == nil || !strings.Contains(err.Error(), "more than 10 times") {
+		t.Errorf("error of a sum of 11 is %v, want more than 10 times", err)
+	}
+	_, err = load(t, "f = prod(i, 0, 1e300, 1)")
+	if err == nil {
+		t.Error("no error for a prod of 1e300")
+	}
+}
+
+// a point where the script has no value is as far from the minimum as can be
+func TestScriptNoValue(t *testing.T) {
+	for _, f := range []string{"sqrt(x[0])", "1 / (x[0] + 1)", "-1 / (x[0] + 1)", "log(x[0] + 1)"} {
+		if got := value(t, f, -1); !math.IsInf(got, 1) {
+			t.Errorf("%s at -1 is %v, want +Inf", f, got)
+		}
+	}
+}
+
+// x[i] after the last variable or before the first is found when the script
+// is loaded, as it's tried with Dimensions variables
+func TestScriptOutOfRange(t *testing.T) {
+	defer func(n int) { Dimensions = n }(Dimensions)
+	Dimensions = 3
+	for _, f := range []string{"x[n]", "x[-1]", "sum(i, 0, n, x[i])"} {
+		_, err := load(t, "f = "+f)
+		if err == nil || !strings.Contains(err.Error(), "outside x[0] to x[2]") {
+			t.Errorf("error of %s is %v, want outside x[0] to x[2]", f, err)
+		}
+	}
+	if _, err := load(t, "f = sum(i, 0, n-1, x[i])"); err != nil {
+		t.Errorf("sum over the variables: %v", err)
+	}
+}
+
+// min and max set the range searched, and are worked out before there are
+// any variables, so they can use n but not x
+func TestScriptBounds(t *testing.T) {
+	defer func(n int) { Dimensions = n }(Dimensions)
+	Dimensions = 4
+	function, err := load(t, "min = -n", "max = 2*pi", "f = x[0]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if function.Min != -4 || function.Max != 2*math.Pi {
+		t.Errorf("range is %v to %v, want -4 to 2pi", function.Min, function.Max)
+	}
+	function, err = load(t, "f = x[0]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if function.Min != -10 || function.Max != 10 {
+		t.Errorf("range is %v to %v, want -10 to 10", function.Min, function.Max)
+	}
+	for _, bound := range []string{"min = x[0]", "max = x[0]", "max = sum(i, 0, n-1, x[i])"} {
+		_, err := load(t, bound, "f = x[0]")
+		if err == nil || !strings.Contains(err.Error(), "cannot use x") {
+			t.Errorf("error of %s is %v, want cannot use x", bound, err)
+		}
+	}
+	if _, err := load(t, "min = 1", "max = 1", "f = x[0]"); err == nil {
+		t.Error("no error for min = max")
+	}
+	// min and max as functions are fine in f
+	if got := value(t, "min(x[0], 2) + max(x[1], 5, x[2])", 1, 7, 3); got != 8 {
+		t.Errorf("min and max are %v, want 8", got)
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	for _, lines := range [][]string{
+		{"g = 1"},
+		{"f = 1 +"},
+		{"f = (1"},
+		{"f = y"},
+		{"f = sqrt 2"},
+		{"x = 1", "f = x[0]"},
+		{"sum = 1", "f = 1"},
+		{"f = sum(x, 0, 1, 1)"},
+		{"f = 1 2"},
+		{"f"},
+	} {
+		if _, err := load(t, lines...); err == nil {
+			t.Errorf("no error for %q", lines)
+		}
+	}
+}
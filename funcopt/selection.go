@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Selector makes the pool the parents of the next generation are picked
+// from, with more copies of the organisms more likely to be picked
+type Selector interface {
+	Pool(population []Organism, generation int) []Organism
+}
+
+// FitnessSelector picks from the best PoolSize organisms in proportion to how
+// much better they are than the worst of them
+type FitnessSelector struct{}
+
+// Pool creates the pool from the best organisms
+func (FitnessSelector) Pool(population []Organism, generation int) []Organism {
+	return createPool(population)
+}
+
+// BoltzmannSelector picks organisms with a chance that falls exponentially
+// with their fitness, divided by a temperature that cools as the run goes
+// on. While it's hot, worse organisms are nearly as likely to be picked as
+// the best, and as it cools the pressure to pick the best grows
+type BoltzmannSelector struct {
+	// Temperature is the starting temperature, where 1 is the spread of
+	// fitness of the population
+	Temperature float64
+	// Cooling is what the temperature is multiplied by every generation
+	Cooling float64
+}
+
+// the lowest temperature, so the pool doesn't end up as only the best
+const minTemperature = 0.01
+
+// Pool creates the pool with up to 100 copies of each organism
+func (b BoltzmannSelector) Pool(population []Organism, generation int) (pool []Organism) {
+	t := math.Max(b.Temperature*math.Pow(b.Cooling, float64(generation)), minTemperature)
+	best, worst := population[0].Fitness, population[0].Fitness
+	for _, o := range population {
+		best, worst = math.Min(best, o.Fitness), math.Max(worst, o.Fitness)
+	}
+	if worst == best {
+		return population
+	}
+	for _, o := range population {
+		num := int(100 * math.Exp(-(o.Fitness-best)/(worst-best)/t))
+		for n := 0; n < num; n++ {
+			pool = append(pool, o)
+		}
+	}
+	return
+}
+
+// TruncationSelector picks evenly from the best fraction of the population,
+// and never from the rest
+type TruncationSelector struct {
+	// Fraction is the part of the population that survives to breed
+	Fraction float64
+}
+
+// Pool creates the pool from the survivors
+func (t TruncationSelector) Pool(population []Organism, generation int) []Organism {
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	n := max(1, int(t.Fraction*float64(len(population))))
+	return population[:min(n, len(population))]
+}
@@ -0,0 +1,9 @@
+# an external function for funcopt, the sphere function worked out in Python
+#
+#   go run . -fitness-command "python3 sphere.py" -min -5.12 -max 5.12
+import json
+import sys
+
+for line in sys.stdin:
+    x = json.loads(line)["x"]
+    print(json.dumps({"fitness": sum(xi * xi for xi in x)}), flush=True)
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChartWidth and ChartHeight are the size of the fitness chart of each run
+var (
+	ChartWidth  = 300
+	ChartHeight = 100
+)
+
+// Summary is the summary a demo writes at the end of a run, which has
+// parameters that differ from demo to demo
+type Summary struct {
+	Target      string                 `json:"target"`
+	Seed        int64                  `json:"seed"`
+	Params      map[string]interface{} `json:"params"`
+	WallTime    string                 `json:"wall_time"`
+	Generations int                    `json:"generations"`
+	Evaluations int                    `json:"evaluations"`
+	Fitness     float64                `json:"fitness"`
+	Curve       []float64              `json:"curve"`
+	Artifacts   []string               `json:"artifacts"`
+}
+
+// Run is a run as it is shown in the report
+type Run struct {
+	Summary
+	Dir string
+	// Thumbnail is the evolved image as a data URL, so the report is a single
+	// file that can be shared
+	Thumbnail template.URL
+	// Points are the points of the line of the fitness chart
+	Points string
+	// Rows are the parameters, sorted by name
+	Rows [][2]string
+}
+
+func main() {
+	out := flag.String("out", "gallery.html", "HTML file to write the report to")
+	title := flag.String("title", "Runs", "title of the report")
+	flag.Parse()
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	var runs []Run
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || d.Name() != "summary.json" {
+				return err
+			}
+			run, err := readRun(path)
+			if err != nil {
+				fmt.Println("Cannot read summary:", err)
+				return nil
+			}
+			runs = append(runs, run)
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Cannot find summaries:", err)
+			return
+		}
+	}
+	if len(runs) == 0 {
+		fmt.Println("Cannot find any summary.json files")
+		return
+	}
+	// the best runs first
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Fitness < runs[j].Fitness
+	})
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Println("Cannot create report:", err)
+		return
+	}
+	defer f.Close()
+	err = report.Execute(f, map[string]interface{}{
+		"Title":  *title,
+		"Runs":   runs,
+		"Width":  ChartWidth,
+		"Height": ChartHeight,
+	})
+	if err != nil {
+		fmt.Println("Cannot write report:", err)
+		return
+	}
+	fmt.Printf("Wrote %d runs to %s\n", len(runs), *out)
+}
+
+// read the run from its summary, with the first PNG file it wrote as the
+// thumbnail
+func readRun(path string) (run Run, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &run.Summary)
+	if err != nil {
+		err = fmt.Errorf("%s: %v", path, err)
+		return
+	}
+	run.Dir = filepath.Dir(path)
+	for _, a := range run.Artifacts {
+		if strings.ToLower(filepath.Ext(a)) != ".png" {
+			continue
+		}
+		if !filepath.IsAbs(a) {
+			a = filepath.Join(run.Dir, a)
+		}
+		img, err := os.ReadFile(a)
+		if err == nil {
+			run.Thumbnail = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(img))
+			break
+		}
+	}
+	run.Points = points(run.Curve)
+	for name, value := range run.Params {
+		v, _ := json.Marshal(value)
+		run.Rows = append(run.Rows, [2]string{name, string(v)})
+	}
+	sort.Slice(run.Rows, func(i, j int) bool {
+		return run.Rows[i][0] < run.Rows[j][0]
+	})
+	return
+}
+
+// the points of the line of the fitness chart, with the highest fitness at
+// the top
+func points(curve []float64) string {
+	if len(curve) < 2 {
+		return ""
+	}
+	low, high := curve[0], curve[0]
+	for _, f := range curve {
+		low, high = min(low, f), max(high, f)
+	}
+	var sb strings.Builder
+	for i, f := range curve {
+		x := float64(i) * float64(ChartWidth) / float64(len(curve)-1)
+		y := float64(ChartHeight) / 2
+		if high > low {
+			y = float64(ChartHeight) * (high - f) / (high - low)
+		}
+		fmt.Fprintf(&sb, "%.1f,%.1f ", x, y)
+	}
+	return strings.TrimSpace(sb.String())
+}
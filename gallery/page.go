@@ -0,0 +1,44 @@
+package main
+
+import "html/template"
+
+// the report, with a card for each run
+var report = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.runs { display: flex; flex-wrap: wrap; gap: 2em; }
+.run { border: 1px solid #ccc; padding: 1em; width: 320px; }
+.run img { width: 100%; image-rendering: pixelated; }
+.run h2 { font-size: 1em; word-break: break-all; }
+svg { border: 1px solid #eee; }
+td { padding: 1px 6px; font-size: 0.85em; vertical-align: top; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="runs">
+{{range .Runs}}
+<div class="run">
+  <h2>{{.Dir}}</h2>
+  {{if .Thumbnail}}<img src="{{.Thumbnail}}" alt="evolved image">{{end}}
+  {{if .Points}}<svg width="{{$.Width}}" height="{{$.Height}}"><polyline points="{{.Points}}" fill="none" stroke="steelblue"/></svg>{{end}}
+  <table>
+    <tr><td>Target</td><td>{{.Target}}</td></tr>
+    <tr><td>Fitness</td><td>{{.Fitness}}</td></tr>
+    <tr><td>Generations</td><td>{{.Generations}}</td></tr>
+    <tr><td>Evaluations</td><td>{{.Evaluations}}</td></tr>
+    <tr><td>Wall time</td><td>{{.WallTime}}</td></tr>
+    <tr><td>Seed</td><td>{{.Seed}}</td></tr>
+    {{range .Rows}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>
+    {{end}}
+  </table>
+</div>
+{{end}}
+</div>
+</body>
+</html>
+`))
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Seed is the random seed every demo is run with
+var Seed = "1"
+
+// runs are the demos and the arguments that keep each of them short, by the
+// name of their golden
+var runs = []struct {
+	name string
+	demo string
+	args []string
+}{
+	{"ca", "ca", []string{"-generations", "20"}},
+	{"curvefit", "curvefit", []string{"-generations", "300"}},
+	{"dithering", "dithering", []string{"-generations", "100"}},
+	{"funcopt", "funcopt", []string{"-generations", "300"}},
+	{"funcopt_de", "funcopt", []string{"-generations", "300", "-strategy", "de"}},
+	{"funcopt_cmaes", "funcopt", []string{"-generations", "300", "-strategy", "cmaes"}},
+	{"gp", "gp", []string{"-generations", "100"}},
+	{"kernel", "kernel", []string{"-generations", "50"}},
+	{"knapsack", "knapsack", []string{"-generations", "300"}},
+	{"maze", "maze", []string{"-generations", "100"}},
+	{"monalisa", "monalisa", []string{"-generations", "100", "-no-preview"}},
+	{"monalisa_circles", "monalisa_circles", []string{"-generations", "50", "-no-preview"}},
+	{"monalisa_triangles", "monalisa_triangles", []string{"-generations", "50", "-no-preview"}},
+	{"music", "music", []string{"-generations", "300"}},
+	{"neuro", "neuro", []string{"-generations", "100"}},
+	{"nqueens", "nqueens", []string{"-generations", "300"}},
+	{"packing", "packing", []string{"-generations", "200"}},
+	{"regex", "regex", []string{"-generations", "100"}},
+	{"shakespeare", "shakespeare", []string{"-target", "To be or not to be"}},
+	{"snake", "snake", []string{"-generations", "10"}},
+	{"stringart", "stringart", []string{"-generations", "200"}},
+	{"sudoku", "sudoku", []string{"-generations", "300"}},
+	{"timetable", "timetable", []string{"-generations", "300"}},
+	{"tsp", "tsp", []string{"-generations", "300"}},
+}
+
+// the parts of the output that change from run to run even with the same
+// seed, which are how long the run took, how fast it went and how long it's
+// expected to go on for
+var timings = regexp.MustCompile(`(?i)(total )?time taken[a-z ]*: [^|]*(\| )?|evaluations per second: [0-9.]+| \([0-9.]+/s\)|( \|)? eta: .*|time in each phase: .*`)
+
+func main() {
+	update := flag.Bool("update", false, "write the output of each run as its golden instead of comparing")
+	dir := flag.String("dir", "golden", "directory of the goldens")
+	only := flag.String("demo", "", "only run this demo")
+	flag.Parse()
+
+	failed := 0
+	for _, run := range runs {
+		if *only != "" && run.demo != *only {
+			continue
+		}
+		name := run.name + ".txt"
+		got, err := trajectory(run.demo, run.args)
+		if err != nil {
+			fmt.Printf("FAIL %s: cannot run: %v\n", name, err)
+			failed++
+			continue
+		}
+		golden := filepath.Join(*dir, name)
+		if *update {
+			err = os.WriteFile(golden, []byte(got), 0644)
+			if err != nil {
+				fmt.Println("Cannot write golden:", err)
+				return
+			}
+			fmt.Println("updated", name)
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			fmt.Printf("FAIL %s: cannot read golden: %v\n", name, err)
+			failed++
+			continue
+		}
+		if line, g, w := firstDiff(got, string(want)); line > 0 {
+			fmt.Printf("FAIL %s: line %d\n  got:  %s\n  want: %s\n", name, line, g, w)
+			failed++
+			continue
+		}
+		fmt.Println("ok  ", name)
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d runs changed\n", failed)
+		os.Exit(1)
+	}
+}
+
+// build the demo and run it with the seed in a scratch directory, with a copy
+// of the files it reads, so the files it writes don't end up in the repo.
+// Return its output without the timings
+func trajectory(demo string, args []string) (string, error) {
+	work, err := os.MkdirTemp("", "golden")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(work)
+	bin := filepath.Join(work, demo)
+	build := exec.Command("go", "build", "-o", bin, "./"+demo)
+	build.Stderr = os.Stderr
+	if err = build.Run(); err != nil {
+		return "", err
+	}
+	files, err := os.ReadDir(demo)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) == ".go" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(demo, f.Name()))
+		if err != nil {
+			return "", err
+		}
+		if err = os.WriteFile(filepath.Join(work, f.Name()), data, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	cmd := exec.Command(bin, append([]string{"-seed", Seed}, args...)...)
+	cmd.Dir = work
+	cmd.Stdin = strings.NewReader("")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	// progress that's written over with a carriage return is a line too
+	output := strings.ReplaceAll(out.String(), "\r", "\n")
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(timings.ReplaceAllString(line, ""))
+		// skip the images shown in the terminal
+		if line == "" || strings.Contains(line, "\x1b") {
+			continue
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String(), nil
+}
+
+// the first line that differs, counting from 1, or 0 if none do
+func firstDiff(got, want string) (line int, g, w string) {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := 0; i < max(len(gotLines), len(wantLines)); i++ {
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i >= len(gotLines) || i >= len(wantLines) || g != w {
+			return i + 1, g, w
+		}
+	}
+	return 0, "", ""
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// Node is a node of an expression tree, either a function of its children,
+// a constant or a variable
+type Node struct {
+	Op       string
+	Value    float64
+	Var      int
+	Children []*Node
+}
+
+// the functions and how many arguments they take
+var functionArity = map[string]int{
+	"add": 2,
+	"sub": 2,
+	"mul": 2,
+	"div": 2,
+	"sin": 1,
+	"cos": 1,
+}
+
+// the names of the functions, in a fixed order so random picks are
+// repeatable
+var functionNames = []string{"add", "sub", "mul", "div", "sin", "cos"}
+
+// evaluate the expression with the given variables
+func (n *Node) eval(vars []float64) float64 {
+	switch n.Op {
+	case "const":
+		return n.Value
+	case "var":
+		return vars[n.Var]
+	case "add":
+		return n.Children[0].eval(vars) + n.Children[1].eval(vars)
+	case "sub":
+		return n.Children[0].eval(vars) - n.Children[1].eval(vars)
+	case "mul":
+		return n.Children[0].eval(vars) * n.Children[1].eval(vars)
+	case "div":
+		// protected division, so dividing by 0 doesn't blow up the fitness
+		d := n.Children[1].eval(vars)
+		if math.Abs(d) < 1e-9 {
+			return 1
+		}
+		return n.Children[0].eval(vars) / d
+	case "sin":
+		return math.Sin(n.Children[0].eval(vars))
+	case "cos":
+		return math.Cos(n.Children[0].eval(vars))
+	}
+	return 0
+}
+
+// the expression written out
+func (n *Node) String() string {
+	switch n.Op {
+	case "const":
+		return fmt.Sprintf("%.4g", n.Value)
+	case "var":
+		return fmt.Sprintf("x%d", n.Var)
+	case "add":
+		return fmt.Sprintf("(%s + %s)", n.Children[0], n.Children[1])
+	case "sub":
+		return fmt.Sprintf("(%s - %s)", n.Children[0], n.Children[1])
+	case "mul":
+		return fmt.Sprintf("(%s * %s)", n.Children[0], n.Children[1])
+	case "div":
+		return fmt.Sprintf("(%s / %s)", n.Children[0], n.Children[1])
+	}
+	return fmt.Sprintf("%s(%s)", n.Op, n.Children[0])
+}
+
+// a deep copy of the expression
+func (n *Node) copy() *Node {
+	c := &Node{Op: n.Op, Value: n.Value, Var: n.Var}
+	for _, child := range n.Children {
+		c.Children = append(c.Children, child.copy())
+	}
+	return c
+}
+
+// the number of nodes in the expression
+func (n *Node) size() int {
+	s := 1
+	for _, child := range n.Children {
+		s += child.size()
+	}
+	return s
+}
+
+// the depth of the expression, 1 for a single node
+func (n *Node) depth() int {
+	d := 0
+	for _, child := range n.Children {
+		if cd := child.depth(); cd > d {
+			d = cd
+		}
+	}
+	return d + 1
+}
+
+// all the nodes of the expression, parents before children
+func (n *Node) nodes() []*Node {
+	all := []*Node{n}
+	for _, child := range n.Children {
+		all = append(all, child.nodes()...)
+	}
+	return all
+}
+
+// a random constant or variable
+func randomTerminal(numVars int) *Node {
+	if rng.Intn(2) == 0 {
+		return &Node{Op: "const", Value: math.Round((rng.Float64()*10-5)*10) / 10}
+	}
+	return &Node{Op: "var", Var: rng.Intn(numVars)}
+}
+
+// a random expression no deeper than depth. A full expression has functions
+// all the way down to the last level, otherwise a branch can stop early
+func randomTree(depth int, full bool, numVars int) *Node {
+	if depth <= 1 || (!full && rng.Float64() < 0.3) {
+		return randomTerminal(numVars)
+	}
+	op := functionNames[rng.Intn(len(functionNames))]
+	n := &Node{Op: op}
+	for i := 0; i < functionArity[op]; i++ {
+		n.Children = append(n.Children, randomTree(depth-1, full, numVars))
+	}
+	return n
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each node
+var MutationRate = 0.05
+
+// PopSize is the size of the population
+var PopSize = 500
+
+// PoolSize is the max size of the pool
+var PoolSize = 50
+
+// Generations is the most generations to evolve
+var Generations = 500
+
+// MaxDepth is the deepest an expression can grow
+var MaxDepth = 8
+
+// InitDepth is the deepest an expression in the first generation can be
+var InitDepth = 5
+
+// Parsimony is the fitness penalty for every node in the expression, which
+// keeps the expressions from bloating
+var Parsimony = 0.001
+
+// FitnessLimit is the fitness of the evolved expression we are satisfied with
+var FitnessLimit = 1e-6
+
+// Point is a row of the data, the values of the variables and the output
+type Point struct {
+	X []float64
+	Y float64
+}
+
+func main() {
+	dataFile := flag.String("data", "", "CSV file with the points to fit, the inputs followed by the output in each row")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve")
+	flag.IntVar(&MaxDepth, "max-depth", MaxDepth, "deepest an expression can grow")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	points := defaultPoints()
+	if *dataFile != "" {
+		var err error
+		points, err = readPoints(*dataFile)
+		if err != nil {
+			fmt.Println("Cannot read data:", err)
+			return
+		}
+	} else {
+		fmt.Println("Fitting points from x0*x0 + x0 + 1")
+	}
+	if len(points) == 0 {
+		fmt.Println("Cannot fit: there are no points")
+		return
+	}
+	population := createPopulation(points)
+
+	found := false
+	generation := 0
+	var bestOrganism Organism
+	for !found && generation < Generations {
+		generation++
+		bestOrganism = getBest(population)
+		if bestOrganism.Error < FitnessLimit {
+			found = true
+		} else {
+			pool := createPool(population)
+			population = naturalSelection(pool, population, bestOrganism, points)
+			if generation%10 == 0 {
+				sofar := time.Since(start)
+				fmt.Printf("Time taken so far: %s | generation: %d | error: %g | size: %d\n  %s\n", sofar, generation, bestOrganism.Error, bestOrganism.DNA.size(), bestOrganism.DNA)
+			}
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nBest expression after %d generations, with a mean squared error of %g:\n  %s\n", generation, bestOrganism.Error, bestOrganism.DNA)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// points from a hidden function, to use when no data is given
+func defaultPoints() (points []Point) {
+	for x := -1.0; x <= 1.0; x += 0.1 {
+		points = append(points, Point{X: []float64{x}, Y: x*x + x + 1})
+	}
+	return
+}
+
+// read the points from a CSV file, skipping the header row if there is one
+func readPoints(filePath string) (points []Point, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return
+	}
+	for n, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: need at least an input and an output", n+1)
+		}
+		values := make([]float64, len(record))
+		for i := range record {
+			if values[i], err = strconv.ParseFloat(record[i], 64); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			if n == 0 {
+				// the header
+				err = nil
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		points = append(points, Point{X: values[:len(values)-1], Y: values[len(values)-1]})
+	}
+	return
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	spread := top[PoolSize].Fitness - top[0].Fitness
+	if spread == 0 || math.IsInf(spread, 0) || math.IsNaN(spread) {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with up to 100 copies of the best
+	for i := 0; i < len(top)-1; i++ {
+		num := int(100 * (top[PoolSize].Fitness - top[i].Fitness) / spread)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, points []Point) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best expression so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate(len(points[0].X))
+		child.calcFitness(points)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population, ramped half-and-half, with full and
+// partial expressions of every depth up to InitDepth
+func createPopulation(points []Point) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		depth := 2 + i%(InitDepth-1)
+		population[i] = Organism{DNA: randomTree(depth, i%2 == 0, len(points[0].X))}
+		population[i].calcFitness(points)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is an expression
+type Organism struct {
+	DNA *Node
+	// Error is the mean squared error of the expression on the points
+	Error   float64
+	Fitness float64
+}
+
+// calculates the fitness of the Organism, which is the mean squared error,
+// plus a small penalty for its size
+func (o *Organism) calcFitness(points []Point) {
+	sum := 0.0
+	for _, p := range points {
+		d := o.DNA.eval(p.X) - p.Y
+		sum += d * d
+	}
+	o.Error = sum / float64(len(points))
+	if math.IsNaN(o.Error) {
+		o.Error = math.Inf(1)
+	}
+	o.Fitness = o.Error + Parsimony*float64(o.DNA.size())
+}
+
+// crosses over 2 Organisms by replacing a random subtree of the first with a
+// random subtree of the second, as long as the child isn't too deep
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{DNA: d1.DNA.copy()}
+	nodes := child.DNA.nodes()
+	target := nodes[rng.Intn(len(nodes))]
+	donors := d2.DNA.nodes()
+	*target = *donors[rng.Intn(len(donors))].copy()
+	if child.DNA.depth() > MaxDepth {
+		child.DNA = d1.DNA.copy()
+	}
+	return child
+}
+
+// mutate the Organism by changing nodes into others of the same kind, so the
+// shape of the expression stays the same
+func (o *Organism) mutate(numVars int) {
+	for _, n := range o.DNA.nodes() {
+		if rng.Float64() >= MutationRate {
+			continue
+		}
+		switch n.Op {
+		case "const":
+			n.Value += rng.NormFloat64()
+		case "var":
+			*n = *randomTerminal(numVars)
+		default:
+			// another function taking the same number of arguments
+			for {
+				op := functionNames[rng.Intn(len(functionNames))]
+				if functionArity[op] == len(n.Children) {
+					n.Op = op
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Package imgdiff compares images byte by byte, which is what the image demos
+// spend most of their time doing. On amd64 it uses SSE2 to compare 16 bytes at
+// a time, and elsewhere, or when built with the purego tag, a plain loop. A
+// target that's compared many times can be packed first with NewTarget or
+// Packed, which saves unpacking its bytes each time.
+package imgdiff
+
+// SumSquares is the sum of the squares of the differences between the bytes
+// of a and b, which are the same length, like the Pix of 2 images of the same
+// size
+func SumSquares(a, b []byte) uint64 {
+	if len(a) != len(b) {
+		panic("imgdiff: slices of different lengths")
+	}
+	return sumSquares(a, b)
+}
+
+// the sum of the squares in plain Go. Unrolling the loop or loading 8 bytes at
+// a time doesn't make it any faster, as the compiler already leaves out the
+// bounds checks once b is cut to the length of a
+func portable(a, b []byte) (sum uint64) {
+	b = b[:len(a)]
+	for i := range a {
+		d := int(a[i]) - int(b[i])
+		sum += uint64(d * d)
+	}
+	return
+}
+
+// SumSquaresAlpha is the sum of the squares of the differences between the
+// alpha bytes, every 4th byte, of a and b, which are the same length, like the
+// Pix of 2 RGBA images. Taken off SumSquares it leaves the sum of the colors
+func SumSquaresAlpha(a, b []byte) (sum uint64) {
+	if len(a) != len(b) {
+		panic("imgdiff: slices of different lengths")
+	}
+	for i := 3; i < len(a); i += 4 {
+		d := int(a[i]) - int(b[i])
+		sum += uint64(d * d)
+	}
+	return
+}
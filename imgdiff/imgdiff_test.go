@@ -0,0 +1,182 @@
+package imgdiff
+
+import (
+	"image"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// lengths around the 16 bytes the SSE2 loops take at a time, and some
+// that are bigger and not a multiple of them
+var lengths = []int{0, 1, 3, 4, 15, 16, 17, 31, 32, 33, 100, 1000, 4095, 4097, 65541}
+
+// a and b of n random bytes
+func randomBytes(r *rand.Rand, n int) (a, b []byte) {
+	a, b = make([]byte, n), make([]byte, n)
+	for i := range a {
+		a[i], b[i] = byte(r.UintN(256)), byte(r.UintN(256))
+	}
+	return
+}
+
+// the weighted sum a byte at a time, to check the others against
+func weightedLoop(a, b []byte, w Weights) (sum float64) {
+	for i := range a {
+		d := float64(a[i]) - float64(b[i])
+		sum += w[i%4] * d * d
+	}
+	return
+}
+
+func TestSumSquares(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, n := range lengths {
+		a, b := randomBytes(r, n)
+		if got, want := SumSquares(a, b), portable(a, b); got != want {
+			t.Errorf("SumSquares of %d bytes is %d, want %d", n, got, want)
+		}
+	}
+}
+
+// the largest differences, so a sum that overflows in the SSE2 loops shows
+func TestSumSquaresLargest(t *testing.T) {
+	n := 1<<20 + 7
+	a, b := make([]byte, n), make([]byte, n)
+	for i := range a {
+		a[i] = 255
+	}
+	want := uint64(n) * 255 * 255
+	if got := SumSquares(a, b); got != want {
+		t.Errorf("SumSquares is %d, want %d", got, want)
+	}
+	if got := NewTarget(b).SumSquares(a); got != want {
+		t.Errorf("SumSquares of the target is %d, want %d", got, want)
+	}
+}
+
+func TestTargetSumSquares(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	for _, n := range lengths {
+		a, b := randomBytes(r, n)
+		want := portable(a, b)
+		if got := NewTarget(b).SumSquares(a); got != want {
+			t.Errorf("SumSquares of a target of %d bytes is %d, want %d", n, got, want)
+		}
+		if got := Packed(b).SumSquares(a); got != want {
+			t.Errorf("SumSquares of a packed target of %d bytes is %d, want %d", n, got, want)
+		}
+		if got := portableWords(a, NewTarget(b).words); got != want {
+			t.Errorf("portableWords of %d bytes is %d, want %d", n, got, want)
+		}
+	}
+}
+
+// a target over MaxPacked isn't packed, and is compared byte by byte
+func TestTargetUnpacked(t *testing.T) {
+	defer func(n int) { MaxPacked = n }(MaxPacked)
+	MaxPacked = 16
+	r := rand.New(rand.NewPCG(5, 6))
+	a, b := randomBytes(r, 1001)
+	target := NewTarget(b)
+	if target.words != nil {
+		t.Fatal("target over MaxPacked is packed")
+	}
+	if got, want := target.SumSquares(a), portable(a, b); got != want {
+		t.Errorf("SumSquares is %d, want %d", got, want)
+	}
+}
+
+func TestWeightedSumSquares(t *testing.T) {
+	r := rand.New(rand.NewPCG(7, 8))
+	weights := []Weights{Even, Luminance, {1, 1, 1, 0.5}, {2, 0, 1, 3}}
+	for _, n := range lengths {
+		// whole pixels, as the weights are for the channels of each
+		n &^= 3
+		a, b := randomBytes(r, n)
+		for _, w := range weights {
+			want := weightedLoop(a, b, w)
+			if got := WeightedSumSquares(a, b, w); !near(got, want) {
+				t.Errorf("WeightedSumSquares of %d bytes by %v is %v, want %v", n, w, got, want)
+			}
+			if got := NewTarget(b).WeightedSumSquares(a, w); !near(got, want) {
+				t.Errorf("WeightedSumSquares of a target of %d bytes by %v is %v, want %v", n, w, got, want)
+			}
+		}
+	}
+}
+
+func TestTileSums(t *testing.T) {
+	r := rand.New(rand.NewPCG(9, 10))
+	for _, size := range [][2]int{{1, 1}, {5, 3}, {17, 13}, {64, 48}} {
+		width, height := size[0], size[1]
+		a, b := randomBytes(r, width*height*4)
+		for _, grid := range [][2]int{{1, 1}, {3, 2}, {4, 4}, {100, 100}} {
+			tiles := TileSums(a, b, width, height, grid[0], grid[1])
+			if got, want := tiles.Total(), portable(a, b); got != want {
+				t.Errorf("%dx%d tiles of %dx%d add up to %d, want %d", grid[0], grid[1], width, height, got, want)
+			}
+			for row := 0; row < tiles.Rows; row++ {
+				for col := 0; col < tiles.Cols; col++ {
+					rect := tiles.Rect(col, row)
+					var want uint64
+					for y := rect.Min.Y; y < rect.Max.Y; y++ {
+						start, end := (y*width+rect.Min.X)*4, (y*width+rect.Max.X)*4
+						want += portable(a[start:end], b[start:end])
+					}
+					if got := tiles.At(col, row); got != want {
+						t.Errorf("tile %d,%d of %dx%d is %d, want %d", col, row, width, height, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+// images read through their strides, as views into bigger images, come to
+// the same as the bytes of the images alone
+func TestImages(t *testing.T) {
+	r := rand.New(rand.NewPCG(11, 12))
+	width, height := 13, 7
+	a, b := randomBytes(r, width*height*4)
+	big := image.NewRGBA(image.Rect(0, 0, width+5, height+3))
+	for i := range big.Pix {
+		big.Pix[i] = byte(r.UintN(256))
+	}
+	view := big.SubImage(image.Rect(2, 1, width+2, height+1)).(*image.RGBA)
+	for y := 0; y < height; y++ {
+		copy(view.Pix[y*view.Stride:], a[y*width*4:(y+1)*width*4])
+	}
+	img := &image.RGBA{Pix: b, Stride: width * 4, Rect: image.Rect(0, 0, width, height)}
+
+	if got, want := SumSquaresImages(view, img), portable(a, b); got != want {
+		t.Errorf("SumSquaresImages is %d, want %d", got, want)
+	}
+	if got, want := WeightedSumSquaresImages(view, img, Luminance), weightedLoop(a, b, Luminance); !near(got, want) {
+		t.Errorf("WeightedSumSquaresImages is %v, want %v", got, want)
+	}
+	want := TileSums(a, b, width, height, 3, 2)
+	got := TileSumsImages(view, img, 3, 2)
+	for i := range want.Sums {
+		if got.Sums[i] != want.Sums[i] {
+			t.Errorf("tile %d of the images is %d, want %d", i, got.Sums[i], want.Sums[i])
+		}
+	}
+}
+
+func TestSumSquaresBelow(t *testing.T) {
+	r := rand.New(rand.NewPCG(13, 14))
+	a, b := randomBytes(r, 3*boundChunk+5)
+	total := portable(a, b)
+	if sum, ok := SumSquaresBelow(a, b, total); !ok || sum != total {
+		t.Errorf("SumSquaresBelow the total is %d, %v, want %d, true", sum, ok, total)
+	}
+	if sum, ok := SumSquaresBelow(a, b, total-1); ok || sum > total {
+		t.Errorf("SumSquaresBelow less than the total is %d, %v, want at most %d, false", sum, ok, total)
+	}
+}
+
+// whether the sums are the same but for rounding
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
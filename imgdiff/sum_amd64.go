@@ -0,0 +1,26 @@
+//go:build amd64 && !purego
+
+package imgdiff
+
+// the sum of the squares of 16 bytes at a time with SSE2, which every amd64
+// processor has, with the bytes left over added up in Go
+func sumSquares(a, b []byte) uint64 {
+	n := len(a) &^ 15
+	return sumSquaresSSE2(a[:n], b[:n]) + portable(a[n:], b[n:])
+}
+
+// the sum of the squares of a and b, whose length is a multiple of 16
+//
+//go:noescape
+func sumSquaresSSE2(a, b []byte) uint64
+
+// the sum of the squares against a packed target, 16 bytes at a time
+func sumSquaresWords(a []byte, words []uint16) uint64 {
+	n := len(a) &^ 15
+	return sumSquaresWordsSSE2(a[:n], words[:n]) + portableWords(a[n:], words[n:])
+}
+
+// the sum of the squares of a and the words, whose length is a multiple of 16
+//
+//go:noescape
+func sumSquaresWordsSSE2(a []byte, words []uint16) uint64
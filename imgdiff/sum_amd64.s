@@ -0,0 +1,85 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func sumSquaresSSE2(a, b []byte) uint64
+TEXT ·sumSquaresSSE2(SB), NOSPLIT, $0-56
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ b_base+24(FP), DI
+	PXOR X0, X0 // zero, to widen bytes to words and longs to quads
+	PXOR X7, X7 // 2 quads of sums
+	SHRQ $4, CX
+	JZ   done
+
+loop:
+	MOVOU (SI), X1
+	MOVOU (DI), X2
+	MOVO  X1, X3
+	MOVO  X2, X4
+	PUNPCKLBW X0, X1
+	PUNPCKHBW X0, X3
+	PUNPCKLBW X0, X2
+	PUNPCKHBW X0, X4
+	PSUBW X2, X1 // the differences of the low 8 bytes as words
+	PSUBW X4, X3 // and of the high 8 bytes
+	PMADDWL X1, X1 // squared and added in pairs, 4 longs
+	PMADDWL X3, X3
+	PADDL X3, X1
+	MOVO  X1, X2
+	PUNPCKLLQ X0, X1 // widened to quads, so the sums can't overflow
+	PUNPCKHLQ X0, X2
+	PADDQ X1, X7
+	PADDQ X2, X7
+	ADDQ  $16, SI
+	ADDQ  $16, DI
+	DECQ  CX
+	JNZ   loop
+
+done:
+	MOVQ   X7, AX
+	PSHUFD $0xee, X7, X7
+	MOVQ   X7, BX
+	ADDQ   BX, AX
+	MOVQ   AX, ret+48(FP)
+	RET
+
+// func sumSquaresWordsSSE2(a []byte, words []uint16) uint64
+TEXT ·sumSquaresWordsSSE2(SB), NOSPLIT, $0-56
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ words_base+24(FP), DI
+	PXOR X0, X0
+	PXOR X7, X7
+	SHRQ $4, CX
+	JZ   wordsdone
+
+wordsloop:
+	MOVOU (SI), X1
+	MOVO  X1, X3
+	PUNPCKLBW X0, X1
+	PUNPCKHBW X0, X3
+	MOVOU (DI), X2 // the target is already in words
+	MOVOU 16(DI), X4
+	PSUBW X2, X1
+	PSUBW X4, X3
+	PMADDWL X1, X1
+	PMADDWL X3, X3
+	PADDL X3, X1
+	MOVO  X1, X2
+	PUNPCKLLQ X0, X1
+	PUNPCKHLQ X0, X2
+	PADDQ X1, X7
+	PADDQ X2, X7
+	ADDQ  $16, SI
+	ADDQ  $32, DI
+	DECQ  CX
+	JNZ   wordsloop
+
+wordsdone:
+	MOVQ   X7, AX
+	PSHUFD $0xee, X7, X7
+	MOVQ   X7, BX
+	ADDQ   BX, AX
+	MOVQ   AX, ret+48(FP)
+	RET
@@ -0,0 +1,11 @@
+//go:build !amd64 || purego
+
+package imgdiff
+
+func sumSquares(a, b []byte) uint64 {
+	return portable(a, b)
+}
+
+func sumSquaresWords(a []byte, words []uint16) uint64 {
+	return portableWords(a, words)
+}
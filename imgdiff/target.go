@@ -0,0 +1,78 @@
+package imgdiff
+
+import "sync"
+
+// Target is an image that others are compared with many times, kept with
+// every byte already widened to 16 bits, which is how they are compared. That
+// saves unpacking the bytes of the target again for every comparison. A
+// target bigger than MaxPacked isn't widened, and is compared byte by byte
+type Target struct {
+	pix   []byte
+	words []uint16
+}
+
+// MaxPacked is the most bytes a target can have to be packed. The words take
+// twice the memory of the bytes, which for a target of hundreds of megapixels
+// costs more than it saves
+var MaxPacked = 64 << 20
+
+// NewTarget packs the bytes of the target, like the Pix of an image
+func NewTarget(pix []byte) *Target {
+	if len(pix) > MaxPacked {
+		return &Target{pix: pix}
+	}
+	t := &Target{pix: pix, words: make([]uint16, len(pix))}
+	for i, p := range pix {
+		t.words[i] = uint16(p)
+	}
+	return t
+}
+
+// SumSquares is the sum of the squares of the differences between the bytes
+// of a and those of the target, which are the same length
+func (t *Target) SumSquares(a []byte) uint64 {
+	if len(a) != len(t.pix) {
+		panic("imgdiff: slices of different lengths")
+	}
+	if t.words == nil {
+		return sumSquares(a, t.pix)
+	}
+	return sumSquaresWords(a, t.words)
+}
+
+// the targets packed most recently, the latest first
+var (
+	packedMu sync.Mutex
+	packed   []*Target
+)
+
+// Recent is how many of the targets packed most recently are kept
+var Recent = 4
+
+// Packed is the target packed from the bytes, which is packed the first time
+// and kept until Recent other targets have been packed since it was last
+// used. The bytes mustn't change while it's kept
+func Packed(pix []byte) *Target {
+	packedMu.Lock()
+	defer packedMu.Unlock()
+	for i, t := range packed {
+		if len(t.pix) == len(pix) && (len(pix) == 0 || &t.pix[0] == &pix[0]) {
+			copy(packed[1:i+1], packed[:i])
+			packed[0] = t
+			return t
+		}
+	}
+	t := NewTarget(pix)
+	packed = append([]*Target{t}, packed[:min(len(packed), Recent-1)]...)
+	return t
+}
+
+// the sum of the squares of the words in plain Go
+func portableWords(a []byte, words []uint16) (sum uint64) {
+	words = words[:len(a)]
+	for i := range a {
+		d := int(a[i]) - int(words[i])
+		sum += uint64(d * d)
+	}
+	return
+}
@@ -0,0 +1,104 @@
+package imgdiff
+
+import "image"
+
+// Tiles are the sums of the squares of the differences between 2 images, for
+// each tile of a grid laid over them. Added up they're the SumSquares of the
+// images, and apart they show where the images differ the most
+type Tiles struct {
+	// Cols and Rows are the number of tiles across and down
+	Cols, Rows int
+	// Width and Height are the size of the images in pixels
+	Width, Height int
+	// Sums are the sums of the tiles, a row at a time from the top left
+	Sums []uint64
+}
+
+// TileSums is the sum of the squares for each of cols by rows tiles of a and
+// b, the Pix of 2 RGBA images of width by height pixels with no gaps between
+// their rows. When the size doesn't divide evenly some tiles are a pixel
+// bigger than others
+func TileSums(a, b []byte, width, height, cols, rows int) Tiles {
+	if len(a) != len(b) {
+		panic("imgdiff: slices of different lengths")
+	}
+	if len(a) != width*height*4 {
+		panic("imgdiff: slices not the size of the images")
+	}
+	cols, rows = max(min(cols, width), 1), max(min(rows, height), 1)
+	t := Tiles{Cols: cols, Rows: rows, Width: width, Height: height, Sums: make([]uint64, cols*rows)}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			r := t.Rect(col, row)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				start, end := (y*width+r.Min.X)*4, (y*width+r.Max.X)*4
+				t.Sums[row*cols+col] += sumSquares(a[start:end], b[start:end])
+			}
+		}
+	}
+	return t
+}
+
+// TileSumsImages is TileSums of 2 RGBA images of the same size, read a row at
+// a time through their strides, so either can be a view into a bigger image
+func TileSumsImages(a, b *image.RGBA, cols, rows int) Tiles {
+	width, height := a.Rect.Dx(), a.Rect.Dy()
+	cols, rows = max(min(cols, width), 1), max(min(rows, height), 1)
+	t := Tiles{Cols: cols, Rows: rows, Width: width, Height: height, Sums: make([]uint64, cols*rows)}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			r := t.Rect(col, row)
+			t.Sums[row*cols+col] = SumSquaresImages(a.SubImage(r.Add(a.Rect.Min)).(*image.RGBA), b.SubImage(r.Add(b.Rect.Min)).(*image.RGBA))
+		}
+	}
+	return t
+}
+
+// Rect is the part of the images the tile covers
+func (t Tiles) Rect(col, row int) image.Rectangle {
+	return image.Rect(t.Width*col/t.Cols, t.Height*row/t.Rows, t.Width*(col+1)/t.Cols, t.Height*(row+1)/t.Rows)
+}
+
+// At is the sum of the tile
+func (t Tiles) At(col, row int) uint64 {
+	return t.Sums[row*t.Cols+col]
+}
+
+// Total is the sum of all the tiles, the same as SumSquares of the images
+func (t Tiles) Total() (total uint64) {
+	for _, s := range t.Sums {
+		total += s
+	}
+	return
+}
+
+// Worst is the tile with the largest sum, where the images differ the most
+func (t Tiles) Worst() (col, row int) {
+	worst := 0
+	for i, s := range t.Sums {
+		if s > t.Sums[worst] {
+			worst = i
+		}
+	}
+	return worst % t.Cols, worst / t.Cols
+}
+
+// number of bytes added up between checks on the bound
+const boundChunk = 4096
+
+// SumSquaresBelow is SumSquares of a and b, added up a piece at a time so it
+// can stop as soon as the sum so far is over the bound. It reports whether
+// the sum is at most the bound, and if it isn't the sum is only part of it
+func SumSquaresBelow(a, b []byte, bound uint64) (sum uint64, ok bool) {
+	if len(a) != len(b) {
+		panic("imgdiff: slices of different lengths")
+	}
+	for start := 0; start < len(a); start += boundChunk {
+		end := min(start+boundChunk, len(a))
+		sum += sumSquares(a[start:end], b[start:end])
+		if sum > bound {
+			return sum, false
+		}
+	}
+	return sum, true
+}
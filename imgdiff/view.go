@@ -0,0 +1,43 @@
+package imgdiff
+
+import "image"
+
+// SumSquaresImages is SumSquares of 2 RGBA images of the same size, read a
+// row at a time through their strides. Either can be a view into a bigger
+// image, like a SubImage or a memory mapped file, which is compared where it
+// is without being copied into an image of its own
+func SumSquaresImages(a, b *image.RGBA) (sum uint64) {
+	rowsOf(a, b, func(ra, rb []byte) {
+		sum += sumSquares(ra, rb)
+	})
+	return
+}
+
+// WeightedSumSquaresImages is WeightedSumSquares of 2 RGBA images of the same
+// size, read a row at a time through their strides
+func WeightedSumSquaresImages(a, b *image.RGBA, w Weights) (sum float64) {
+	rowsOf(a, b, func(ra, rb []byte) {
+		sum += WeightedSumSquares(ra, rb, w)
+	})
+	return
+}
+
+// call f with the bytes of each row of a and of b, or once with all of them if
+// neither has gaps between its rows
+func rowsOf(a, b *image.RGBA, f func(ra, rb []byte)) {
+	w, h := a.Rect.Dx(), a.Rect.Dy()
+	if w != b.Rect.Dx() || h != b.Rect.Dy() {
+		panic("imgdiff: images of different sizes")
+	}
+	if w == 0 || h == 0 {
+		return
+	}
+	if a.Stride == w*4 && b.Stride == w*4 {
+		f(a.Pix[:w*h*4], b.Pix[:w*h*4])
+		return
+	}
+	for y := 0; y < h; y++ {
+		start, end := a.Stride*y, a.Stride*y+w*4
+		f(a.Pix[start:end], b.Pix[b.Stride*y:b.Stride*y+w*4])
+	}
+}
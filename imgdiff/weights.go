@@ -0,0 +1,93 @@
+package imgdiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Weights are how much the differences in each channel of an RGBA pixel
+// count, red, green, blue and alpha, in that order
+type Weights [4]float64
+
+// Even counts every channel the same, as SumSquares does
+var Even = Weights{1, 1, 1, 1}
+
+// Luminance counts the colors by how much they add to the brightness of a
+// pixel, as the Rec. 601 luma does, so a difference in green counts for more
+// than the same difference in blue, as it does to the eye
+var Luminance = Weights{0.299, 0.587, 0.114, 1}
+
+// SetColors sets the weights of red, green and blue from a list like
+// 0.299,0.587,0.114, or luminance or even, leaving the weight of alpha
+func (w *Weights) SetColors(s string) error {
+	switch strings.ToLower(s) {
+	case "luminance":
+		copy(w[:3], Luminance[:3])
+		return nil
+	case "even":
+		copy(w[:3], Even[:3])
+		return nil
+	}
+	fields := strings.Split(s, ",")
+	if len(fields) != 3 {
+		return fmt.Errorf("need 3 weights, for red, green and blue, in %q", s)
+	}
+	var colors [3]float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return err
+		}
+		if v < 0 {
+			return fmt.Errorf("weight %v is negative", v)
+		}
+		colors[i] = v
+	}
+	copy(w[:3], colors[:])
+	return nil
+}
+
+// Channels are the sums of the squares of the differences between the bytes
+// of a and b, which are the same length like the Pix of 2 RGBA images, for
+// each channel
+func Channels(a, b []byte) (sums [4]uint64) {
+	if len(a) != len(b) {
+		panic("imgdiff: slices of different lengths")
+	}
+	b = b[:len(a)]
+	for i := 0; i+3 < len(a); i += 4 {
+		r, g, bl, al := int(a[i])-int(b[i]), int(a[i+1])-int(b[i+1]), int(a[i+2])-int(b[i+2]), int(a[i+3])-int(b[i+3])
+		sums[0] += uint64(r * r)
+		sums[1] += uint64(g * g)
+		sums[2] += uint64(bl * bl)
+		sums[3] += uint64(al * al)
+	}
+	return
+}
+
+// WeightedSumSquares is SumSquares with the square of each difference
+// multiplied by the weight of its channel. With even weights it's as fast as
+// SumSquares, and with only alpha weighted apart almost as fast
+func WeightedSumSquares(a, b []byte, w Weights) float64 {
+	return weighted(func() uint64 { return SumSquares(a, b) }, a, b, w)
+}
+
+// WeightedSumSquares is SumSquares of the target with the square of each
+// difference multiplied by the weight of its channel
+func (t *Target) WeightedSumSquares(a []byte, w Weights) float64 {
+	return weighted(func() uint64 { return t.SumSquares(a) }, a, t.pix, w)
+}
+
+// the weighted sum, using the sum of all the channels if the colors are
+// weighted evenly
+func weighted(sum func() uint64, a, b []byte, w Weights) float64 {
+	if w[0] == 1 && w[1] == 1 && w[2] == 1 {
+		if w[3] == 1 {
+			return float64(sum())
+		}
+		return float64(sum()) - (1-w[3])*float64(SumSquaresAlpha(a, b))
+	}
+	c := Channels(a, b)
+	return w[0]*float64(c[0]) + w[1]*float64(c[1]) + w[2]*float64(c[2]) + w[3]*float64(c[3])
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// apply the convolution kernel to every color channel of the image, the
+// kernel being a square of weights stored row by row
+func convolve(img *image.RGBA, kernel []float64) *image.RGBA {
+	size := int(math.Sqrt(float64(len(kernel))))
+	half := size / 2
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	out := image.NewRGBA(img.Rect)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum [3]float64
+			for ky := 0; ky < size; ky++ {
+				for kx := 0; kx < size; kx++ {
+					// repeat the pixels at the edges
+					sx, sy := clamp(x+kx-half, 0, w-1), clamp(y+ky-half, 0, h-1)
+					i := sy*img.Stride + sx*4
+					weight := kernel[ky*size+kx]
+					sum[0] += float64(img.Pix[i]) * weight
+					sum[1] += float64(img.Pix[i+1]) * weight
+					sum[2] += float64(img.Pix[i+2]) * weight
+				}
+			}
+			o := y*out.Stride + x*4
+			for c := 0; c < 3; c++ {
+				out.Pix[o+c] = uint8(clamp(int(math.Round(sum[c])), 0, 255))
+			}
+			out.Pix[o+3] = 255
+		}
+	}
+	return out
+}
+
+// how different the filtered input is from the expected output, the
+// fitness of a kernel
+func filterDiff(input, output *image.RGBA, kernel []float64) int64 {
+	filtered := convolve(input, kernel)
+	var d int64
+	for i := 0; i < len(filtered.Pix); i++ {
+		x := int64(filtered.Pix[i]) - int64(output.Pix[i])
+		d += x * x
+	}
+	return int64(math.Sqrt(float64(d)))
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sausheong/ga/optimize"
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation
+var MutationRate = 0.2
+
+// MutationSize is the standard deviation of the change to a mutated weight
+var MutationSize = 0.1
+
+// PopSize is the size of the population
+var PopSize = 100
+
+// PoolSize is the max size of the pool
+var PoolSize = 20
+
+// KernelSize is the width and height of the kernel
+var KernelSize = 3
+
+// FitnessLimit is the fitness of the evolved kernel we are satisfied with
+var FitnessLimit int64 = 300
+
+// Generations is the most generations to evolve, or 0 to evolve until the
+// fitness is under FitnessLimit
+var Generations = 0
+
+// Strategy is the way the kernel is evolved, ga for the genetic algorithm,
+// or de or cmaes for the optimizers in the optimize package
+var Strategy = "ga"
+
+// WeightLimit is the largest weight, positive or negative, searched by the
+// optimizers other than the genetic algorithm
+var WeightLimit = 8.0
+
+// the kernel used to make the output when none is given, which sharpens
+var hiddenKernel = []float64{
+	0, -1, 0,
+	-1, 5, -1,
+	0, -1, 0,
+}
+
+func main() {
+	inputFile := flag.String("input", "./ml.png", "input image")
+	outputFile := flag.String("output", "", "output image the evolved kernel should turn the input into, by default the input sharpened")
+	flag.IntVar(&KernelSize, "size", KernelSize, "width and height of the kernel, an odd number")
+	flag.StringVar(&Strategy, "strategy", Strategy, "ga for the genetic algorithm, de for differential evolution or cmaes for CMA-ES")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve, 0 to evolve until the fitness limit")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	if KernelSize < 1 || KernelSize%2 == 0 {
+		fmt.Println("Cannot evolve kernel: size needs to be an odd number")
+		return
+	}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	input := load(*inputFile)
+	var output *image.RGBA
+	if *outputFile == "" {
+		// the hidden kernel is 3x3 so that's what we evolve
+		KernelSize = 3
+		output = convolve(input, hiddenKernel)
+		fmt.Println("Evolving a kernel to match:")
+		printKernel(hiddenKernel)
+	} else {
+		output = load(*outputFile)
+		if output.Rect.Dx() != input.Rect.Dx() || output.Rect.Dy() != input.Rect.Dy() {
+			fmt.Println("Cannot evolve kernel: input and output need to be the same size")
+			return
+		}
+	}
+	if Strategy != "ga" {
+		evolveWith(Strategy, input, output, start)
+		return
+	}
+	population := createPopulation(input, output)
+
+	found := false
+	generation := 0
+	for !found && (Generations == 0 || generation < Generations) {
+		generation++
+		bestOrganism := getBest(population)
+		if bestOrganism.Fitness < FitnessLimit {
+			found = true
+		} else {
+			pool := createPool(population)
+			population = naturalSelection(pool, population, input, output)
+			if generation%10 == 0 {
+				sofar := time.Since(start)
+				fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | pool size: %d\n", sofar, generation, bestOrganism.Fitness, len(pool))
+				printKernel(bestOrganism.DNA)
+			}
+		}
+	}
+	bestOrganism := getBest(population)
+	save("./evolved.png", convolve(input, bestOrganism.DNA))
+	fmt.Printf("\nEvolved kernel with fitness %d:\n", bestOrganism.Fitness)
+	printKernel(bestOrganism.DNA)
+	elapsed := time.Since(start)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// evolve the kernel with one of the optimizers instead of the genetic
+// algorithm, to compare them
+func evolveWith(strategy string, input, output *image.RGBA, start time.Time) {
+	problem := optimize.Problem{
+		F: func(kernel []float64) float64 {
+			return float64(filterDiff(input, output, kernel))
+		},
+		Dimensions: KernelSize * KernelSize,
+		Min:        -WeightLimit,
+		Max:        WeightLimit,
+	}
+	var optimizer optimize.Optimizer
+	switch strategy {
+	case "de":
+		optimizer = optimize.NewDE(problem, PopSize)
+	case "cmaes":
+		// start with small weights like the genetic algorithm, as large ones
+		// saturate the image and every kernel looks as bad as the next
+		es := optimize.NewCMAES(problem, 0)
+		for i := range es.Mean {
+			es.Mean[i] = rng.Float64()*2 - 1
+		}
+		es.Sigma = 0.5
+		optimizer = es
+	default:
+		fmt.Println("Cannot find strategy:", strategy)
+		return
+	}
+
+	var kernel []float64
+	fitness := math.Inf(1)
+	for generation := 1; fitness >= float64(FitnessLimit) && (Generations == 0 || generation <= Generations); generation++ {
+		kernel, fitness = optimizer.Step()
+		if generation%10 == 0 {
+			sofar := time.Since(start)
+			fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d\n", sofar, generation, int64(fitness))
+			printKernel(kernel)
+		}
+	}
+	save("./evolved.png", convolve(input, kernel))
+	fmt.Printf("\nEvolved kernel with fitness %d:\n", int64(fitness))
+	printKernel(kernel)
+	elapsed := time.Since(start)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// print the kernel as a grid of weights
+func printKernel(kernel []float64) {
+	for y := 0; y < KernelSize; y++ {
+		row := make([]string, KernelSize)
+		for x := 0; x < KernelSize; x++ {
+			row[x] = fmt.Sprintf("%6.2f", kernel[y*KernelSize+x])
+		}
+		fmt.Println(strings.Join(row, " "))
+	}
+}
+
+// save the image
+func save(filePath string, rgba *image.RGBA) {
+	imgFile, err := os.Create(filePath)
+	if err != nil {
+		fmt.Println("Cannot create file:", err)
+		return
+	}
+	defer imgFile.Close()
+	png.Encode(imgFile, rgba)
+}
+
+// load the image
+func load(filePath string) *image.RGBA {
+	imgFile, err := os.Open(filePath)
+	if err != nil {
+		fmt.Println("Cannot read file:", err)
+		os.Exit(1)
+	}
+	defer imgFile.Close()
+
+	img, _, err := image.Decode(imgFile)
+	if err != nil {
+		fmt.Println("Cannot decode file:", err)
+		os.Exit(1)
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			rgba.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return rgba
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation
+	for i := 0; i < len(top)-1; i++ {
+		num := (top[PoolSize].Fitness - top[i].Fitness)
+		for n := int64(0); n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, input, output *image.RGBA) []Organism {
+	next := make([]Organism, len(population))
+
+	for i := 0; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.calcFitness(input, output)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(input, output *image.RGBA) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(input, output)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a convolution kernel, its DNA being the real-valued weights
+type Organism struct {
+	DNA     []float64
+	Fitness int64
+}
+
+// creates an organism with random weights between -1 and 1
+func createOrganism(input, output *image.RGBA) (organism Organism) {
+	weights := make([]float64, KernelSize*KernelSize)
+	for i := 0; i < len(weights); i++ {
+		weights[i] = rng.Float64()*2 - 1
+	}
+	organism = Organism{
+		DNA:     weights,
+		Fitness: 0,
+	}
+	organism.calcFitness(input, output)
+	return
+}
+
+// calculates the fitness of the Organism by filtering the input with it
+func (o *Organism) calcFitness(input, output *image.RGBA) {
+	o.Fitness = filterDiff(input, output, o.DNA)
+}
+
+// crosses over 2 Organisms
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]float64, len(d1.DNA)),
+		Fitness: 0,
+	}
+	mid := rng.Intn(len(d1.DNA))
+	for i := 0; i < len(d1.DNA); i++ {
+		if i > mid {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism by nudging weights by a normally distributed amount
+func (o *Organism) mutate() {
+	for i := 0; i < len(o.DNA); i++ {
+		if rng.Float64() < MutationRate {
+			o.DNA[i] += rng.NormFloat64() * MutationSize
+		}
+	}
+}
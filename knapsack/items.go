@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Item is something that can be packed into the knapsack
+type Item struct {
+	Name   string
+	Weight float64
+	Value  float64
+}
+
+// the items to pack when no file is given
+var defaultItems = []Item{
+	{"map", 0.9, 150},
+	{"compass", 1.3, 35},
+	{"water", 15.3, 200},
+	{"sandwich", 5.0, 160},
+	{"glucose", 1.5, 60},
+	{"tin", 6.8, 45},
+	{"banana", 2.7, 60},
+	{"apple", 3.9, 40},
+	{"cheese", 2.3, 30},
+	{"beer", 5.2, 10},
+	{"suntan cream", 1.1, 70},
+	{"camera", 3.2, 30},
+	{"t-shirt", 2.4, 15},
+	{"trousers", 4.8, 10},
+	{"umbrella", 7.3, 40},
+	{"waterproof trousers", 4.2, 70},
+	{"waterproof overclothes", 4.3, 75},
+	{"note-case", 2.2, 80},
+	{"sunglasses", 0.7, 20},
+	{"towel", 1.8, 12},
+	{"socks", 0.4, 50},
+	{"book", 3.0, 10},
+}
+
+// read the items from a CSV file, with the name, weight and value in each
+// row, skipping the header row if there is one
+func readItems(filePath string) (items []Item, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return
+	}
+	for n, record := range records {
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: need a name, weight and value", n+1)
+		}
+		weight, werr := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		value, verr := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if werr != nil || verr != nil {
+			if n == 0 {
+				// the header
+				continue
+			}
+			return nil, fmt.Errorf("line %d: cannot parse weight and value", n+1)
+		}
+		items = append(items, Item{Name: record[0], Weight: weight, Value: value})
+	}
+	return
+}
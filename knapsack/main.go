@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation
+var MutationRate = 0.02
+
+// PopSize is the size of the population
+var PopSize = 200
+
+// PoolSize is the max size of the pool
+var PoolSize = 40
+
+// Generations is the number of generations to evolve
+var Generations = 500
+
+// Capacity is the most weight the knapsack can carry
+var Capacity = 40.0
+
+// Penalty is how much value is taken away for every unit of weight over the
+// capacity when overweight knapsacks are penalized instead of repaired, in
+// multiples of the most valuable item for its weight so being overweight is
+// never worth it
+var Penalty = 2.0
+
+// the value taken away for every unit of weight over the capacity
+var penaltyPerWeight float64
+
+// Repair fixes overweight knapsacks by taking items out, instead of
+// penalizing them
+var Repair = false
+
+func main() {
+	itemsFile := flag.String("items", "", "CSV file with the name, weight and value of each item")
+	flag.Float64Var(&Capacity, "capacity", Capacity, "most weight the knapsack can carry")
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	flag.BoolVar(&Repair, "repair", Repair, "take items out of overweight knapsacks instead of penalizing them")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	items := defaultItems
+	if *itemsFile != "" {
+		var err error
+		items, err = readItems(*itemsFile)
+		if err != nil {
+			fmt.Println("Cannot read items:", err)
+			return
+		}
+	}
+	if len(items) < 2 {
+		fmt.Println("Cannot pack the knapsack: need at least 2 items")
+		return
+	}
+	for _, item := range items {
+		if item.Weight > 0 && Penalty*item.Value/item.Weight > penaltyPerWeight {
+			penaltyPerWeight = Penalty * item.Value / item.Weight
+		}
+	}
+	population := createPopulation(items)
+
+	for generation := 1; generation <= Generations; generation++ {
+		bestOrganism := getBest(population)
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism, items)
+		if generation%50 == 0 || generation == Generations {
+			weight, value := bestOrganism.pack(items)
+			fmt.Printf("generation: %d | weight: %.2f | value: %.2f | fitness: %.2f | pool size: %d\n", generation, weight, value, bestOrganism.Fitness, len(pool))
+		}
+	}
+	best := getBest(population)
+	fmt.Println("\nBest knapsack:")
+	for i, in := range best.DNA {
+		if in {
+			fmt.Printf("  %s (weight: %.2f, value: %.2f)\n", items[i].Name, items[i].Weight, items[i].Value)
+		}
+	}
+	weight, value := best.pack(items)
+	fmt.Printf("Total weight: %.2f of %.2f | total value: %.2f\n", weight, Capacity, value)
+	elapsed := time.Since(start)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness > population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	spread := top[0].Fitness - top[PoolSize].Fitness
+	if spread == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with up to 100 copies of the best
+	for i := 0; i < len(top)-1; i++ {
+		num := int(100 * (top[i].Fitness - top[PoolSize].Fitness) / spread)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, items []Item) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best knapsack so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.calcFitness(items)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(items []Item) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(items)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness > population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a packed knapsack, with a bit for every item that is true if
+// the item is in the knapsack
+type Organism struct {
+	DNA     []bool
+	Fitness float64
+}
+
+// creates an organism with random items, each with an even chance of being
+// packed
+func createOrganism(items []Item) (organism Organism) {
+	bits := make([]bool, len(items))
+	for i := 0; i < len(bits); i++ {
+		bits[i] = rng.Intn(2) == 0
+	}
+	organism = Organism{
+		DNA:     bits,
+		Fitness: 0,
+	}
+	organism.calcFitness(items)
+	return
+}
+
+// the total weight and value of the packed items
+func (o *Organism) pack(items []Item) (weight, value float64) {
+	for i, in := range o.DNA {
+		if in {
+			weight += items[i].Weight
+			value += items[i].Value
+		}
+	}
+	return
+}
+
+// calculates the fitness of the Organism, which is the value of the items,
+// less a penalty if the knapsack is overweight
+func (o *Organism) calcFitness(items []Item) {
+	if Repair {
+		o.repair(items)
+	}
+	weight, value := o.pack(items)
+	if weight > Capacity {
+		value -= penaltyPerWeight * (weight - Capacity)
+	}
+	o.Fitness = value
+}
+
+// take random items out of the knapsack until it's within the capacity
+func (o *Organism) repair(items []Item) {
+	weight, _ := o.pack(items)
+	for _, i := range rng.Perm(len(o.DNA)) {
+		if weight <= Capacity {
+			return
+		}
+		if o.DNA[i] {
+			o.DNA[i] = false
+			weight -= items[i].Weight
+		}
+	}
+}
+
+// crosses over 2 Organisms
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]bool, len(d1.DNA)),
+		Fitness: 0,
+	}
+	mid := rng.Intn(len(d1.DNA))
+	for i := 0; i < len(d1.DNA); i++ {
+		if i > mid {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism by flipping bits
+func (o *Organism) mutate() {
+	for i := 0; i < len(o.DNA); i++ {
+		if rng.Float64() < MutationRate {
+			o.DNA[i] = !o.DNA[i]
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each move
+var MutationRate = 0.02
+
+// PopSize is the size of the population
+var PopSize = 200
+
+// PoolSize is the max size of the pool
+var PoolSize = 30
+
+// Generations is the number of generations to evolve
+var Generations = 1000
+
+// DistanceWeight is how much more the distance left to the exit counts in
+// the fitness than the length of the path
+var DistanceWeight = 10
+
+func main() {
+	mazeFile := flag.String("maze", "", "text file with the maze, # for walls, S for the start and E for the exit")
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	maze, err := readMaze(*mazeFile)
+	if err != nil {
+		fmt.Println("Cannot read maze:", err)
+		return
+	}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	population := createPopulation(maze)
+
+	var bestOrganism Organism
+	for generation := 1; generation <= Generations; generation++ {
+		bestOrganism = getBest(population)
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism, maze)
+		if generation%100 == 0 {
+			sofar := time.Since(start)
+			path := maze.walk(bestOrganism.DNA)
+			last := path[len(path)-1]
+			fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | moves: %d | distance to exit: %d\n", sofar, generation, bestOrganism.Fitness, len(path)-1, maze.distance[last.Y][last.X])
+			fmt.Print(maze.render(path))
+		}
+	}
+	elapsed := time.Since(start)
+	path := maze.walk(bestOrganism.DNA)
+	if path[len(path)-1] == maze.Exit {
+		fmt.Printf("\nFound the exit in %d moves, the shortest path is %d moves\n", len(path)-1, maze.distance[maze.Start.Y][maze.Start.X])
+	} else {
+		fmt.Println("\nDidn't find the exit")
+	}
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation
+	for i := 0; i < len(top)-1; i++ {
+		num := (top[PoolSize].Fitness - top[i].Fitness)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, maze *Maze) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best path so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.calcFitness(maze)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(maze *Maze) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(maze)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a sequence of moves, of any length
+type Organism struct {
+	DNA     []int
+	Fitness int
+}
+
+// creates an organism with a few random moves
+func createOrganism(maze *Maze) (organism Organism) {
+	organism = Organism{
+		DNA:     make([]int, 1+rng.Intn(20)),
+		Fitness: 0,
+	}
+	for i := range organism.DNA {
+		organism.DNA[i] = rng.Intn(len(moves))
+	}
+	organism.calcFitness(maze)
+	return
+}
+
+// calculates the fitness of the Organism, which is how far from the exit the
+// moves end up, plus how many moves it took to get there
+func (o *Organism) calcFitness(maze *Maze) {
+	path := maze.walk(o.DNA)
+	last := path[len(path)-1]
+	o.Fitness = DistanceWeight*maze.distance[last.Y][last.X] + len(path) - 1
+}
+
+// crosses over 2 Organisms of different lengths, cutting both at the same
+// relative position
+func crossover(d1 Organism, d2 Organism) Organism {
+	cut := rng.Float64()
+	mid1 := int(cut * float64(len(d1.DNA)))
+	mid2 := int(cut * float64(len(d2.DNA)))
+	child := Organism{
+		DNA:     make([]int, 0, mid1+len(d2.DNA)-mid2),
+		Fitness: 0,
+	}
+	child.DNA = append(child.DNA, d1.DNA[:mid1]...)
+	child.DNA = append(child.DNA, d2.DNA[mid2:]...)
+	if len(child.DNA) == 0 {
+		child.DNA = append(child.DNA, rng.Intn(len(moves)))
+	}
+	return child
+}
+
+// mutate the Organism by changing, inserting or deleting moves, and now and
+// then adding a move at the end so the path can grow
+func (o *Organism) mutate() {
+	dna := make([]int, 0, len(o.DNA)+1)
+	for _, move := range o.DNA {
+		if rng.Float64() >= MutationRate {
+			dna = append(dna, move)
+			continue
+		}
+		switch rng.Intn(3) {
+		case 0:
+			dna = append(dna, rng.Intn(len(moves)))
+		case 1:
+			dna = append(dna, rng.Intn(len(moves)), move)
+		case 2:
+			// deleted, by not copying it over
+		}
+	}
+	if len(dna) == 0 || rng.Float64() < 0.5 {
+		dna = append(dna, rng.Intn(len(moves)))
+	}
+	o.DNA = dna
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Point is a square of the maze
+type Point struct {
+	X int
+	Y int
+}
+
+// the moves, up, right, down and left
+var moves = []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+// Maze is a grid of walls with a start and an exit
+type Maze struct {
+	Walls [][]bool
+	Start Point
+	Exit  Point
+	// the number of moves from every square to the exit, -1 if it can't be
+	// reached
+	distance [][]int
+}
+
+// the maze to use when none is given
+var defaultMaze = `
+#####################
+#S  #     #       # #
+### # ### # ##### # #
+#   #   #   #   #   #
+# ##### ##### # ### #
+#     #     # #   # #
+##### # ### # ### # #
+#   # #   # #   #   #
+# # # ### # ### #####
+# #   #   #   #     #
+# ##### ##### ##### #
+#     #     #     # #
+# ### ##### ##### # #
+#   #           #  E#
+#####################
+`
+
+// read the maze from a text file, with # for the walls, S for the start and E
+// for the exit
+func readMaze(filePath string) (*Maze, error) {
+	text := defaultMaze
+	if filePath != "" {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	return parseMaze(text)
+}
+
+// parse the maze from text
+func parseMaze(text string) (*Maze, error) {
+	m := &Maze{}
+	foundStart, foundExit := false, false
+	for _, line := range strings.Split(strings.Trim(text, "\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		row := make([]bool, len(line))
+		for x, c := range line {
+			switch c {
+			case '#':
+				row[x] = true
+			case 'S':
+				m.Start, foundStart = Point{x, len(m.Walls)}, true
+			case 'E':
+				m.Exit, foundExit = Point{x, len(m.Walls)}, true
+			}
+		}
+		m.Walls = append(m.Walls, row)
+	}
+	if !foundStart || !foundExit {
+		return nil, fmt.Errorf("the maze needs a start S and an exit E")
+	}
+	m.distances()
+	if m.distance[m.Start.Y][m.Start.X] < 0 {
+		return nil, fmt.Errorf("the exit can't be reached from the start")
+	}
+	return m, nil
+}
+
+// true if the square is a wall, or outside the maze
+func (m *Maze) wall(p Point) bool {
+	if p.Y < 0 || p.Y >= len(m.Walls) || p.X < 0 || p.X >= len(m.Walls[p.Y]) {
+		return true
+	}
+	return m.Walls[p.Y][p.X]
+}
+
+// work out the number of moves from every square to the exit, with a
+// breadth first search from the exit
+func (m *Maze) distances() {
+	m.distance = make([][]int, len(m.Walls))
+	for y := range m.distance {
+		m.distance[y] = make([]int, len(m.Walls[y]))
+		for x := range m.distance[y] {
+			m.distance[y][x] = -1
+		}
+	}
+	m.distance[m.Exit.Y][m.Exit.X] = 0
+	queue := []Point{m.Exit}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, move := range moves {
+			n := Point{p.X + move.X, p.Y + move.Y}
+			if !m.wall(n) && m.distance[n.Y][n.X] < 0 {
+				m.distance[n.Y][n.X] = m.distance[p.Y][p.X] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+}
+
+// follow the moves from the start, ignoring those into walls, stopping at
+// the exit. Returns the squares visited
+func (m *Maze) walk(dna []int) (path []Point) {
+	p := m.Start
+	path = append(path, p)
+	for _, move := range dna {
+		if p == m.Exit {
+			break
+		}
+		n := Point{p.X + moves[move].X, p.Y + moves[move].Y}
+		if !m.wall(n) {
+			p = n
+			path = append(path, p)
+		}
+	}
+	return
+}
+
+// the maze with the path drawn on it
+func (m *Maze) render(path []Point) string {
+	visited := make(map[Point]bool)
+	for _, p := range path {
+		visited[p] = true
+	}
+	var sb strings.Builder
+	for y, row := range m.Walls {
+		for x, wall := range row {
+			p := Point{x, y}
+			switch {
+			case p == m.Start:
+				sb.WriteString("S")
+			case p == m.Exit:
+				sb.WriteString("E")
+			case wall:
+				sb.WriteString("#")
+			case visited[p]:
+				sb.WriteString(".")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,154 @@
+// Package migrate passes organisms between runs on different machines over
+// TCP, so several runs evolve as islands that now and then share their best.
+// Each run listens for migrants and sends its own to a list of peers. A
+// migrant is sent on a connection of its own, as a 4 byte header, its length
+// and the genome, whose encoding is up to the demo, so the peers only need to
+// agree on that.
+package migrate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Timeout is how long to wait to connect to a peer or to send or receive a
+// migrant
+var Timeout = 10 * time.Second
+
+// MaxSize is the largest genome accepted from a peer
+var MaxSize = 16 << 20
+
+// Waiting is how many migrants are kept until they're taken, after which the
+// oldest are dropped for the newest
+var Waiting = 16
+
+// the header that starts each migrant, to tell a peer from anything else
+// that happens to connect
+var header = [4]byte{'G', 'A', 'M', '1'}
+
+// Node is a run's end of the migration, which receives migrants from its
+// peers and sends its own to them
+type Node struct {
+	// Peers are the host and port of each of the other runs
+	Peers    []string
+	listener net.Listener
+	mu       sync.Mutex
+	received [][]byte
+}
+
+// Listen starts a node that receives migrants on the address, like :7000, and
+// sends them to the peers. With no address it only sends
+func Listen(addr string, peers []string) (*Node, error) {
+	n := &Node{Peers: peers}
+	if addr == "" {
+		return n, nil
+	}
+	var err error
+	n.listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go n.serve()
+	return n, nil
+}
+
+// Addr is the address the node receives migrants on, or nil if it doesn't
+func (n *Node) Addr() net.Addr {
+	if n.listener == nil {
+		return nil
+	}
+	return n.listener.Addr()
+}
+
+// Close stops receiving migrants
+func (n *Node) Close() error {
+	if n.listener == nil {
+		return nil
+	}
+	return n.listener.Close()
+}
+
+// accept connections until the listener is closed
+func (n *Node) serve() {
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			return
+		}
+		go n.receive(conn)
+	}
+}
+
+// read a migrant from the connection and keep it until it's taken. A
+// connection that doesn't start with the header is dropped
+func (n *Node) receive(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(Timeout))
+	var prefix [8]byte
+	if _, err := io.ReadFull(conn, prefix[:]); err != nil || [4]byte(prefix[:4]) != header {
+		return
+	}
+	size := binary.BigEndian.Uint32(prefix[4:])
+	if int64(size) > int64(MaxSize) {
+		return
+	}
+	genome := make([]byte, size)
+	if _, err := io.ReadFull(conn, genome); err != nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.received = append(n.received, genome)
+	if len(n.received) > Waiting {
+		n.received = n.received[len(n.received)-Waiting:]
+	}
+}
+
+// Take returns the migrants received since the last time, oldest first
+func (n *Node) Take() [][]byte {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	received := n.received
+	n.received = nil
+	return received
+}
+
+// Send sends the genome to every peer at the same time, returning an error
+// for each peer it couldn't be sent to. A peer that's down only misses the
+// migrant, so a run can go on whether or not its peers are there
+func (n *Node) Send(genome []byte) (errs []error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, peer := range n.Peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := send(peer, genome); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", peer, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return
+}
+
+// send the genome to the peer on a connection of its own
+func send(peer string, genome []byte) error {
+	conn, err := net.DialTimeout("tcp", peer, Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(Timeout))
+	msg := make([]byte, 8, 8+len(genome))
+	copy(msg, header[:])
+	binary.BigEndian.PutUint32(msg[4:], uint32(len(genome)))
+	_, err = conn.Write(append(msg, genome...))
+	return err
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/sausheong/ga/output"
+	"github.com/sausheong/ga/rng"
+)
+
+// Checkpoint is the saved state of a run, which is saved gzipped
+type Checkpoint struct {
+	Generation int
+	Pix        [][]uint8
+	// RNG is the state of the random numbers, which older checkpoints don't
+	// have
+	RNG []byte
+}
+
+// save the population to a checkpoint file
+func saveCheckpoint(filePath string, generation int, population []Organism) {
+	cp := Checkpoint{
+		Generation: generation,
+		Pix:        make([][]uint8, len(population)),
+	}
+	for i := 0; i < len(population); i++ {
+		cp.Pix[i] = population[i].DNA.Pix
+	}
+	// without the state of the random numbers, a resumed run goes on
+	// differently from one that never stopped, but it still goes on
+	cp.RNG, _ = rng.State()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	err := gob.NewEncoder(zw).Encode(cp)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = Output.Write(filePath, buf.Bytes())
+	}
+	if err != nil {
+		fmt.Println("Cannot write checkpoint:", err)
+	}
+}
+
+// load the population from a checkpoint file
+func loadCheckpoint(filePath string, target *image.RGBA) (generation int, population []Organism, err error) {
+	cpFile, err := openCheckpoint(filePath)
+	if err != nil {
+		return
+	}
+	defer cpFile.Close()
+
+	r, err := checkpointReader(cpFile)
+	if err != nil {
+		return
+	}
+	var cp Checkpoint
+	err = gob.NewDecoder(r).Decode(&cp)
+	if err != nil {
+		return
+	}
+
+	population = make([]Organism, len(cp.Pix))
+	for i := 0; i < len(cp.Pix); i++ {
+		population[i] = Organism{
+			DNA: &image.RGBA{
+				Pix:    cp.Pix[i],
+				Stride: target.Stride,
+				Rect:   target.Rect,
+			},
+		}
+		population[i].calcFitness(target)
+	}
+	if len(cp.RNG) > 0 {
+		err = rng.Restore(cp.RNG)
+		if err != nil {
+			return
+		}
+	}
+	generation = cp.Generation
+	return
+}
+
+// a reader of the checkpoint, which is gzipped unless it's an older one
+func checkpointReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// open the checkpoint where it was saved, in the directory, memory or bucket
+// the output goes to. A checkpoint posted to a URL can't be read back, so a
+// run can't be resumed from one
+func openCheckpoint(name string) (io.ReadCloser, error) {
+	o, ok := Output.(output.ReadSink)
+	if !ok {
+		return nil, fmt.Errorf("cannot read %s back from the output, which only takes files", name)
+	}
+	data, err := o.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
@@ -2,20 +2,37 @@ package main
 
 import (
 	"bytes"
-	"encoding/base64"
+	"flag"
 	"fmt"
 	"image"
 	"image/png"
 	"math"
-	"math/rand"
 	"os"
 	"sort"
 	"time"
+
+	"github.com/sausheong/ga/imgdiff"
+	"github.com/sausheong/ga/output"
+	"github.com/sausheong/ga/preview"
+	"github.com/sausheong/ga/rng"
+	"github.com/sausheong/ga/web"
 )
 
 // MutationRate is the rate of mutation
 var MutationRate = 0.0004
 
+// CrossoverRate is the chance that a child is bred from 2 parents, otherwise
+// it's a mutated copy of one parent
+var CrossoverRate = 1.0
+
+// Parents is the number of parents of each child bred by crossover
+var Parents = 2
+
+// Recombination is how a child is bred from more than 2 parents, either
+// segments, cutting the pixels into a segment from each parent, or vote,
+// where each byte is the one most of the parents have
+var Recombination = "segments"
+
 // PopSize is the size of the population
 var PopSize = 250
 
@@ -25,29 +42,143 @@ var PoolSize = 30
 // FitnessLimit is the fitness of the evolved image we are satisfied with
 var FitnessLimit int64 = 7500
 
+// Generations is the most generations to evolve, or 0 to evolve until the
+// fitness is under FitnessLimit
+var Generations = 0
+
+// ChannelWeights are how much the differences in red, green, blue and alpha
+// count in the fitness. The target is usually opaque everywhere, so the alpha
+// says little about how close the colors are, and a weight of 0 leaves it
+// out. With imgdiff.Luminance the colors count by how bright they look
+var ChannelWeights = imgdiff.Even
+
+// CheckpointFile is where the population is saved when the run is paused
+var CheckpointFile = "./checkpoint.gob"
+
+// Output is the sink the files of a run are written to
+var Output output.Sink = output.Local{}
+
 func main() {
+	flag.Float64Var(&CrossoverRate, "crossover-rate", CrossoverRate, "chance of breeding a child from 2 parents rather than copying 1")
+	flag.IntVar(&Parents, "parents", Parents, "number of parents of each child bred by crossover")
+	flag.StringVar(&Recombination, "recombination", Recombination, "how a child is bred from more than 2 parents, segments or vote")
+	resume := flag.Bool("resume", false, "resume the run from the checkpoint file")
+	flag.Float64Var(&ChannelWeights[3], "alpha-weight", ChannelWeights[3], "how much the alpha channel counts in the fitness next to the colors, 0 to leave it out")
+	flag.Func("color-weights", "how much red, green and blue count in the fitness, like 0.299,0.587,0.114, or luminance", ChannelWeights.SetColors)
+	flag.StringVar(&CheckpointFile, "checkpoint", CheckpointFile, "checkpoint file to save to when paused")
+	checkpointEvery := flag.Duration("checkpoint-every", 0, "time between checkpoints, like 10m, or 0 to save one only when paused")
+	previewEvery := flag.Duration("preview-every", 0, "time between previews, like 30s, instead of every 100 generations")
+	previewName := flag.String("preview", "auto", "inline image preview: auto, iterm, sixel, kitty or blocks")
+	noPreview := flag.Bool("no-preview", false, "don't show images, only a progress line")
+	serve := flag.String("serve", "", "address to serve the web UI on, e.g. :8080")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve, 0 to evolve until the fitness limit")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	outputTo := flag.String("output", "", "where the files of the run go, a local directory, s3://bucket/prefix or gs://bucket/prefix")
+	flag.Parse()
+	if ChannelWeights[3] < 0 {
+		fmt.Println("Cannot weigh alpha: need alpha-weight >= 0")
+		return
+	}
+	if Recombination != "segments" && Recombination != "vote" {
+		fmt.Println("Cannot find recombination:", Recombination)
+		return
+	}
+	err := preview.Set(*previewName)
+	if err != nil {
+		fmt.Println("Cannot set preview:", err)
+		return
+	}
+	if *outputTo != "" {
+		Output, err = output.Open(*outputTo)
+		if err != nil {
+			fmt.Println("Cannot open output:", err)
+			return
+		}
+	}
+	progress := preview.NewProgress(FitnessLimit)
+
+	var monitor *web.Monitor
+	if *serve != "" {
+		monitor = web.NewMonitor(map[string]interface{}{
+			"MutationRate":  MutationRate,
+			"CrossoverRate": CrossoverRate,
+			"Parents":       Parents,
+			"PopSize":       PopSize,
+			"PoolSize":      PoolSize,
+			"FitnessLimit":  FitnessLimit,
+		})
+		monitor.Serve(*serve)
+	}
+
 	start := time.Now()
-	rand.Seed(time.Now().UTC().UnixNano())
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
 	target := load("./ml.png")
-	printImage(target.SubImage(target.Rect))
-	population := createPopulation(target)
+	if !*noPreview {
+		preview.Print(target.SubImage(target.Rect))
+	}
 
-	found := false
+	var population []Organism
 	generation := 0
-	for !found {
+	if *resume {
+		generation, population, err = loadCheckpoint(CheckpointFile, target)
+		if err != nil {
+			fmt.Println("Cannot resume from checkpoint:", err)
+			return
+		}
+		fmt.Printf("Resumed from %s at generation %d\n", CheckpointFile, generation)
+	} else {
+		population = createPopulation(target)
+	}
+
+	// send SIGUSR1 to pause and save a checkpoint, and again to resume
+	pause := make(chan os.Signal, 1)
+	notifyPause(pause)
+
+	shown := preview.Cadence{Generations: 100, Interval: *previewEvery}
+	checkpoints := preview.Cadence{Interval: *checkpointEvery}
+	found := false
+	for !found && (Generations == 0 || generation < Generations) {
+		select {
+		case <-pause:
+			saveCheckpoint(CheckpointFile, generation, population)
+			fmt.Printf("\nPaused at generation %d, checkpoint saved to %s\n", generation, CheckpointFile)
+			<-pause
+			fmt.Println("Resumed")
+		default:
+		}
+
 		generation++
-		bestOrganism := getBest(population)
+		bestOrganism := fittest(population)
+		if monitor != nil {
+			monitor.Record(generation, bestOrganism.Fitness)
+		}
 		if bestOrganism.Fitness < FitnessLimit {
 			found = true
 		} else {
 			pool := createPool(population, target)
 			population = naturalSelection(pool, population, target)
-			if generation%100 == 0 {
-				sofar := time.Since(start)
-				fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | pool size: %d", sofar, generation, bestOrganism.Fitness, len(pool))
+			if checkpoints.Due(generation) {
+				saveCheckpoint(CheckpointFile, generation, population)
+				fmt.Printf("Checkpoint saved to %s at generation %d\n", CheckpointFile, generation)
+			}
+			if shown.Due(generation) {
 				save("./evolved.png", bestOrganism.DNA)
-				fmt.Println()
-				printImage(bestOrganism.DNA.SubImage(bestOrganism.DNA.Rect))
+				if monitor != nil {
+					monitor.Update(generation, bestOrganism.Fitness, bestOrganism.DNA)
+				}
+				if *noPreview {
+					fmt.Println(progress.Line(generation, bestOrganism.Fitness))
+				} else {
+					sofar := time.Since(start)
+					progress.Record(generation, bestOrganism.Fitness)
+					fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | pool size: %d | eta: %s", sofar, generation, bestOrganism.Fitness, len(pool), progress.ETA())
+					fmt.Println()
+					preview.Print(bestOrganism.DNA.SubImage(bestOrganism.DNA.Rect))
+				}
 			}
 		}
 
@@ -59,7 +190,7 @@ func main() {
 // create a random image
 func createRandomImageFrom(img *image.RGBA) (created *image.RGBA) {
 	pix := make([]uint8, len(img.Pix))
-	rand.Read(pix)
+	rng.Read(pix)
 	created = &image.RGBA{
 		Pix:    pix,
 		Stride: img.Stride,
@@ -68,15 +199,14 @@ func createRandomImageFrom(img *image.RGBA) (created *image.RGBA) {
 	return
 }
 
-// save the image
+// save the image as a PNG file to the output
 func save(filePath string, rgba *image.RGBA) {
-	imgFile, err := os.Create(filePath)
-	defer imgFile.Close()
+	var buf bytes.Buffer
+	png.Encode(&buf, rgba.SubImage(rgba.Rect))
+	err := Output.Write(filePath, buf.Bytes())
 	if err != nil {
-		fmt.Println("Cannot create file:", err)
+		fmt.Println("Cannot write file:", err)
 	}
-
-	png.Encode(imgFile, rgba.SubImage(rgba.Rect))
 }
 
 // load the image
@@ -95,19 +225,9 @@ func load(filePath string) *image.RGBA {
 }
 
 // difference between 2 images
-func diff(a, b *image.RGBA) (d int64) {
-	d = 0
-	for i := 0; i < len(a.Pix); i++ {
-		d += int64(squareDifference(a.Pix[i], b.Pix[i]))
-	}
-
-	return int64(math.Sqrt(float64(d)))
-}
-
-// square the difference
-func squareDifference(x, y uint8) uint64 {
-	d := uint64(x) - uint64(y)
-	return d * d
+func diff(a, b *image.RGBA) int64 {
+	// b is the target, which is packed once for all the comparisons with it
+	return int64(math.Sqrt(imgdiff.Packed(b.Pix).WeightedSumSquares(a.Pix, ChannelWeights)))
 }
 
 // create the reproduction pool that creates the next generation
@@ -140,11 +260,24 @@ func naturalSelection(pool []Organism, population []Organism, target *image.RGBA
 	next := make([]Organism, len(population))
 
 	for i := 0; i < len(population); i++ {
-		r1, r2 := rand.Intn(len(pool)), rand.Intn(len(pool))
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
 		a := pool[r1]
 		b := pool[r2]
 
-		child := crossover(a, b)
+		var child Organism
+		if rng.Float64() < CrossoverRate {
+			if Parents > 2 {
+				parents := []Organism{a, b}
+				for len(parents) < Parents {
+					parents = append(parents, pool[rng.Intn(len(pool))])
+				}
+				child = multiCrossover(parents)
+			} else {
+				child = crossover(a, b)
+			}
+		} else {
+			child = clone(a)
+		}
 		child.mutate()
 		child.calcFitness(target)
 
@@ -162,17 +295,18 @@ func createPopulation(target *image.RGBA) (population []Organism) {
 	return
 }
 
-// Get the best gene
-func getBest(population []Organism) Organism {
-	best := int64(0)
-	index := 0
-	for i := 0; i < len(population); i++ {
-		if population[i].Fitness > best {
-			index = i
-			best = population[i].Fitness
+// the organism closest to the target
+func fittest(population []Organism) Organism {
+	if len(population) == 0 {
+		return Organism{}
+	}
+	best := population[0]
+	for _, o := range population {
+		if o.Fitness < best.Fitness {
+			best = o
 		}
 	}
-	return population[index]
+	return best
 }
 
 // Organism represents the genotype of the GA
@@ -212,7 +346,7 @@ func crossover(d1 Organism, d2 Organism) Organism {
 		},
 		Fitness: 0,
 	}
-	mid := rand.Intn(len(d1.DNA.Pix))
+	mid := rng.Intn(len(d1.DNA.Pix))
 	for i := 0; i < len(d1.DNA.Pix); i++ {
 		if i > mid {
 			child.DNA.Pix[i] = d1.DNA.Pix[i]
@@ -224,20 +358,71 @@ func crossover(d1 Organism, d2 Organism) Organism {
 	return child
 }
 
-// mutate the Organism string
-func (o *Organism) mutate() {
-	for i := 0; i < len(o.DNA.Pix); i++ {
-		if rand.Float64() < MutationRate {
-			o.DNA.Pix[i] = uint8(rand.Intn(255))
+// crosses over more than 2 organisms, either cutting the pixels at random
+// places into a segment from each parent in turn, or taking the byte most of
+// the parents have, from a random parent if none of them agree
+func multiCrossover(parents []Organism) Organism {
+	n := len(parents[0].DNA.Pix)
+	child := Organism{
+		DNA: &image.RGBA{
+			Pix:    make([]uint8, n),
+			Stride: parents[0].DNA.Stride,
+			Rect:   parents[0].DNA.Rect,
+		},
+		Fitness: 0,
+	}
+	if Recombination == "vote" {
+		for i := 0; i < n; i++ {
+			pick, votes := parents[rng.Intn(len(parents))].DNA.Pix[i], 1
+			for _, a := range parents {
+				count := 0
+				for _, b := range parents {
+					if a.DNA.Pix[i] == b.DNA.Pix[i] {
+						count++
+					}
+				}
+				if count > votes {
+					pick, votes = a.DNA.Pix[i], count
+				}
+			}
+			child.DNA.Pix[i] = pick
 		}
+		return child
 	}
+	cuts := make([]int, len(parents)-1)
+	for i := range cuts {
+		cuts[i] = rng.Intn(n)
+	}
+	sort.Ints(cuts)
+	k := 0
+	for i := 0; i < n; i++ {
+		for k < len(cuts) && i >= cuts[k] {
+			k++
+		}
+		child.DNA.Pix[i] = parents[k].DNA.Pix[i]
+	}
+	return child
 }
 
-// this only works for iTerm!
+// copies the organism, so the copy can be mutated without changing it
+func clone(d Organism) Organism {
+	child := Organism{
+		DNA: &image.RGBA{
+			Pix:    make([]uint8, len(d.DNA.Pix)),
+			Stride: d.DNA.Stride,
+			Rect:   d.DNA.Rect,
+		},
+		Fitness: 0,
+	}
+	copy(child.DNA.Pix, d.DNA.Pix)
+	return child
+}
 
-func printImage(img image.Image) {
-	var buf bytes.Buffer
-	png.Encode(&buf, img)
-	imgBase64Str := base64.StdEncoding.EncodeToString(buf.Bytes())
-	fmt.Printf("\x1b]1337;File=inline=1:%s\a\n", imgBase64Str)
+// mutate the Organism string
+func (o *Organism) mutate() {
+	for i := 0; i < len(o.DNA.Pix); i++ {
+		if rng.Float64() < MutationRate {
+			o.DNA.Pix[i] = uint8(rng.Intn(255))
+		}
+	}
 }
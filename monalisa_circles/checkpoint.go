@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/sausheong/ga/output"
+	"github.com/sausheong/ga/rng"
+)
+
+func init() {
+	// circle colors are stored as an interface so gob needs to know the concrete type
+	gob.Register(color.RGBA{})
+}
+
+// Checkpoint is the saved state of a run, which is saved gzipped
+type Checkpoint struct {
+	Generation int
+	// Circles are the circles of each organism in older checkpoints, which
+	// have them all rather than Shapes and Genomes
+	Circles [][]Circle
+	// Shapes are the different circles of the population, each once, and
+	// Genomes are the circles of each organism as positions in Shapes
+	Shapes  []Circle
+	Genomes [][]int32
+	// Backgrounds are the background colors, which older checkpoints don't have
+	Backgrounds []color.RGBA
+	// RNG is the state of the random numbers, which older checkpoints don't
+	// have
+	RNG []byte
+}
+
+// save the population to a checkpoint file
+func saveCheckpoint(filePath string, generation int, population []Organism) {
+	cp := Checkpoint{
+		Generation:  generation,
+		Backgrounds: make([]color.RGBA, len(population)),
+	}
+	cp.share(population)
+	for i := 0; i < len(population); i++ {
+		cp.Backgrounds[i] = population[i].Background
+	}
+	// without the state of the random numbers, a resumed run goes on
+	// differently from one that never stopped, but it still goes on
+	cp.RNG, _ = rng.State()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	err := gob.NewEncoder(zw).Encode(cp)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = Output.Write(filePath, buf.Bytes())
+	}
+	if err != nil {
+		fmt.Println("Cannot write checkpoint:", err)
+	}
+}
+
+// load the population from a checkpoint file, redrawing each organism
+func loadCheckpoint(filePath string, target *image.RGBA) (generation int, population []Organism, err error) {
+	cpFile, err := openCheckpoint(filePath)
+	if err != nil {
+		return
+	}
+	defer cpFile.Close()
+
+	r, err := checkpointReader(cpFile)
+	if err != nil {
+		return
+	}
+	var cp Checkpoint
+	err = gob.NewDecoder(r).Decode(&cp)
+	if err != nil {
+		return
+	}
+	circles, err := cp.circles()
+	if err != nil {
+		return
+	}
+
+	population = make([]Organism, len(circles))
+	for i := 0; i < len(circles); i++ {
+		var background color.RGBA
+		if i < len(cp.Backgrounds) {
+			background = cp.Backgrounds[i]
+		}
+		population[i] = Organism{
+			DNA:        draw(target.Rect.Dx(), target.Rect.Dy(), background, circles[i]),
+			Circles:    circles[i],
+			Background: background,
+		}
+		population[i].calcFitness(target)
+	}
+	if len(cp.RNG) > 0 {
+		err = rng.Restore(cp.RNG)
+		if err != nil {
+			return
+		}
+	}
+	generation = cp.Generation
+	return
+}
+
+// keep each different circle of the population once, with the circles of
+// each organism as positions in them. Children are mostly copies of their
+// parents, so a population has far fewer different circles than circles
+func (cp *Checkpoint) share(population []Organism) {
+	positions := make(map[Circle]int32)
+	cp.Genomes = make([][]int32, len(population))
+	for i, o := range population {
+		genome := make([]int32, len(o.Circles))
+		for j, c := range o.Circles {
+			k, ok := positions[c]
+			if !ok {
+				k = int32(len(cp.Shapes))
+				positions[c] = k
+				cp.Shapes = append(cp.Shapes, c)
+			}
+			genome[j] = k
+		}
+		cp.Genomes[i] = genome
+	}
+}
+
+// the circles of each organism, from the shared ones, or as they were saved
+// in older checkpoints
+func (cp *Checkpoint) circles() ([][]Circle, error) {
+	if cp.Genomes == nil {
+		return cp.Circles, nil
+	}
+	circles := make([][]Circle, len(cp.Genomes))
+	for i, genome := range cp.Genomes {
+		circles[i] = make([]Circle, len(genome))
+		for j, k := range genome {
+			if k < 0 || int(k) >= len(cp.Shapes) {
+				return nil, fmt.Errorf("organism %d has circle %d of %d", i, k, len(cp.Shapes))
+			}
+			circles[i][j] = cp.Shapes[k]
+		}
+	}
+	return circles, nil
+}
+
+// a reader of the checkpoint, which is gzipped unless it's an older one
+func checkpointReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// open the checkpoint where it was saved, in the directory, memory or bucket
+// the output goes to. A checkpoint posted to a URL can't be read back, so a
+// run can't be resumed from one
+func openCheckpoint(name string) (io.ReadCloser, error) {
+	o, ok := Output.(output.ReadSink)
+	if !ok {
+		return nil, fmt.Errorf("cannot read %s back from the output, which only takes files", name)
+	}
+	data, err := o.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
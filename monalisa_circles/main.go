@@ -2,25 +2,32 @@ package main
 
 import (
 	"bytes"
-	"encoding/base64"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"math"
-	"math/rand"
 	"os"
 	"sort"
 	"time"
 
 	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/sausheong/ga/blend"
+	"github.com/sausheong/ga/imgdiff"
+	"github.com/sausheong/ga/output"
+	"github.com/sausheong/ga/preview"
+	"github.com/sausheong/ga/rng"
+	"github.com/sausheong/ga/web"
 )
 
-const escape = "\x1b"
-
 // MutationRate is the rate of mutation
 var MutationRate = 0.02
 
+// CrossoverRate is the chance that a child is bred from 2 parents, otherwise
+// it's a mutated copy of one parent
+var CrossoverRate = 1.0
+
 // PopSize is the size of the population
 var PopSize = 150
 
@@ -33,30 +40,197 @@ var NumCircles = 180
 // MaxCircleSize is the size of the circles to use
 var MaxCircleSize = 8
 
+// StartCircleSize is the size of the circles to use at the start of the run,
+// falling to MaxCircleSize over CircleSizeGenerations generations, so the
+// picture is blocked in with large circles before the details are filled in
+var StartCircleSize = 8
+
+// CircleSizeGenerations is the number of generations over which the size
+// of the circles falls from StartCircleSize to MaxCircleSize
+var CircleSizeGenerations = 0
+
+// RadiusRate is the rate of mutation of only the radius of a circle, which
+// changes by up to RadiusStep
+var RadiusRate = 0.0
+
+// RadiusStep is the most the radius changes by when it's mutated
+var RadiusStep = 2
+
+// Background is whether each organism evolves a color to fill the picture
+// with before the circles are drawn, instead of starting from transparent
+var Background = false
+
+// Blend is how new circles are blended onto the picture, unless each circle
+// evolves a mode of its own
+var Blend = blend.Normal
+
+// EvolveBlend is whether each circle has a blend mode of its own, picked at
+// random when the circle is made and evolving with it
+var EvolveBlend = false
+
+// FitnessLimit is the fitness of the evolved image we are satisfied with
+var FitnessLimit int64 = 5000
+
+// Generations is the most generations to evolve, or 0 to evolve until the
+// fitness is under FitnessLimit
+var Generations = 0
+
+// ChannelWeights are how much the differences in red, green, blue and alpha
+// count in the fitness. The target is usually opaque everywhere, so the alpha
+// says little about how close the colors are, and a weight of 0 leaves it
+// out. With imgdiff.Luminance the colors count by how bright they look
+var ChannelWeights = imgdiff.Even
+
+// CheckpointFile is where the population is saved when the run is paused
+var CheckpointFile = "./checkpoint.gob"
+
+// Output is the sink the files of a run are written to
+var Output output.Sink = output.Local{}
+
 func main() {
+	flag.Float64Var(&CrossoverRate, "crossover-rate", CrossoverRate, "chance of breeding a child from 2 parents rather than copying 1")
+	flag.IntVar(&MaxCircleSize, "max-circle-size", MaxCircleSize, "size of the circles to use")
+	flag.IntVar(&StartCircleSize, "start-circle-size", StartCircleSize, "size of the circles to use at the start")
+	flag.IntVar(&CircleSizeGenerations, "circle-size-generations", CircleSizeGenerations, "number of generations over which the size of the circles falls from start-circle-size to max-circle-size")
+	flag.Float64Var(&RadiusRate, "radius-rate", RadiusRate, "rate of mutation of only the radius of a circle")
+	flag.BoolVar(&Background, "background", Background, "evolve a background color for each picture")
+	flag.Func("blend", "how the circles are blended onto the picture: normal, add, multiply or screen (default normal)", Blend.Set)
+	flag.BoolVar(&EvolveBlend, "evolve-blend", EvolveBlend, "give each circle a blend mode of its own, which evolves")
+	flag.Func("style", "whether new circles are filled, outlined or both: fill, stroke or both (default fill)", CircleStyle.Set)
+	flag.BoolVar(&EvolveStyle, "evolve-style", EvolveStyle, "give each circle a style of its own, which evolves")
+	flag.Float64Var(&StrokeWidth, "stroke-width", StrokeWidth, "widest outline of a circle, in pixels")
+	flag.Float64Var(&StrokeRate, "stroke-rate", StrokeRate, "rate of mutation of only the width of the outline of a circle")
+	resume := flag.Bool("resume", false, "resume the run from the checkpoint file")
+	flag.Float64Var(&ChannelWeights[3], "alpha-weight", ChannelWeights[3], "how much the alpha channel counts in the fitness next to the colors, 0 to leave it out")
+	flag.Func("color-weights", "how much red, green and blue count in the fitness, like 0.299,0.587,0.114, or luminance", ChannelWeights.SetColors)
+	flag.StringVar(&CheckpointFile, "checkpoint", CheckpointFile, "checkpoint file to save to when paused")
+	checkpointEvery := flag.Duration("checkpoint-every", 0, "time between checkpoints, like 10m, or 0 to save one only when paused")
+	previewEvery := flag.Duration("preview-every", 0, "time between previews, like 30s, instead of every 10 generations")
+	previewName := flag.String("preview", "auto", "inline image preview: auto, iterm, sixel, kitty or blocks")
+	noPreview := flag.Bool("no-preview", false, "don't show images, only a progress line")
+	serve := flag.String("serve", "", "address to serve the web UI on, e.g. :8080")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve, 0 to evolve until the fitness limit")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	outputTo := flag.String("output", "", "where the files of the run go, a local directory, s3://bucket/prefix or gs://bucket/prefix")
+	flag.Parse()
+	if ChannelWeights[3] < 0 {
+		fmt.Println("Cannot weigh alpha: need alpha-weight >= 0")
+		return
+	}
+	if MaxCircleSize < 1 || StartCircleSize < 1 {
+		fmt.Println("Cannot size circles: the sizes must be at least 1")
+		return
+	}
+	if StrokeWidth < 1 {
+		fmt.Println("Cannot outline circles: need stroke-width >= 1")
+		return
+	}
+	err := preview.Set(*previewName)
+	if err != nil {
+		fmt.Println("Cannot set preview:", err)
+		return
+	}
+	if *outputTo != "" {
+		Output, err = output.Open(*outputTo)
+		if err != nil {
+			fmt.Println("Cannot open output:", err)
+			return
+		}
+	}
+	progress := preview.NewProgress(FitnessLimit)
+
+	var monitor *web.Monitor
+	if *serve != "" {
+		monitor = web.NewMonitor(map[string]interface{}{
+			"MutationRate":          MutationRate,
+			"CrossoverRate":         CrossoverRate,
+			"PopSize":               PopSize,
+			"PoolSize":              PoolSize,
+			"NumCircles":            NumCircles,
+			"MaxCircleSize":         MaxCircleSize,
+			"StartCircleSize":       StartCircleSize,
+			"CircleSizeGenerations": CircleSizeGenerations,
+			"RadiusRate":            RadiusRate,
+			"Background":            Background,
+			"Blend":                 Blend.String(),
+			"EvolveBlend":           EvolveBlend,
+			"Style":                 CircleStyle.String(),
+			"EvolveStyle":           EvolveStyle,
+			"StrokeWidth":           StrokeWidth,
+			"FitnessLimit":          FitnessLimit,
+		})
+		monitor.Serve(*serve)
+	}
+
 	start := time.Now()
-	rand.Seed(time.Now().UTC().UnixNano())
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
 	target := load("./ml.png")
-	printImage(target.SubImage(target.Rect))
+	if !*noPreview {
+		preview.Print(target.SubImage(target.Rect))
+	}
 
-	population := createPopulation(target)
+	var population []Organism
+	generation := 0
+	if *resume {
+		generation, population, err = loadCheckpoint(CheckpointFile, target)
+		if err != nil {
+			fmt.Println("Cannot resume from checkpoint:", err)
+			return
+		}
+		fmt.Printf("Resumed from %s at generation %d\n", CheckpointFile, generation)
+	} else {
+		population = createPopulation(target)
+	}
 
+	// send SIGUSR1 to pause and save a checkpoint, and again to resume
+	pause := make(chan os.Signal, 1)
+	notifyPause(pause)
+
+	shown := preview.Cadence{Generations: 10, Interval: *previewEvery}
+	checkpoints := preview.Cadence{Interval: *checkpointEvery}
 	found := false
-	generation := 0
-	for !found {
+	for !found && (Generations == 0 || generation < Generations) {
+		select {
+		case <-pause:
+			saveCheckpoint(CheckpointFile, generation, population)
+			fmt.Printf("\nPaused at generation %d, checkpoint saved to %s\n", generation, CheckpointFile)
+			<-pause
+			fmt.Println("Resumed")
+		default:
+		}
+
 		generation++
-		bestOrganism := getBest(population)
-		if bestOrganism.Fitness < 5000 {
+		bestOrganism := fittest(population)
+		if monitor != nil {
+			monitor.Record(generation, bestOrganism.Fitness)
+		}
+		if bestOrganism.Fitness < FitnessLimit {
 			found = true
 		} else {
 			pool := createPool(population, target)
-			population = naturalSelection(pool, population, target)
+			population = naturalSelection(pool, population, target, generation)
+			if checkpoints.Due(generation) {
+				saveCheckpoint(CheckpointFile, generation, population)
+				fmt.Printf("Checkpoint saved to %s at generation %d\n", CheckpointFile, generation)
+			}
 			sofar := time.Since(start)
-			if generation%10 == 0 {
+			if shown.Due(generation) {
 				save("./evolved.png", bestOrganism.DNA)
-				fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | pool size: %d", sofar, generation, bestOrganism.Fitness, len(pool))
-				fmt.Println()
-				printImage(bestOrganism.DNA.SubImage(bestOrganism.DNA.Rect))
+				if monitor != nil {
+					monitor.Update(generation, bestOrganism.Fitness, bestOrganism.DNA)
+				}
+				if *noPreview {
+					fmt.Println(progress.Line(generation, bestOrganism.Fitness))
+				} else {
+					progress.Record(generation, bestOrganism.Fitness)
+					fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | pool size: %d | eta: %s", sofar, generation, bestOrganism.Fitness, len(pool), progress.ETA())
+					fmt.Println()
+					preview.Print(bestOrganism.DNA.SubImage(bestOrganism.DNA.Rect))
+				}
 			}
 		}
 
@@ -66,13 +240,12 @@ func main() {
 }
 
 func save(filePath string, rgba *image.RGBA) {
-	imgFile, err := os.Create(filePath)
-	defer imgFile.Close()
+	var buf bytes.Buffer
+	png.Encode(&buf, rgba.SubImage(rgba.Rect))
+	err := Output.Write(filePath, buf.Bytes())
 	if err != nil {
-		fmt.Println("Cannot create file:", err)
+		fmt.Println("Cannot write file:", err)
 	}
-
-	png.Encode(imgFile, rgba.SubImage(rgba.Rect))
 }
 
 func getImage(filePath string) image.Image {
@@ -95,18 +268,9 @@ func load(filePath string) *image.RGBA {
 	return img.(*image.RGBA)
 }
 
-func diff(a, b *image.RGBA) (d int64) {
-	d = 0
-	for i := 0; i < len(a.Pix); i++ {
-		d += int64(squareDifference(a.Pix[i], b.Pix[i]))
-	}
-
-	return int64(math.Sqrt(float64(d)))
-}
-
-func squareDifference(x, y uint8) uint64 {
-	d := uint64(x) - uint64(y)
-	return d * d
+func diff(a, b *image.RGBA) int64 {
+	// b is the target, which is packed once for all the comparisons with it
+	return int64(math.Sqrt(imgdiff.Packed(b.Pix).WeightedSumSquares(a.Pix, ChannelWeights)))
 }
 
 // create the reproduction pool that creates the next generation
@@ -133,17 +297,22 @@ func createPool(population []Organism, target *image.RGBA) (pool []Organism) {
 }
 
 // perform natural selection to create the next generation
-func naturalSelection(pool []Organism, population []Organism, target *image.RGBA) []Organism {
+func naturalSelection(pool []Organism, population []Organism, target *image.RGBA, generation int) []Organism {
 	next := make([]Organism, len(population))
 
 	for i := 0; i < len(population); i++ {
 		// fmt.Println("pool:", len(pool))
-		r1, r2 := rand.Intn(len(pool)), rand.Intn(len(pool))
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
 		a := pool[r1]
 		b := pool[r2]
 
-		child := crossover(a, b)
-		child.mutate()
+		var child Organism
+		if rng.Float64() < CrossoverRate {
+			child = crossover(a, b)
+		} else {
+			child = clone(a)
+		}
+		child.mutate(generation)
 		child.calcFitness(target)
 
 		next[i] = child
@@ -160,17 +329,18 @@ func createPopulation(target *image.RGBA) (population []Organism) {
 	return
 }
 
-// Get the best organism
-func getBest(population []Organism) Organism {
-	best := int64(0)
-	index := 0
-	for i := 0; i < len(population); i++ {
-		if population[i].Fitness > best {
-			index = i
-			best = population[i].Fitness
+// the organism closest to the target
+func fittest(population []Organism) Organism {
+	if len(population) == 0 {
+		return Organism{}
+	}
+	best := population[0]
+	for _, o := range population {
+		if o.Fitness < best.Fitness {
+			best = o
 		}
 	}
-	return population[index]
+	return best
 }
 
 // Point represents a position in the image
@@ -185,13 +355,22 @@ type Circle struct {
 	Y     int
 	R     int
 	Color color.Color
+	// Blend is how the circle is blended onto the picture
+	Blend blend.Mode
+	// Style is whether the circle is filled, outlined or both, and Width is
+	// how wide its outline is
+	Style Style
+	Width float64
 }
 
 // Organism represents an individual in the population
 type Organism struct {
 	DNA     *image.RGBA
 	Circles []Circle
-	Fitness int64
+	// Background is the color the picture is filled with first, which is
+	// transparent unless it evolves
+	Background color.RGBA
+	Fitness    int64
 }
 
 // create an organism
@@ -199,25 +378,57 @@ func createOrganism(target *image.RGBA) (organism Organism) {
 	// randomly make triangles
 	circles := make([]Circle, NumCircles)
 	for i := 0; i < NumCircles; i++ {
-		circles[i] = createCircle(target.Rect.Dx(), target.Rect.Dy())
+		circles[i] = createCircle(target.Rect.Dx(), target.Rect.Dy(), circleSize(0))
 	}
 
+	var background color.RGBA
+	if Background {
+		background = randomBackground()
+	}
 	organism = Organism{
-		DNA:     draw(target.Rect.Dx(), target.Rect.Dy(), circles),
-		Circles: circles,
-		Fitness: 0,
+		DNA:        draw(target.Rect.Dx(), target.Rect.Dy(), background, circles),
+		Circles:    circles,
+		Background: background,
+		Fitness:    0,
 	}
 	organism.calcFitness(target)
 	return
 }
 
-func createCircle(w int, h int) (c Circle) {
+// a random opaque background color
+func randomBackground() color.RGBA {
+	return color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+}
+
+// the color with each channel changed a little, so the background shifts
+// gradually instead of jumping to another color
+func nudge(c color.RGBA) color.RGBA {
+	channel := func(v uint8) uint8 {
+		return uint8(max(0, min(255, int(v)+rng.Intn(33)-16)))
+	}
+	return color.RGBA{channel(c.R), channel(c.G), channel(c.B), 255}
+}
+
+// the size of the circles to use at the generation
+func circleSize(generation int) int {
+	if CircleSizeGenerations > 0 && generation < CircleSizeGenerations {
+		return StartCircleSize - (StartCircleSize-MaxCircleSize)*generation/CircleSizeGenerations
+	}
+	return MaxCircleSize
+}
+
+func createCircle(w int, h int, size int) (c Circle) {
 	c = Circle{
-		X:     rand.Intn(w),
-		Y:     rand.Intn(h),
-		R:     rand.Intn(MaxCircleSize),
-		Color: color.RGBA{uint8(rand.Intn(255)), uint8(rand.Intn(255)), uint8(rand.Intn(255)), uint8(rand.Intn(255))},
+		X:     rng.Intn(w),
+		Y:     rng.Intn(h),
+		R:     rng.Intn(size),
+		Color: color.RGBA{uint8(rng.Intn(255)), uint8(rng.Intn(255)), uint8(rng.Intn(255)), uint8(rng.Intn(255))},
+		Blend: Blend,
+	}
+	if EvolveBlend {
+		c.Blend = blend.Modes[rng.Intn(len(blend.Modes))]
 	}
+	c.Style, c.Width = newStyle()
 	return
 }
 
@@ -235,11 +446,15 @@ func (d *Organism) calcFitness(target *image.RGBA) {
 func crossover(d1 Organism, d2 Organism) Organism {
 
 	child := Organism{
-		Circles: make([]Circle, len(d1.Circles)),
-		Fitness: 0,
+		Circles:    make([]Circle, len(d1.Circles)),
+		Background: d1.Background,
+		Fitness:    0,
+	}
+	if rng.Intn(2) == 0 {
+		child.Background = d2.Background
 	}
 
-	mid := rand.Intn(len(d1.Circles))
+	mid := rng.Intn(len(d1.Circles))
 	for i := 0; i < len(d1.Circles); i++ {
 		if i > mid {
 			child.Circles[i] = d1.Circles[i]
@@ -248,40 +463,91 @@ func crossover(d1 Organism, d2 Organism) Organism {
 		}
 
 	}
-	child.DNA = draw(d1.DNA.Rect.Dx(), d1.DNA.Rect.Dy(), child.Circles)
+	child.DNA = draw(d1.DNA.Rect.Dx(), d1.DNA.Rect.Dy(), child.Background, child.Circles)
+	return child
+}
+
+// copies the organism, so the copy can be mutated without changing it
+func clone(d Organism) Organism {
+	child := Organism{
+		DNA:        d.DNA,
+		Circles:    make([]Circle, len(d.Circles)),
+		Background: d.Background,
+		Fitness:    0,
+	}
+	copy(child.Circles, d.Circles)
 	return child
 }
 
 // mutate the organism
-func (d *Organism) mutate() {
+func (d *Organism) mutate(generation int) {
+	size := circleSize(generation)
 	for i := 0; i < len(d.Circles); i++ {
-		if rand.Float64() < MutationRate {
-			d.Circles[i] = createCircle(d.DNA.Rect.Dx(), d.DNA.Rect.Dy())
+		if rng.Float64() < MutationRate {
+			d.Circles[i] = createCircle(d.DNA.Rect.Dx(), d.DNA.Rect.Dy(), size)
+		}
+		if rng.Float64() < RadiusRate {
+			r := d.Circles[i].R + rng.Intn(2*RadiusStep+1) - RadiusStep
+			d.Circles[i].R = max(1, min(r, size))
 		}
+		// only a circle with an outline takes a chance, so runs without any go
+		// on as they did before there were outlines
+		if StrokeRate > 0 && d.Circles[i].Style != Fill && rng.Float64() < StrokeRate {
+			d.Circles[i].Width = max(1, min(StrokeWidth, d.Circles[i].Width+rng.Float64()-0.5))
+		}
+	}
+	if Background && rng.Float64() < MutationRate {
+		d.Background = nudge(d.Background)
 	}
-	d.DNA = draw(d.DNA.Rect.Dx(), d.DNA.Rect.Dy(), d.Circles)
+	d.DNA = draw(d.DNA.Rect.Dx(), d.DNA.Rect.Dy(), d.Background, d.Circles)
 }
 
-func draw(w int, h int, circles []Circle) *image.RGBA {
+func draw(w int, h int, background color.RGBA, circles []Circle) *image.RGBA {
 	dest := image.NewRGBA(image.Rect(0, 0, w, h))
+	fill(dest, background)
 	gc := draw2dimg.NewGraphicContext(dest)
+	// the circles that aren't drawn normally are drawn onto a layer first,
+	// which is only made if there are any
+	var layer *blend.Layer
+	var lc *draw2dimg.GraphicContext
 
 	for _, circle := range circles {
-		gc.SetFillColor(circle.Color)
-		gc.MoveTo(float64(circle.X), float64(circle.Y))
-		gc.ArcTo(float64(circle.X), float64(circle.Y), float64(circle.R), float64(circle.R), 0, 6.283185307179586)
-		gc.Close()
-		gc.Fill()
+		if circle.Blend == blend.Normal {
+			gc.SetFillColor(circle.Color)
+			gc.SetStrokeColor(circle.Color)
+			circle.paint(gc)
+			continue
+		}
+		if layer == nil {
+			layer = blend.NewLayer(dest.Rect)
+			lc = draw2dimg.NewGraphicContext(layer.Mask)
+			lc.SetFillColor(color.White)
+			lc.SetStrokeColor(color.White)
+		}
+		circle.paint(lc)
+		// a pixel around the circle and its outline for their anti-aliased
+		// edges
+		r := circle.R + 1 + int(math.Ceil(circle.Width/2))
+		around := image.Rect(circle.X-r, circle.Y-r, circle.X+r+1, circle.Y+r+1)
+		layer.Blend(dest, around, color.RGBAModel.Convert(circle.Color).(color.RGBA), circle.Blend)
 	}
 
 	return dest
 }
 
-// this only works for iTerm!
+// trace the outline of the circle, ready to be filled
+func (c Circle) path(gc *draw2dimg.GraphicContext) {
+	gc.MoveTo(float64(c.X), float64(c.Y))
+	gc.ArcTo(float64(c.X), float64(c.Y), float64(c.R), float64(c.R), 0, 6.283185307179586)
+	gc.Close()
+}
 
-func printImage(img image.Image) {
-	var buf bytes.Buffer
-	png.Encode(&buf, img)
-	imgBase64Str := base64.StdEncoding.EncodeToString(buf.Bytes())
-	fmt.Printf("%s]1337;File=inline=1:%s\a\n", escape, imgBase64Str)
+// fill the image with the color
+func fill(img *image.RGBA, c color.RGBA) {
+	if c.A == 0 {
+		return
+	}
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
 }
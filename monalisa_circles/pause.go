@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// pause and resume the run on SIGUSR1
+func notifyPause(c chan os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1)
+}
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// there is no SIGUSR1 on Windows so the run can't be paused
+func notifyPause(c chan os.Signal) {}
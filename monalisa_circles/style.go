@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/sausheong/ga/rng"
+)
+
+// Style is whether a circle is filled, only outlined as a ring, or both
+type Style uint8
+
+const (
+	// Fill fills the circle, as it always was
+	Fill Style = iota
+	// Stroke only draws the outline of the circle
+	Stroke
+	// FillStroke fills the circle and draws its outline over it, in the same
+	// color
+	FillStroke
+)
+
+var styleNames = [...]string{"fill", "stroke", "both"}
+
+// String is the name of the style
+func (s Style) String() string {
+	if int(s) < len(styleNames) {
+		return styleNames[s]
+	}
+	return fmt.Sprintf("Style(%d)", s)
+}
+
+// Set sets the style by its name, so it can be a flag
+func (s *Style) Set(name string) error {
+	for i, n := range styleNames {
+		if strings.ToLower(strings.TrimSpace(name)) == n {
+			*s = Style(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("no style called %q, only %s", name, strings.Join(styleNames[:], ", "))
+}
+
+// CircleStyle is the style of new circles, unless each circle evolves a style
+// of its own
+var CircleStyle = Fill
+
+// EvolveStyle is whether each circle has a style of its own, picked at random
+// when the circle is made and evolving with it
+var EvolveStyle = false
+
+// StrokeWidth is the widest the outline of a circle is, in pixels. Each
+// outline is from 1 pixel up to it
+var StrokeWidth = 3.0
+
+// StrokeRate is the rate of mutation of only the width of the outline of a
+// circle that has one, which changes by up to half a pixel
+var StrokeRate = 0.0
+
+// the style and stroke width of a new circle
+func newStyle() (Style, float64) {
+	s := CircleStyle
+	if EvolveStyle {
+		s = Style(rng.Intn(len(styleNames)))
+	}
+	if s == Fill {
+		return s, 0
+	}
+	return s, 1 + rng.Float64()*(StrokeWidth-1)
+}
+
+// fill the circle, outline it or both, as its style says, in the colors
+// already set. The outline is only the arc, without the line from the center
+// the filled circle starts from
+func (c Circle) paint(gc *draw2dimg.GraphicContext) {
+	if c.Style != Stroke {
+		c.path(gc)
+		gc.Fill()
+	}
+	if c.Style != Fill {
+		gc.SetLineWidth(c.Width)
+		gc.ArcTo(float64(c.X), float64(c.Y), float64(c.R), float64(c.R), 0, 6.283185307179586)
+		gc.Close()
+		gc.Stroke()
+	}
+}
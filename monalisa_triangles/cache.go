@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"image"
+	"math"
+
+	"github.com/sausheong/ga/blend"
+)
+
+// CacheSize is the most genomes whose fitness is remembered, or 0 to work out
+// the fitness of every child
+var CacheSize = 0
+
+// FitnessCache is the fitness of the genomes a run has seen, so a child that's
+// the same as an organism seen before, like a copy of its parent that no
+// mutation changed, isn't drawn and compared with the target again. The cache
+// is only good for one target, which it keeps a hash of so a cache saved with
+// a checkpoint isn't used for another
+type FitnessCache struct {
+	// Hits is the number of children whose fitness was in the cache
+	Hits    int
+	size    int
+	target  uint64
+	fitness map[uint64]int64
+}
+
+// a cache of up to size genomes for the target
+func newFitnessCache(size int, target *image.RGBA) *FitnessCache {
+	return &FitnessCache{size: size, target: targetHash(target), fitness: make(map[uint64]int64)}
+}
+
+// the fitness of the organism, if its genome has been seen
+func (c *FitnessCache) get(o Organism) (fitness int64, ok bool) {
+	fitness, ok = c.fitness[genomeHash(o)]
+	if ok {
+		c.Hits++
+	}
+	return
+}
+
+// remember the fitness of the organism. Once the cache is full half of it is
+// forgotten, whichever half the map gives first
+func (c *FitnessCache) put(o Organism) {
+	if len(c.fitness) >= c.size {
+		n := len(c.fitness) / 2
+		for hash := range c.fitness {
+			if n == 0 {
+				break
+			}
+			delete(c.fitness, hash)
+			n--
+		}
+	}
+	c.fitness[genomeHash(o)] = o.Fitness
+}
+
+// forget every genome, as their fitness says nothing about the new target
+func (c *FitnessCache) retarget(target *image.RGBA) {
+	c.target = targetHash(target)
+	c.fitness = make(map[uint64]int64)
+}
+
+// a hash of the background and triangles of the organism, which are all its
+// fitness depends on
+func genomeHash(o Organism) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 0, 4+len(o.Triangles)*40)
+	buf = append(buf, o.Background.R, o.Background.G, o.Background.B, o.Background.A)
+	for _, t := range o.Triangles {
+		for _, p := range []Point{t.P1, t.P2, t.P3} {
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(p.X))
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(p.Y))
+		}
+		var r, g, b, a uint32
+		if t.Color != nil {
+			r, g, b, a = t.Color.RGBA()
+		}
+		buf = append(buf, uint8(r>>8), uint8(g>>8), uint8(b>>8), uint8(a>>8))
+		// only a mode other than normal and a style other than fill, so the
+		// hashes in caches saved before there were either still match
+		if t.Blend != blend.Normal {
+			buf = append(buf, byte(t.Blend))
+		}
+		if t.Style != Fill {
+			buf = append(buf, byte(t.Style))
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(t.Width))
+		}
+	}
+	h.Write(buf)
+	return h.Sum64()
+}
+
+// a hash of the target and of how it's compared, which a fitness is only
+// good for
+func targetHash(target *image.RGBA) uint64 {
+	h := fnv.New64a()
+	var buf []byte
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(target.Rect.Dx()))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(target.Rect.Dy()))
+	for _, weight := range ChannelWeights {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(weight))
+	}
+	h.Write(buf)
+	for y := target.Rect.Min.Y; y < target.Rect.Max.Y; y++ {
+		h.Write(target.Pix[target.PixOffset(target.Rect.Min.X, y):target.PixOffset(target.Rect.Max.X, y)])
+	}
+	return h.Sum64()
+}
+
+// draw the picture of the organism if it doesn't have one, as a child whose
+// fitness came from the cache doesn't
+func (d *Organism) redraw(w, h int) {
+	if d.DNA == nil {
+		d.DNA = draw(w, h, d.Background, d.Triangles)
+	}
+}
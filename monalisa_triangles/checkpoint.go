@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/output"
+	"github.com/sausheong/ga/rng"
+)
+
+func init() {
+	// triangle colors are stored as an interface so gob needs to know the concrete type
+	gob.Register(color.RGBA{})
+}
+
+// Checkpoint is the saved state of a run, which is saved gzipped
+type Checkpoint struct {
+	Generation int
+	// Triangles are the triangles of each organism in older checkpoints,
+	// which have them all rather than Shapes and Genomes
+	Triangles [][]Triangle
+	// Shapes are the different triangles of the population, each once, and
+	// Genomes are the triangles of each organism as positions in Shapes
+	Shapes  []Triangle
+	Genomes [][]int32
+	// Backgrounds are the background colors, which older checkpoints don't have
+	Backgrounds []color.RGBA
+	// RNG is the state of the random numbers, which older checkpoints don't
+	// have
+	RNG []byte
+	// Fitnesses are the fitness cache of the run, if it has one, and
+	// FitnessTarget the hash of the target they're the fitness for
+	Fitnesses     map[uint64]int64
+	FitnessTarget uint64
+	// Evaluations is how many times the fitness had been worked out, and
+	// Samples the best fitness over the last of the run, which tell when it
+	// stalls. Older checkpoints have neither
+	Evaluations int
+	Samples     []savedSample
+}
+
+// savedSample is a sample of the best fitness in a checkpoint, with how long
+// before the checkpoint it was taken rather than when, so the time the run
+// was stopped doesn't count against its improvement a minute
+type savedSample struct {
+	Age         time.Duration
+	Evaluations int
+	Fitness     int64
+}
+
+// save the population of the run, and the fitness cache if there is one, to
+// a checkpoint file
+func saveCheckpoint(filePath string, r *Run) {
+	population := r.Population
+	cp := Checkpoint{
+		Generation:  r.Generation,
+		Backgrounds: make([]color.RGBA, len(population)),
+		Evaluations: r.Evaluations,
+		Samples:     make([]savedSample, len(r.samples)),
+	}
+	cp.share(population)
+	for i := 0; i < len(population); i++ {
+		cp.Backgrounds[i] = population[i].Background
+	}
+	now := time.Now()
+	for i, s := range r.samples {
+		cp.Samples[i] = savedSample{now.Sub(s.at), s.evaluations, s.fitness}
+	}
+	// without the state of the random numbers, a resumed run goes on
+	// differently from one that never stopped, but it still goes on
+	cp.RNG, _ = rng.State()
+	if r.Cache != nil {
+		cp.Fitnesses, cp.FitnessTarget = r.Cache.fitness, r.Cache.target
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	err := gob.NewEncoder(zw).Encode(cp)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = Output.Write(filePath, buf.Bytes())
+	}
+	if err != nil {
+		fmt.Println("Cannot write checkpoint:", err)
+	}
+}
+
+// save a contact sheet of the population of w by h pictures, best first, with
+// each picture made smaller by the scale
+func saveMontage(filePath string, population []Organism, w, h, scale int) {
+	sorted := make([]Organism, len(population))
+	copy(sorted, population)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Fitness < sorted[j].Fitness
+	})
+	for i := range sorted {
+		sorted[i].redraw(w, h)
+		if scale > 1 {
+			sorted[i].DNA = shrink(sorted[i].DNA, scale)
+		}
+	}
+	cols := int(math.Ceil(math.Sqrt(float64(len(sorted)))))
+	save(filePath, contactSheet(sorted, max(cols, 1)))
+}
+
+// load the run from a checkpoint file, redrawing each organism of its
+// population towards its target. With a fitness cache, the cache saved with
+// the checkpoint is loaded into it if it's for the same target, and the
+// organisms in it aren't drawn until they're needed
+func loadCheckpoint(filePath string, run *Run) (err error) {
+	target, cache := run.Target, run.Cache
+	cpFile, err := openCheckpoint(filePath)
+	if err != nil {
+		return
+	}
+	defer cpFile.Close()
+
+	r, err := checkpointReader(cpFile)
+	if err != nil {
+		return
+	}
+	var cp Checkpoint
+	err = gob.NewDecoder(r).Decode(&cp)
+	if err != nil {
+		return
+	}
+	triangles, err := cp.triangles()
+	if err != nil {
+		return
+	}
+
+	if cache != nil && cp.FitnessTarget == cache.target {
+		for hash, fitness := range cp.Fitnesses {
+			if len(cache.fitness) >= cache.size {
+				break
+			}
+			cache.fitness[hash] = fitness
+		}
+	}
+
+	population := make([]Organism, len(triangles))
+	for i := 0; i < len(triangles); i++ {
+		var background color.RGBA
+		if i < len(cp.Backgrounds) {
+			background = cp.Backgrounds[i]
+		}
+		population[i] = Organism{
+			Triangles:  triangles[i],
+			Background: background,
+			ID:         newID(),
+		}
+		if cache != nil {
+			if fitness, ok := cache.get(population[i]); ok {
+				population[i].Fitness = fitness
+				continue
+			}
+		}
+		population[i].DNA = draw(target.Rect.Dx(), target.Rect.Dy(), background, triangles[i])
+		population[i].calcFitness(target)
+		if cache != nil {
+			cache.put(population[i])
+		}
+	}
+	if len(cp.RNG) > 0 {
+		err = rng.Restore(cp.RNG)
+		if err != nil {
+			return
+		}
+	}
+	run.Generation, run.Population, run.Evaluations = cp.Generation, population, cp.Evaluations
+	now := time.Now()
+	run.samples = make([]sample, len(cp.Samples))
+	for i, s := range cp.Samples {
+		run.samples[i] = sample{now.Add(-s.Age), s.Evaluations, s.Fitness}
+	}
+	return
+}
+
+// keep each different triangle of the population once, with the triangles of
+// each organism as positions in them. Children are mostly copies of their
+// parents, so a population has far fewer different triangles than triangles
+func (cp *Checkpoint) share(population []Organism) {
+	positions := make(map[Triangle]int32)
+	cp.Genomes = make([][]int32, len(population))
+	for i, o := range population {
+		genome := make([]int32, len(o.Triangles))
+		for j, t := range o.Triangles {
+			k, ok := positions[t]
+			if !ok {
+				k = int32(len(cp.Shapes))
+				positions[t] = k
+				cp.Shapes = append(cp.Shapes, t)
+			}
+			genome[j] = k
+		}
+		cp.Genomes[i] = genome
+	}
+}
+
+// the triangles of each organism, from the shared ones, or as they were saved
+// in older checkpoints
+func (cp *Checkpoint) triangles() ([][]Triangle, error) {
+	if cp.Genomes == nil {
+		return cp.Triangles, nil
+	}
+	triangles := make([][]Triangle, len(cp.Genomes))
+	for i, genome := range cp.Genomes {
+		triangles[i] = make([]Triangle, len(genome))
+		for j, k := range genome {
+			if k < 0 || int(k) >= len(cp.Shapes) {
+				return nil, fmt.Errorf("organism %d has triangle %d of %d", i, k, len(cp.Shapes))
+			}
+			triangles[i][j] = cp.Shapes[k]
+		}
+	}
+	return triangles, nil
+}
+
+// open the checkpoint where it was saved, in the directory, memory or bucket
+// the output goes to. A checkpoint posted to a URL can't be read back, so a
+// run can't be resumed from one
+func openCheckpoint(name string) (io.ReadCloser, error) {
+	o, ok := Output.(output.ReadSink)
+	if !ok {
+		return nil, fmt.Errorf("cannot read %s back from the output, which only takes files", name)
+	}
+	data, err := o.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// a reader of the checkpoint, which is gzipped unless it's an older one
+func checkpointReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
@@ -0,0 +1,633 @@
+//go:build !js
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sausheong/ga/migrate"
+	"github.com/sausheong/ga/output"
+	"github.com/sausheong/ga/preview"
+	"github.com/sausheong/ga/remote"
+	"github.com/sausheong/ga/rng"
+	"github.com/sausheong/ga/web"
+)
+
+func main() {
+	targetFile := flag.String("target", "./ml.png", "target image to evolve")
+	flag.Float64Var(&MutationRate, "mutation-rate", MutationRate, "rate of mutation")
+	flag.Float64Var(&CrossoverRate, "crossover-rate", CrossoverRate, "chance of breeding a child from 2 parents rather than copying 1")
+	flag.IntVar(&Parents, "parents", Parents, "number of parents of each child bred by crossover")
+	flag.IntVar(&PopSize, "pop-size", PopSize, "size of the population")
+	flag.IntVar(&PoolSize, "pool-size", PoolSize, "max size of the pool")
+	flag.Float64Var(&Mutations.Move, "mutate-move", Mutations.Move, "rate of moving a corner of a triangle")
+	flag.Float64Var(&Mutations.Recolor, "mutate-recolor", Mutations.Recolor, "rate of changing the color of a triangle")
+	flag.Float64Var(&Mutations.Resize, "mutate-resize", Mutations.Resize, "rate of resizing a triangle")
+	flag.Float64Var(&Mutations.Alpha, "mutate-alpha", Mutations.Alpha, "rate of changing how see-through a triangle is")
+	flag.Float64Var(&Mutations.Reorder, "mutate-reorder", Mutations.Reorder, "rate of swapping the order a triangle is drawn in")
+	flag.Float64Var(&Mutations.Stroke, "mutate-stroke", Mutations.Stroke, "rate of changing the width of the outline of a triangle")
+	flag.Float64Var(&Mutations.Blend, "mutate-blend", Mutations.Blend, "rate of changing the blend mode of a triangle, with evolve-blend")
+	mutationsFile := flag.String("mutations", "", "JSON file with the rates of the smaller mutations, like {\"move\": 0.02}")
+	flag.IntVar(&NumTriangles, "triangles", NumTriangles, "number of triangles in each picture")
+	flag.IntVar(&MinSize, "min-size", MinSize, "size of the smallest triangles")
+	flag.IntVar(&MaxSize, "max-size", MaxSize, "size of the largest triangles")
+	flag.IntVar(&SizeGenerations, "size-generations", SizeGenerations, "number of generations over which the smallest size allowed falls from max-size to min-size")
+	flag.IntVar(&LargeTriangles, "large-triangles", LargeTriangles, "number of triangles in the layer of large triangles drawn under the rest")
+	flag.IntVar(&LargeSize, "large-size", LargeSize, "size of the largest triangles in the large layer")
+	flag.BoolVar(&Background, "background", Background, "evolve a background color for each picture")
+	flag.Func("blend", "how new triangles are blended onto the picture: normal, add, multiply or screen (default normal)", Blend.Set)
+	flag.BoolVar(&EvolveBlend, "evolve-blend", EvolveBlend, "give each triangle a blend mode of its own, which evolves")
+	flag.Func("style", "whether new triangles are filled, outlined or both: fill, stroke or both (default fill)", TriangleStyle.Set)
+	flag.BoolVar(&EvolveStyle, "evolve-style", EvolveStyle, "give each triangle a style of its own, which evolves")
+	flag.Float64Var(&StrokeWidth, "stroke-width", StrokeWidth, "widest outline of a triangle, in pixels")
+	flag.Float64Var(&EarlyReject, "early-reject", EarlyReject, "drop children whose fitness estimated from a small picture is this fraction worse than the median, 0 to draw every child in full")
+	flag.Float64Var(&ChannelWeights[3], "alpha-weight", ChannelWeights[3], "how much the alpha channel counts in the fitness next to the colors, 0 to leave it out")
+	flag.Func("color-weights", "how much red, green and blue count in the fitness, like 0.299,0.587,0.114, or luminance", ChannelWeights.SetColors)
+	flag.Func("renderer", "what draws the triangles: "+strings.Join(rendererNames(), ", "), setRenderer)
+	fitnessGRPC := flag.String("fitness-grpc", "", "address of a gRPC evaluator that works out the fitness of each picture instead")
+	benchmark := flag.Int("benchmark-renderers", 0, "time every renderer drawing this many random organisms, the same for each, instead of running")
+	flag.Int64Var(&FitnessLimit, "fitness-limit", FitnessLimit, "fitness of the evolved image we are satisfied with")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve, 0 to evolve until the fitness limit")
+	flag.Float64Var(&MinImprovementPerMinute, "min-improvement-per-minute", MinImprovementPerMinute, "stop once the fitness improves by less than this a minute, 0 to never stop for it")
+	flag.Float64Var(&MinImprovementPer1000, "min-improvement-per-1000", MinImprovementPer1000, "stop once the fitness improves by less than this every 1000 evaluations, 0 to never stop for it")
+	api := flag.String("api", "", "address to serve the job API on instead of running once, e.g. :8080")
+	flag.IntVar(&MaxJobs, "max-jobs", MaxJobs, "number of API jobs evolved at the same time")
+	resume := flag.Bool("resume", false, "resume the run from the checkpoint file")
+	importFile := flag.String("import", "", "JSON file of shapes from geometrize or another polygon evolver to start every organism from, or the imported ones of the init mix")
+	flag.Func("init", "mix of ways of making the first population, like sampled=0.3,greedy=0.1,imported=0.1, with the rest random", Init.Set)
+	flag.IntVar(&GreedyTriangles, "greedy", GreedyTriangles, "number of triangles to place one at a time, each the best of greedy-candidates random ones, before evolving them, 0 to start from random triangles")
+	flag.IntVar(&GreedyCandidates, "greedy-candidates", GreedyCandidates, "number of random triangles tried for each one placed greedily")
+	flag.IntVar(&GreedyAlpha, "greedy-alpha", GreedyAlpha, "alpha of the triangles placed greedily, from 1 to 255")
+	watchDir := flag.String("watch", "", "folder to watch for genome JSON files, like those of -export, each put into the population in place of its worst organism")
+	listen := flag.String("listen", "", "address to receive the best organisms of other runs on, e.g. :7000")
+	peerList := flag.String("peers", "", "host and port of other runs to send the best organism to, like a:7000,b:7000")
+	migrateEvery := flag.Duration("migrate-every", 0, "time between sending the best organism to the peers, like 1m, instead of every 50 generations")
+	exportFile := flag.String("export", "", "JSON file to write the triangles of the best organism to at the end, as geometrize does")
+	flag.StringVar(&CheckpointFile, "checkpoint", CheckpointFile, "checkpoint file to save to when paused")
+	flag.IntVar(&CacheSize, "fitness-cache", CacheSize, "number of genomes to remember the fitness of, so a child the same as one seen before isn't drawn again, saved with each checkpoint, or 0 for none")
+	checkpointEvery := flag.Duration("checkpoint-every", 0, "time between checkpoints, like 10m, or 0 to save one only when paused")
+	montage := flag.String("montage", "", "image file to save a contact sheet of the population to with each checkpoint, best first")
+	flag.IntVar(&MontageScale, "montage-scale", MontageScale, "how many times smaller each picture is in the contact sheet")
+	previewName := flag.String("preview", "auto", "inline image preview: auto, iterm, sixel, kitty or blocks")
+	noPreview := flag.Bool("no-preview", false, "don't show images, only a progress line")
+	previewEvery := flag.Duration("preview-every", 0, "time between previews and frames, like 30s, instead of every 10 generations")
+	serve := flag.String("serve", "", "address to serve the web UI on, e.g. :8080")
+	mapped := flag.Bool("mmap", false, "memory map the target from a raw copy of its pixels saved next to it, for targets too big to read into memory")
+	regionFlag := flag.String("region", "", "evolve only this region of the target, like x,y,width,height, compared where it is in the target rather than copied")
+	morphFile := flag.String("morph", "", "second target image to morph into over the run")
+	morphGenerations := flag.Int("morph-generations", 2000, "number of generations to morph from the target to the second target")
+	webhook := flag.String("webhook", "", "URL to post a message with the best image to when the run finishes or reaches a milestone")
+	milestoneList := flag.String("milestones", "", "fitnesses to post a message to the webhook at, like 20000,15000,10000")
+	outputTo := flag.String("output", "", "where the files of the run go, a local directory, s3://bucket/prefix or gs://bucket/prefix")
+	summary := flag.String("summary", "./summary.json", "file to write a summary of the run to at the end, as Markdown if it ends with .md, or empty for none")
+	lineage := flag.String("lineage", "", "file to write the ancestry of the best organism to at the end, as Graphviz if it ends with .dot, GraphML if it ends with .graphml, or JSON")
+	history := flag.String("history", "", "directory to also save each shown image to, named like evolved_gen001200_f7650.png, rather than only overwriting evolved.png")
+	keep := flag.Int("keep", 0, "number of the latest images to keep in the history directory, 0 for all")
+	heatmapFile := flag.String("heatmap", "", "image file to save a heatmap of where the best image differs from the target to with each shown image")
+	flag.IntVar(&TileGrid, "tile-grid", TileGrid, "number of tiles across and down the heatmap")
+	frames := flag.String("frames", "", "directory to save a frame of the best organism to every 10 generations")
+	video := flag.String("video", "", "directory of frames to evolve one after the other, saving the results to the frames directory")
+	frameGenerations := flag.Int("frame-generations", 500, "max number of generations to evolve each video frame")
+	paletteSize := flag.Int("palette", 0, "constrain triangle colors to a palette of this many colors taken from the target")
+	paletteColors := flag.String("palette-colors", "", "constrain triangle colors to a palette like #ff8800,#000000")
+	interactive := flag.Int("interactive", 0, "every this many generations, pick favorite candidates to give them a fitness bonus")
+	search := flag.String("tune", "", "tune the mutation rate, population size and pool size with a grid or random search instead of running once")
+	tuneGenerations := flag.Int("tune-generations", 200, "number of generations to run each set of parameters for when tuning")
+	tuneSamples := flag.Int("tune-samples", 20, "number of random sets of parameters to try with -tune random")
+	compareFile := flag.String("compare", "", "JSON file of parameters to change for a second run, like {\"mutation_rate\": 0.05}, evolved side by side with the first to compare them")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	err := preview.Set(*previewName)
+	if err != nil {
+		fmt.Println("Cannot set preview:", err)
+		return
+	}
+	if *keep < 0 {
+		fmt.Println("Cannot keep history: need keep >= 0")
+		return
+	}
+	if GreedyTriangles < 0 || GreedyCandidates < 1 || GreedyAlpha < 1 || GreedyAlpha > 255 {
+		fmt.Println("Cannot warm start: need greedy >= 0, greedy-candidates >= 1 and 1 <= greedy-alpha <= 255")
+		return
+	}
+	if TileGrid < 1 {
+		fmt.Println("Cannot tile the heatmap: need tile-grid >= 1")
+		return
+	}
+	if MontageScale < 1 {
+		fmt.Println("Cannot make contact sheet: need montage-scale >= 1")
+		return
+	}
+	if ChannelWeights[3] < 0 {
+		fmt.Println("Cannot weigh alpha: need alpha-weight >= 0")
+		return
+	}
+	if *outputTo != "" {
+		Output, err = output.Open(*outputTo)
+		if err != nil {
+			fmt.Println("Cannot open output:", err)
+			return
+		}
+	}
+	if *fitnessGRPC != "" {
+		evaluator = &remoteEvaluator{client: remote.NewClient(*fitnessGRPC)}
+	}
+	milestones, err := parseMilestones(*milestoneList)
+	if err != nil {
+		fmt.Println("Cannot read milestones:", err)
+		return
+	}
+	if *mutationsFile != "" {
+		err = readMutations(*mutationsFile, &Mutations)
+		if err != nil {
+			fmt.Println("Cannot read mutations:", err)
+			return
+		}
+	}
+	params := defaultParams()
+	err = params.validateRun()
+	if err != nil {
+		fmt.Println("Cannot run:", err)
+		return
+	}
+	if *api != "" {
+		serveJobs(*api)
+		return
+	}
+
+	display := &terminalDisplay{
+		noPreview: *noPreview,
+		heatmap:   *heatmapFile,
+		progress:  preview.NewProgress(params.FitnessLimit),
+		start:     time.Now(),
+	}
+	if *serve != "" {
+		display.monitor = web.NewMonitor(map[string]interface{}{
+			"MutationRate":   params.MutationRate,
+			"CrossoverRate":  params.CrossoverRate,
+			"Parents":        params.Parents,
+			"PopSize":        params.PopSize,
+			"PoolSize":       params.PoolSize,
+			"NumTriangles":   params.NumTriangles,
+			"LargeTriangles": params.LargeTriangles,
+			"Background":     params.Background,
+			"EarlyReject":    params.EarlyReject,
+			"FitnessLimit":   params.FitnessLimit,
+		})
+		display.monitor.Serve(*serve)
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	if *video != "" {
+		out := *frames
+		if out == "" {
+			out = "./evolved_frames"
+		}
+		err = evolveVideo(*video, out, params, *frameGenerations, display)
+		if err != nil {
+			fmt.Println("Cannot evolve video:", err)
+		}
+		return
+	}
+	// each frame of a video starts again from generation 0, so only a single
+	// run keeps a history
+	display.history, display.keep = *history, *keep
+	target, err := loadTarget(*targetFile, *mapped, *regionFlag)
+	if err != nil {
+		fmt.Println("Cannot load target:", err)
+		return
+	}
+	var morph *Morph
+	if *morphFile != "" {
+		var to *image.RGBA
+		to, err = loadTarget(*morphFile, *mapped, *regionFlag)
+		if err != nil {
+			fmt.Println("Cannot load target:", err)
+			return
+		}
+		morph, err = newMorph(target, to, *morphGenerations)
+		if err != nil {
+			fmt.Println("Cannot morph:", err)
+			return
+		}
+	}
+	switch {
+	case *paletteColors != "":
+		params.Palette, err = parsePalette(*paletteColors)
+		if err != nil {
+			fmt.Println("Cannot use palette:", err)
+			return
+		}
+	case *paletteSize > 0:
+		params.Palette = extractPalette(target, *paletteSize)
+	}
+	if *benchmark > 0 {
+		benchmarkRenderers(target, params, *benchmark)
+		return
+	}
+	if *search != "" {
+		if *tuneGenerations < 1 {
+			fmt.Println("Cannot tune: need at least 1 generation")
+			return
+		}
+		trials, err := tuneParams(params, *search, *tuneSamples)
+		if err != nil {
+			fmt.Println("Cannot tune:", err)
+			return
+		}
+		tune(target, trials, *tuneGenerations)
+		return
+	}
+	if *compareFile != "" {
+		other, err := compareParams(*compareFile, params)
+		if err != nil {
+			fmt.Println("Cannot compare:", err)
+			return
+		}
+		display.Target(target)
+		compare(target, [2]Params{params, other}, *seed, preview.Cadence{Generations: 10, Interval: *previewEvery}, *noPreview)
+		return
+	}
+	display.Target(target)
+
+	if *importFile != "" {
+		var skipped int
+		params.Imported, skipped, err = readShapes(*importFile)
+		if err != nil {
+			fmt.Println("Cannot import shapes:", err)
+			return
+		}
+		if len(params.Palette) > 0 {
+			fmt.Println("Cannot import shapes: imported colors aren't in the palette")
+			return
+		}
+		fmt.Printf("Imported %d triangles from %s, skipping %d shapes that aren't polygons or ellipses\n", len(params.Imported.Triangles), *importFile, skipped)
+		// without a mix, every organism starts from the imported triangles
+		if params.Init == (InitMix{}) {
+			params.Init.Imported = 1
+		}
+	}
+	err = params.validateInit()
+	if err != nil {
+		fmt.Println("Cannot make the first population:", err)
+		return
+	}
+
+	var run *Run
+	switch {
+	case *resume:
+		run = &Run{Target: target, Params: params}
+		if CacheSize > 0 {
+			run.Cache = newFitnessCache(CacheSize, target)
+		}
+		err = loadCheckpoint(CheckpointFile, run)
+		if err != nil {
+			fmt.Println("Cannot resume from checkpoint:", err)
+			return
+		}
+		fmt.Printf("Resumed from %s at generation %d\n", CheckpointFile, run.Generation)
+		if run.Cache != nil {
+			fmt.Printf("Loaded the fitness of %d genomes, %d of them in the population\n", len(run.Cache.fitness), run.Cache.Hits)
+		}
+	case GreedyTriangles > 0 && params.Init == (InitMix{}):
+		start := time.Now()
+		genome := greedy(target, GreedyTriangles, params)
+		run = &Run{Target: target, Params: params, Evaluations: GreedyTriangles*GreedyCandidates + params.PopSize}
+		run.Population, err = seededPopulation(target, genome, params)
+		if err != nil {
+			fmt.Println("Cannot warm start:", err)
+			return
+		}
+		fmt.Printf("Placed %d triangles greedily in %s, to a fitness of %d\n", len(genome.Triangles), time.Since(start).Round(time.Millisecond), run.Population[0].Fitness)
+	default:
+		run = newRun(target, params)
+	}
+	if CacheSize > 0 && run.Cache == nil {
+		run.Cache = newFitnessCache(CacheSize, target)
+	}
+	if *lineage != "" {
+		run.Lineage = newLineage(run.Population, run.Generation)
+	}
+
+	// send SIGUSR1 to pause and save a checkpoint, and again to resume
+	pause := make(chan os.Signal, 1)
+	notifyPause(pause)
+
+	// save the population, and a contact sheet of it if asked for
+	checkpointed := false
+	checkpoint := func() {
+		saveCheckpoint(CheckpointFile, run)
+		if *montage != "" {
+			saveMontage(*montage, run.Population, target.Rect.Dx(), target.Rect.Dy(), MontageScale)
+		}
+		checkpointed = true
+	}
+
+	var watch *watcher
+	if *watchDir != "" {
+		watch = newWatcher(*watchDir)
+	}
+	var node *migrate.Node
+	if *listen != "" || *peerList != "" {
+		node, err = migrate.Listen(*listen, parsePeers(*peerList))
+		if err != nil {
+			fmt.Println("Cannot listen for migrants:", err)
+			return
+		}
+		defer node.Close()
+	}
+	migrations := preview.Cadence{Generations: 50, Interval: *migrateEvery}
+
+	stdin := bufio.NewReader(os.Stdin)
+	started := time.Now()
+	var bestOrganism Organism
+	var curve []int64
+	shown := preview.Cadence{Generations: 10, Interval: *previewEvery}
+	checkpoints := preview.Cadence{Interval: *checkpointEvery}
+	found := false
+	for !found {
+		select {
+		case <-pause:
+			checkpoint()
+			fmt.Printf("\nPaused at generation %d, checkpoint saved to %s\n", run.Generation, CheckpointFile)
+			<-pause
+			fmt.Println("Resumed")
+		default:
+		}
+
+		if morph != nil && morph.Moving(run.Generation+1) {
+			run.Retarget(morph.At(run.Generation + 1))
+			// the heatmap follows the moving target, on the scale it started at
+			display.target = run.Target
+			// don't stop until the target has stopped moving
+			run.Params.FitnessLimit = 0
+		} else {
+			run.Params.FitnessLimit = params.FitnessLimit
+		}
+		if watch != nil {
+			watch.inject(run)
+		}
+		if node != nil {
+			migrateBest(node, run, run.Generation > 0 && migrations.Due(run.Generation))
+		}
+		bestOrganism, found = run.Step()
+		if evaluator != nil && evaluator.failed() != nil {
+			fmt.Println("\nCannot evaluate fitness:", evaluator.failed())
+			break
+		}
+		// stop at the most generations, after at least one so there's a best
+		// organism to save even when resumed past them
+		if Generations > 0 && run.Generation >= Generations {
+			found = true
+		}
+		if display.monitor != nil {
+			display.monitor.Record(run.Generation, bestOrganism.Fitness)
+		}
+		if run.Generation%10 == 0 {
+			curve = append(curve, bestOrganism.Fitness)
+		}
+		for len(milestones) > 0 && bestOrganism.Fitness < milestones[0] {
+			notify(*webhook, fmt.Sprintf("Fitness is below %d at generation %d", milestones[0], run.Generation), run.Generation, bestOrganism)
+			milestones = milestones[1:]
+		}
+		if !found && checkpoints.Due(run.Generation) {
+			checkpoint()
+			fmt.Printf("Checkpoint saved to %s at generation %d\n", CheckpointFile, run.Generation)
+		}
+		if !found && shown.Due(run.Generation) {
+			display.Show(run.Generation, bestOrganism.Fitness, run.PoolSize, bestOrganism.DNA)
+			if *frames != "" {
+				save(filepath.Join(*frames, fmt.Sprintf("frame_%06d.png", run.Generation)), bestOrganism.DNA)
+			}
+		}
+		if !found && *interactive > 0 && run.Generation%*interactive == 0 {
+			pickFavorites(run, stdin, preview.Print)
+		}
+	}
+	save("./evolved.png", bestOrganism.DNA)
+	if run.Stalled {
+		fmt.Printf("\nStopped at generation %d as the fitness improved too slowly\n", run.Generation)
+	}
+	elapsed := time.Since(display.start)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+	fmt.Printf("Time in each phase: %s\n", run.Phases)
+	if run.Cache != nil {
+		fmt.Printf("Fitness of %d children from the cache, %d evaluated\n", run.Cache.Hits, run.Evaluations)
+	}
+	if *exportFile != "" {
+		err = writeShapes(*exportFile, exportShapes(bestOrganism, target.Rect.Dx(), target.Rect.Dy()))
+		if err != nil {
+			fmt.Println("Cannot export shapes:", err)
+		}
+	}
+	if *lineage != "" {
+		err = writeAncestry(*lineage, run.Lineage.Ancestry(bestOrganism.ID))
+		if err != nil {
+			fmt.Println("Cannot write lineage:", err)
+		}
+	}
+	notify(*webhook, fmt.Sprintf("Finished %s with a fitness of %d after %d generations in %s", *targetFile, bestOrganism.Fitness, run.Generation, elapsed), run.Generation, bestOrganism)
+	if *summary != "" {
+		artifacts := []string{"./evolved.png"}
+		if *frames != "" {
+			artifacts = append(artifacts, *frames)
+		}
+		if *history != "" {
+			artifacts = append(artifacts, *history)
+		}
+		if checkpointed {
+			artifacts = append(artifacts, CheckpointFile)
+			if *montage != "" {
+				artifacts = append(artifacts, *montage)
+			}
+		}
+		if *lineage != "" {
+			artifacts = append(artifacts, *lineage)
+		}
+		if *exportFile != "" {
+			artifacts = append(artifacts, *exportFile)
+		}
+		err = writeSummary(*summary, Summary{
+			Target:      *targetFile,
+			Seed:        *seed,
+			Params:      run.Params,
+			Started:     started,
+			WallTime:    time.Since(started).String(),
+			Generations: run.Generation,
+			Evaluations: run.Evaluations,
+			Phases:      run.Phases,
+			Fitness:     bestOrganism.Fitness,
+			Curve:       curve,
+			Artifacts:   artifacts,
+		})
+		if err != nil {
+			fmt.Println("Cannot write summary:", err)
+		}
+	}
+}
+
+// shows the run in the terminal, and in the web UI when it's served, saving
+// the evolved image as it goes
+type terminalDisplay struct {
+	noPreview bool
+	progress  *preview.Progress
+	monitor   *web.Monitor
+	start     time.Time
+	// history is the directory each shown image is also saved to, keeping
+	// the latest keep of them, or all of them if keep is 0
+	history string
+	keep    int
+	saved   []string
+	// heatmap is the file a heatmap of the tiles of the best image is saved
+	// to, drawn to the scale of the worst tile of the first one
+	heatmap string
+	target  *image.RGBA
+	scale   float64
+}
+
+// show the target image
+func (t *terminalDisplay) Target(img *image.RGBA) {
+	t.target, t.scale = img, 0
+	if !t.noPreview {
+		preview.Print(img.SubImage(img.Rect))
+	}
+}
+
+// save and show the best organism
+func (t *terminalDisplay) Show(generation int, fitness int64, poolSize int, best *image.RGBA) {
+	save("./evolved.png", best)
+	if t.history != "" {
+		t.saveHistory(generation, fitness, best)
+	}
+	if t.monitor != nil {
+		t.monitor.Update(generation, fitness, best)
+	}
+	if t.noPreview {
+		fmt.Println(t.progress.Line(generation, fitness))
+	} else {
+		sofar := time.Since(t.start)
+		t.progress.Record(generation, fitness)
+		fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | pool size: %d | eta: %s", sofar, generation, fitness, poolSize, t.progress.ETA())
+		fmt.Println()
+		preview.Print(best.SubImage(best.Rect))
+	}
+	if t.heatmap != "" {
+		t.saveHeatmap(best)
+	}
+}
+
+// save the heatmap of the tiles of the best image, and show it with the
+// worst tile, where the next improvements are most likely to be
+func (t *terminalDisplay) saveHeatmap(best *image.RGBA) {
+	if t.target == nil || !best.Rect.Eq(t.target.Rect) {
+		return
+	}
+	tiles := tiles(best, t.target)
+	if t.scale == 0 {
+		t.scale = worstTileError(tiles)
+	}
+	img := heatmap(tiles, t.scale)
+	save(t.heatmap, img)
+	col, row := tiles.Worst()
+	fmt.Printf("worst tile: %d,%d | error: %.1f of %.1f at first\n", col, row, tileError(tiles, col, row), t.scale)
+	if !t.noPreview {
+		preview.Print(img.SubImage(img.Rect))
+	}
+}
+
+// save the image to the history, named after its generation and fitness,
+// removing the oldest once there are more than are kept
+func (t *terminalDisplay) saveHistory(generation int, fitness int64, best *image.RGBA) {
+	name := filepath.Join(t.history, fmt.Sprintf("evolved_gen%06d_f%d.png", generation, fitness))
+	save(name, best)
+	t.saved = append(t.saved, name)
+	for t.keep > 0 && len(t.saved) > t.keep {
+		err := Output.Remove(t.saved[0])
+		if err != nil {
+			fmt.Println("Cannot remove file:", err)
+		}
+		t.saved = t.saved[1:]
+	}
+}
+
+// the peers from a comma separated list of hosts and ports
+func parsePeers(list string) (peers []string) {
+	for _, peer := range strings.Split(list, ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			peers = append(peers, peer)
+		}
+	}
+	return
+}
+
+// put the migrants received from the peers into the run, and if it's time,
+// send the best organism of the run to them. Sending doesn't hold up the run,
+// as a peer that's down can take a while to give up on
+func migrateBest(node *migrate.Node, run *Run, send bool) {
+	for _, genome := range node.Take() {
+		immigrant, err := injectShapes(run, genome)
+		if err != nil {
+			fmt.Println("Cannot take migrant:", err)
+			continue
+		}
+		fmt.Printf("Took a migrant at generation %d with a fitness of %d\n", run.Generation, immigrant.Fitness)
+	}
+	if !send || len(node.Peers) == 0 {
+		return
+	}
+	w, h := run.Target.Rect.Dx(), run.Target.Rect.Dy()
+	genome, err := shapesJSON(exportShapes(fittest(run.Population), w, h))
+	if err != nil {
+		fmt.Println("Cannot send migrant:", err)
+		return
+	}
+	go func() {
+		for _, err := range node.Send(genome) {
+			fmt.Println("Cannot send migrant:", err)
+		}
+	}()
+}
+
+// the milestones from a list of fitnesses, from the highest to the lowest
+func parseMilestones(list string) (milestones []int64, err error) {
+	for _, f := range strings.Split(list, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		m, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		milestones = append(milestones, m)
+	}
+	sort.Slice(milestones, func(i, j int) bool {
+		return milestones[i] > milestones[j]
+	})
+	return
+}
+
+// post the message with the best image to the webhook, if there is one
+func notify(webhook string, text string, generation int, best Organism) {
+	if webhook == "" {
+		return
+	}
+	msg := web.Message{Text: text, Generation: generation, Fitness: best.Fitness}
+	err := web.Notify(webhook, msg, best.DNA)
+	if err != nil {
+		fmt.Println("Cannot notify webhook:", err)
+	}
+}
@@ -0,0 +1,142 @@
+//go:build !js
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"time"
+
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/llgcode/draw2d/draw2dkit"
+	"github.com/sausheong/ga/preview"
+	"github.com/sausheong/ga/rng"
+)
+
+// ChartHeight is the height of the chart of the fitness of the compared runs
+var ChartHeight = 150
+
+// the colors of the lines of the 2 runs in the chart
+var compareColors = [2]color.RGBA{{0, 90, 200, 255}, {220, 60, 20, 255}}
+
+// the parameters of the second run, which are those of the first with the
+// ones in the JSON file changed
+func compareParams(filePath string, base Params) (p Params, err error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return
+	}
+	p = base
+	if err = json.Unmarshal(data, &p); err != nil {
+		return
+	}
+	err = p.validate()
+	return
+}
+
+// evolve 2 runs side by side on the same target, a generation of one and then
+// of the other, until both are good enough. Each has its own random numbers
+// from the same seed, so the only difference between them is their
+// parameters. Every shown generation the best of each is shown next to the
+// other, over a chart of the fitness of both, and saved to compare.png
+func compare(target *image.RGBA, params [2]Params, seed int64, shown preview.Cadence, noPreview bool) {
+	rng.Seed(seed)
+	state, err := rng.State()
+	if err != nil {
+		fmt.Println("Cannot compare:", err)
+		return
+	}
+	states := [2][]byte{state, state}
+	var runs [2]*Run
+	var curves [2][]int64
+	var took [2]time.Duration
+	var done [2]bool
+	// do f with the random numbers of the run, keeping them for next time
+	with := func(i int, f func()) {
+		rng.Restore(states[i])
+		start := time.Now()
+		f()
+		took[i] += time.Since(start)
+		states[i], _ = rng.State()
+	}
+	for i := range runs {
+		with(i, func() { runs[i] = newRun(target, params[i]) })
+	}
+
+	for generation := 1; !done[0] || !done[1]; generation++ {
+		for i, run := range runs {
+			if done[i] {
+				continue
+			}
+			with(i, func() { _, done[i] = run.Step() })
+			curves[i] = append(curves[i], lowestFitness(run.Population))
+		}
+		if !shown.Due(generation) && (!done[0] || !done[1]) {
+			continue
+		}
+		fmt.Printf("\ngeneration: %d | A fitness: %d | B fitness: %d\n", generation, curves[0][len(curves[0])-1], curves[1][len(curves[1])-1])
+		img := comparison(fittest(runs[0].Population), fittest(runs[1].Population), curves)
+		save("./compare.png", img)
+		if !noPreview {
+			preview.Print(img.SubImage(img.Rect))
+		}
+	}
+
+	fmt.Println()
+	for i, run := range runs {
+		fmt.Printf("%c reached the fitness limit after %d generations and %d evaluations in %s\n",
+			'A'+i, run.Generation, run.Evaluations, took[i].Round(time.Millisecond))
+	}
+}
+
+// the best of each run next to each other, over a chart of the fitness of the
+// runs by generation, with the same scale for both
+func comparison(a, b Organism, curves [2][]int64) *image.RGBA {
+	sheet := contactSheet([]Organism{a, b}, 2)
+	w, h := max(sheet.Rect.Dx(), 200), sheet.Rect.Dy()
+	img := image.NewRGBA(image.Rect(0, 0, w, h+ChartHeight))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for row := 0; row < h; row++ {
+		copy(img.Pix[img.PixOffset(0, row):], sheet.Pix[sheet.PixOffset(0, row):sheet.PixOffset(sheet.Rect.Dx(), row)])
+	}
+
+	generations := max(len(curves[0]), len(curves[1]))
+	high, low := curves[0][0], curves[0][0]
+	for _, c := range curves {
+		for _, f := range c {
+			high, low = max(high, f), min(low, f)
+		}
+	}
+	margin := 5.0
+	point := func(g int, f int64) (float64, float64) {
+		x := margin + float64(g)/float64(max(generations-1, 1))*(float64(w)-2*margin)
+		y := float64(h) + float64(ChartHeight)/2
+		if high > low {
+			y = float64(h) + margin + float64(high-f)/float64(high-low)*(float64(ChartHeight)-2*margin)
+		}
+		return x, y
+	}
+	gc := draw2dimg.NewGraphicContext(img)
+	gc.SetStrokeColor(color.RGBA{200, 200, 200, 255})
+	gc.SetLineWidth(1)
+	draw2dkit.Rectangle(gc, 0.5, float64(h)+0.5, float64(w)-0.5, float64(h+ChartHeight)-0.5)
+	gc.Stroke()
+	gc.SetLineWidth(2)
+	for i, c := range curves {
+		gc.SetStrokeColor(compareColors[i])
+		for g, f := range c {
+			if g == 0 {
+				gc.MoveTo(point(g, f))
+			} else {
+				gc.LineTo(point(g, f))
+			}
+		}
+		gc.Stroke()
+	}
+	return img
+}
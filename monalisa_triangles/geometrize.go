@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// the types of shape in the JSON that geometrize exports
+const (
+	shapeRectangle = iota
+	shapeRotatedRectangle
+	shapeTriangle
+	shapeEllipse
+	shapeRotatedEllipse
+	shapeCircle
+)
+
+// number of triangles each ellipse or circle is cut into
+const ellipseTriangles = 8
+
+// Shape is a shape in a JSON list of shapes from another polygon evolver.
+// Geometrize exports a type and its data, like {"type": 2, "data": [x1, y1,
+// x2, y2, x3, y3], "color": [r, g, b, a]}, and other tools a polygon, like
+// {"points": [[x1, y1], [x2, y2], [x3, y3]], "color": "#ff880080"}
+type Shape struct {
+	Type   int          `json:"type"`
+	Data   []float64    `json:"data,omitempty"`
+	Points [][2]float64 `json:"points,omitempty"`
+	Color  ShapeColor   `json:"color"`
+	Score  float64      `json:"score,omitempty"`
+}
+
+// ShapeColor is the color of a shape, which isn't premultiplied by its alpha,
+// read from a list like [r, g, b, a] or a hex color like #rrggbbaa
+type ShapeColor color.NRGBA
+
+// UnmarshalJSON reads the color from a list or a hex color
+func (c *ShapeColor) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		h := strings.TrimPrefix(s, "#")
+		if len(h) == 6 {
+			h += "ff"
+		}
+		v, err := strconv.ParseUint(h, 16, 32)
+		if len(h) != 8 || err != nil {
+			return fmt.Errorf("color %q isn't like #rrggbb or #rrggbbaa", s)
+		}
+		*c = ShapeColor{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
+		return nil
+	}
+	var list []int
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	if len(list) < 3 || len(list) > 4 {
+		return fmt.Errorf("color %v isn't like [r, g, b, a]", list)
+	}
+	if len(list) == 3 {
+		list = append(list, 255)
+	}
+	for _, v := range list {
+		if v < 0 || v > 255 {
+			return fmt.Errorf("color %v has a value outside 0 to 255", list)
+		}
+	}
+	*c = ShapeColor{uint8(list[0]), uint8(list[1]), uint8(list[2]), uint8(list[3])}
+	return nil
+}
+
+// MarshalJSON writes the color as a list like [r, g, b, a], as geometrize does
+func (c ShapeColor) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]int{int(c.R), int(c.G), int(c.B), int(c.A)})
+}
+
+// Genome is the background and triangles an organism starts from
+type Genome struct {
+	Background color.RGBA
+	Triangles  []Triangle
+}
+
+// read the shapes from the JSON file as a genome
+func readShapes(filePath string) (genome Genome, skipped int, err error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return
+	}
+	return parseShapes(data)
+}
+
+// parse the shapes from JSON, a list of them or an object with them as its
+// shapes, as a genome. Geometrize starts with an opaque rectangle from the
+// top left corner covering the picture, as -export does with the background,
+// so a first shape like that is taken as the background. Other rectangles,
+// polygons, ellipses and circles are cut into triangles, and the number of
+// shapes that can't be, like lines and curves, is returned as skipped
+func parseShapes(data []byte) (genome Genome, skipped int, err error) {
+	var shapes []Shape
+	if err = json.Unmarshal(data, &shapes); err != nil {
+		var doc struct {
+			Shapes []Shape `json:"shapes"`
+		}
+		if json.Unmarshal(data, &doc) != nil {
+			return
+		}
+		shapes, err = doc.Shapes, nil
+	}
+	if len(shapes) > 0 && shapes[0].isBackground() {
+		genome.Background = color.RGBA(color.NRGBA(shapes[0].Color))
+		shapes = shapes[1:]
+	}
+	for _, s := range shapes {
+		polygon, ok := s.polygon()
+		if !ok {
+			skipped++
+			continue
+		}
+		// the colors of the triangles are premultiplied by their alpha
+		c := color.RGBAModel.Convert(color.NRGBA(s.Color)).(color.RGBA)
+		for i := 1; i+1 < len(polygon); i++ {
+			genome.Triangles = append(genome.Triangles, Triangle{P1: polygon[0], P2: polygon[i], P3: polygon[i+1], Color: c})
+		}
+	}
+	return
+}
+
+// whether the shape is an opaque rectangle from the top left corner
+func (s Shape) isBackground() bool {
+	return s.Type == shapeRectangle && len(s.Points) == 0 && len(s.Data) >= 4 &&
+		s.Data[0] == 0 && s.Data[1] == 0 && s.Color.A == 255
+}
+
+// the corners of the shape as a polygon that fans out from its first corner,
+// or false if it isn't a shape that can be
+func (s Shape) polygon() (points []Point, ok bool) {
+	if len(s.Points) > 0 {
+		for _, p := range s.Points {
+			points = append(points, point(p[0], p[1]))
+		}
+		return points, len(points) >= 3
+	}
+	d := s.Data
+	switch {
+	case s.Type == shapeRectangle && len(d) >= 4:
+		return rotated(d[0], d[1], d[2], d[3], 0), true
+	case s.Type == shapeRotatedRectangle && len(d) >= 5:
+		return rotated(d[0], d[1], d[2], d[3], d[4]), true
+	case s.Type == shapeTriangle && len(d) >= 6:
+		return []Point{point(d[0], d[1]), point(d[2], d[3]), point(d[4], d[5])}, true
+	case s.Type == shapeEllipse && len(d) >= 4:
+		return ellipse(d[0], d[1], d[2], d[3], 0), true
+	case s.Type == shapeRotatedEllipse && len(d) >= 5:
+		return ellipse(d[0], d[1], d[2], d[3], d[4]), true
+	case s.Type == shapeCircle && len(d) >= 3:
+		return ellipse(d[0], d[1], d[2], d[2], 0), true
+	}
+	return nil, false
+}
+
+// the point nearest to x and y
+func point(x, y float64) Point {
+	return Point{X: int(math.Round(x)), Y: int(math.Round(y))}
+}
+
+// the corners of the rectangle between x1, y1 and x2, y2, turned by the
+// angle in degrees around its center
+func rotated(x1, y1, x2, y2, angle float64) []Point {
+	cx, cy := (x1+x2)/2, (y1+y2)/2
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	var points []Point
+	for _, c := range [][2]float64{{x1, y1}, {x2, y1}, {x2, y2}, {x1, y2}} {
+		dx, dy := c[0]-cx, c[1]-cy
+		points = append(points, point(cx+dx*cos-dy*sin, cy+dx*sin+dy*cos))
+	}
+	return points
+}
+
+// the corners of a polygon around the ellipse at x and y with radii rx and
+// ry, turned by the angle in degrees, starting from its center so the
+// triangles fanning out from it cover the ellipse
+func ellipse(x, y, rx, ry, angle float64) []Point {
+	points := []Point{point(x, y)}
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	for i := 0; i <= ellipseTriangles; i++ {
+		a := 2 * math.Pi * float64(i) / ellipseTriangles
+		dx, dy := rx*math.Cos(a), ry*math.Sin(a)
+		points = append(points, point(x+dx*cos-dy*sin, y+dx*sin+dy*cos))
+	}
+	return points
+}
+
+// the organism, which is w by h pixels, as the shapes geometrize exports, a
+// rectangle of the background if it has one and then each of its triangles
+func exportShapes(o Organism, w, h int) []Shape {
+	var shapes []Shape
+	if o.Background.A > 0 {
+		shapes = append(shapes, Shape{
+			Type:  shapeRectangle,
+			Data:  []float64{0, 0, float64(w), float64(h)},
+			Color: ShapeColor(color.NRGBAModel.Convert(o.Background).(color.NRGBA)),
+		})
+	}
+	for _, t := range o.Triangles {
+		shapes = append(shapes, Shape{
+			Type:  shapeTriangle,
+			Data:  []float64{float64(t.P1.X), float64(t.P1.Y), float64(t.P2.X), float64(t.P2.Y), float64(t.P3.X), float64(t.P3.Y)},
+			Color: ShapeColor(color.NRGBAModel.Convert(t.Color).(color.NRGBA)),
+		})
+	}
+	return shapes
+}
+
+// write the shapes to the output as geometrize JSON
+func writeShapes(filePath string, shapes []Shape) error {
+	data, err := shapesJSON(shapes)
+	if err != nil {
+		return err
+	}
+	return Output.Write(filePath, data)
+}
+
+// the shapes as geometrize JSON, a shape to a line
+func shapesJSON(shapes []Shape) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, s := range shapes {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  ")
+		buf.Write(data)
+	}
+	buf.WriteString("\n]\n")
+	return buf.Bytes(), nil
+}
+
+// put an organism that starts from the genome JSON into the run in place of
+// its worst, returning the organism
+func injectShapes(run *Run, data []byte) (Organism, error) {
+	genome, _, err := parseShapes(data)
+	if err != nil {
+		return Organism{}, err
+	}
+	if len(run.Params.Palette) > 0 {
+		return Organism{}, fmt.Errorf("imported colors aren't in the palette")
+	}
+	if len(genome.Triangles) > run.Params.NumTriangles {
+		return Organism{}, fmt.Errorf("%d triangles is more than the %d of each organism", len(genome.Triangles), run.Params.NumTriangles)
+	}
+	immigrant := seeded(run.Target, genome, run.Params)
+	run.Inject(immigrant)
+	return immigrant, nil
+}
+
+// a population that all starts from the genome, with invisible random
+// triangles after its own to make up the number of triangles of each organism
+func seededPopulation(target *image.RGBA, genome Genome, p Params) ([]Organism, error) {
+	if n := len(genome.Triangles); n > p.NumTriangles {
+		return nil, fmt.Errorf("%d triangles is more than the %d of each organism, try -triangles %d", n, p.NumTriangles, n)
+	}
+	population := make([]Organism, p.PopSize)
+	for i := range population {
+		population[i] = seeded(target, genome, p)
+	}
+	return population, nil
+}
+
+// an organism that starts from the genome, with invisible random triangles
+// after its own to make up the number of triangles
+func seeded(target *image.RGBA, genome Genome, p Params) Organism {
+	w, h := target.Rect.Dx(), target.Rect.Dy()
+	o := Organism{
+		Triangles:  make([]Triangle, p.NumTriangles),
+		Background: genome.Background,
+		ID:         newID(),
+	}
+	copy(o.Triangles, genome.Triangles)
+	for i := len(genome.Triangles); i < p.NumTriangles; i++ {
+		lo, hi := p.triangleSizes(i, 0)
+		o.Triangles[i] = createTriangle(w, h, p.Palette, lo, hi)
+		o.Triangles[i].Blend = p.blendMode()
+		o.Triangles[i].Style, o.Triangles[i].Width = p.newStyle()
+		o.Triangles[i].Color = color.RGBA{}
+	}
+	o.DNA = draw(w, h, o.Background, o.Triangles)
+	o.calcFitness(target)
+	return o
+}
@@ -0,0 +1,366 @@
+//go:build gpu && !js
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"strings"
+
+	"github.com/go-gl/gl/v4.3-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/sausheong/ga/blend"
+)
+
+// the GPU backend draws the triangles with OpenGL and compares the picture
+// with the target in a compute shader, so only the partial sums of the
+// difference come back to the CPU. It needs OpenGL 4.3 and cgo, and is built
+// with
+//
+//	go build -tags gpu
+//
+// The triangles aren't anti-aliased as they are by draw2d, so the fitness is a
+// little different from that of the CPU
+
+// the number of pixels each work group of the compute shader adds up, which
+// keeps each partial sum well within 32 bits
+const groupSize = 256
+
+// every call to OpenGL is made on the one thread that holds its context, as
+// the jobs API can run several evolutions at once
+var gpuCalls = make(chan func())
+
+func init() {
+	var g *gpu
+	var err error
+	started := make(chan struct{})
+	go func() {
+		runtime.LockOSThread()
+		g, err = newGPU()
+		close(started)
+		if err != nil {
+			return
+		}
+		for call := range gpuCalls {
+			call()
+		}
+	}()
+	<-started
+	if err != nil {
+		fmt.Println("Cannot start GPU, drawing on the CPU:", err)
+		return
+	}
+	Renderers["gpu"] = g
+	Render = g
+}
+
+// gpu is the OpenGL state of the backend
+type gpu struct {
+	window         *glfw.Window
+	drawProgram    uint32
+	compareProgram uint32
+	vao, vbo       uint32
+	ssbo           uint32
+	// the framebuffer the organisms are drawn on, and its texture
+	fbo, picture uint32
+	w, h         int
+	// the texture of the target, and the target it was made from
+	target      uint32
+	targetImage *image.RGBA
+	// the picture last drawn, which is still in the picture texture
+	last *image.RGBA
+}
+
+// call f on the thread of the context, waiting for it to finish
+func (g *gpu) do(f func()) {
+	done := make(chan struct{})
+	gpuCalls <- func() {
+		f()
+		close(done)
+	}
+	<-done
+}
+
+// start OpenGL with a hidden window, and compile the shaders
+func newGPU() (*gpu, error) {
+	if err := glfw.Init(); err != nil {
+		return nil, err
+	}
+	glfw.WindowHint(glfw.Visible, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 3)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	window, err := glfw.CreateWindow(1, 1, "monalisa", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	window.MakeContextCurrent()
+	if err = gl.Init(); err != nil {
+		return nil, err
+	}
+	g := &gpu{window: window}
+	g.drawProgram, err = program(map[uint32]string{gl.VERTEX_SHADER: vertexShader, gl.FRAGMENT_SHADER: fragmentShader})
+	if err != nil {
+		return nil, err
+	}
+	g.compareProgram, err = program(map[uint32]string{gl.COMPUTE_SHADER: computeShader})
+	if err != nil {
+		return nil, err
+	}
+	gl.GenVertexArrays(1, &g.vao)
+	gl.BindVertexArray(g.vao)
+	gl.GenBuffers(1, &g.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, g.vbo)
+	// each corner is its position and the color of its triangle
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 6*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(1)
+	gl.VertexAttribPointer(1, 4, gl.FLOAT, false, 6*4, gl.PtrOffset(2*4))
+	gl.GenBuffers(1, &g.ssbo)
+	gl.GenFramebuffers(1, &g.fbo)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	return g, nil
+}
+
+// compile and link the shaders into a program
+func program(shaders map[uint32]string) (uint32, error) {
+	prog := gl.CreateProgram()
+	for kind, source := range shaders {
+		shader := gl.CreateShader(kind)
+		src, free := gl.Strs(source + "\x00")
+		gl.ShaderSource(shader, 1, src, nil)
+		free()
+		gl.CompileShader(shader)
+		var status int32
+		gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+		if status == gl.FALSE {
+			var length int32
+			gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &length)
+			log := strings.Repeat("\x00", int(length+1))
+			gl.GetShaderInfoLog(shader, length, nil, gl.Str(log))
+			return 0, fmt.Errorf("cannot compile shader: %s", strings.TrimRight(log, "\x00"))
+		}
+		gl.AttachShader(prog, shader)
+		defer gl.DeleteShader(shader)
+	}
+	gl.LinkProgram(prog)
+	var status int32
+	gl.GetProgramiv(prog, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var length int32
+		gl.GetProgramiv(prog, gl.INFO_LOG_LENGTH, &length)
+		log := strings.Repeat("\x00", int(length+1))
+		gl.GetProgramInfoLog(prog, length, nil, gl.Str(log))
+		return 0, fmt.Errorf("cannot link shaders: %s", strings.TrimRight(log, "\x00"))
+	}
+	return prog, nil
+}
+
+// make a texture of the size, filled with the pixels of the image if there is
+// one
+func texture(w, h int, img *image.RGBA) (tex uint32) {
+	gl.GenTextures(1, &tex)
+	gl.BindTexture(gl.TEXTURE_2D, tex)
+	gl.TexStorage2D(gl.TEXTURE_2D, 1, gl.RGBA8, int32(w), int32(h))
+	if img != nil {
+		gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, int32(w), int32(h), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	}
+	return
+}
+
+// draw the triangles on the GPU, reading the picture back as an image
+func (g *gpu) Draw(w int, h int, background color.RGBA, triangles []Triangle) (img *image.RGBA) {
+	img = image.NewRGBA(image.Rect(0, 0, w, h))
+	// the corners, with the pixels mapped to -1 to 1, so the first row read
+	// back is the top row of the image
+	vertices := make([]float32, 0, len(triangles)*3*6)
+	// the number of corners drawn for each triangle, 3 for its inside and 6
+	// for each edge of its outline
+	corners := make([]int32, len(triangles))
+	for i, t := range triangles {
+		r, gr, b, a := t.Color.RGBA()
+		corner := func(x, y float64) {
+			vertices = append(vertices,
+				2*float32(x)/float32(w)-1, 2*float32(y)/float32(h)-1,
+				float32(r)/0xffff, float32(gr)/0xffff, float32(b)/0xffff, float32(a)/0xffff)
+			corners[i]++
+		}
+		points := []Point{t.P1, t.P2, t.P3}
+		if t.Style != Stroke {
+			for _, p := range points {
+				corner(float64(p.X), float64(p.Y))
+			}
+		}
+		if t.Style != Fill {
+			// each edge is a rectangle as wide as the outline, made of 2
+			// triangles
+			for j, p := range points {
+				q := points[(j+1)%3]
+				dx, dy := float64(q.X-p.X), float64(q.Y-p.Y)
+				l := math.Hypot(dx, dy)
+				if l == 0 {
+					continue
+				}
+				nx, ny := -dy/l*t.Width/2, dx/l*t.Width/2
+				px, py, qx, qy := float64(p.X), float64(p.Y), float64(q.X), float64(q.Y)
+				corner(px+nx, py+ny)
+				corner(px-nx, py-ny)
+				corner(qx+nx, qy+ny)
+				corner(px-nx, py-ny)
+				corner(qx-nx, qy-ny)
+				corner(qx+nx, qy+ny)
+			}
+		}
+	}
+	g.do(func() {
+		if g.w != w || g.h != h {
+			g.resize(w, h)
+		}
+		gl.BindFramebuffer(gl.FRAMEBUFFER, g.fbo)
+		gl.Viewport(0, 0, int32(w), int32(h))
+		gl.ClearColor(float32(background.R)/255, float32(background.G)/255, float32(background.B)/255, float32(background.A)/255)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		if len(vertices) > 0 {
+			gl.UseProgram(g.drawProgram)
+			gl.BindVertexArray(g.vao)
+			gl.BindBuffer(gl.ARRAY_BUFFER, g.vbo)
+			gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STREAM_DRAW)
+			gl.Enable(gl.BLEND)
+			// each run of triangles with the same blend mode is drawn at once
+			for start, first := 0, int32(0); start < len(triangles); {
+				end, count := start, int32(0)
+				for end < len(triangles) && triangles[end].Blend == triangles[start].Blend {
+					count += corners[end]
+					end++
+				}
+				setBlend(triangles[start].Blend)
+				gl.DrawArrays(gl.TRIANGLES, first, count)
+				start, first = end, first+count
+			}
+		}
+		gl.ReadPixels(0, 0, int32(w), int32(h), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+		g.last = img
+	})
+	return
+}
+
+// set how the colors of the triangles are blended with the picture. The
+// colors are premultiplied, as they are in image.RGBA, and the alpha is always
+// drawn over. Multiply is only the same as on the CPU where the picture is
+// opaque
+func setBlend(m blend.Mode) {
+	switch m {
+	case blend.Add:
+		gl.BlendFuncSeparate(gl.ONE, gl.ONE, gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	case blend.Multiply:
+		gl.BlendFuncSeparate(gl.DST_COLOR, gl.ONE_MINUS_SRC_ALPHA, gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	case blend.Screen:
+		gl.BlendFuncSeparate(gl.ONE, gl.ONE_MINUS_SRC_COLOR, gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	default:
+		gl.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	}
+}
+
+// make the framebuffer the size of the organisms
+func (g *gpu) resize(w, h int) {
+	if g.picture != 0 {
+		gl.DeleteTextures(1, &g.picture)
+	}
+	g.picture = texture(w, h, nil)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, g.fbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, g.picture, 0)
+	g.w, g.h = w, h
+	g.last = nil
+}
+
+// the difference between the pictures, added up on the GPU. a is usually the
+// picture just drawn, which is still in the framebuffer, and b the target
+func (g *gpu) diff(a, b *image.RGBA) (d int64) {
+	w, h := a.Rect.Dx(), a.Rect.Dy()
+	groups := (w*h + groupSize - 1) / groupSize
+	partials := make([]uint32, groups)
+	g.do(func() {
+		if g.w != w || g.h != h {
+			g.resize(w, h)
+		}
+		if g.targetImage != b {
+			if g.target != 0 {
+				gl.DeleteTextures(1, &g.target)
+			}
+			g.target, g.targetImage = texture(w, h, b), b
+		}
+		if g.last != a {
+			gl.BindTexture(gl.TEXTURE_2D, g.picture)
+			gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, int32(w), int32(h), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(a.Pix))
+			g.last = a
+		}
+		gl.UseProgram(g.compareProgram)
+		gl.BindImageTexture(0, g.picture, 0, false, 0, gl.READ_ONLY, gl.RGBA8)
+		gl.BindImageTexture(1, g.target, 0, false, 0, gl.READ_ONLY, gl.RGBA8)
+		gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, g.ssbo)
+		gl.BufferData(gl.SHADER_STORAGE_BUFFER, groups*4, nil, gl.DYNAMIC_READ)
+		gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, 0, g.ssbo)
+		gl.DispatchCompute(uint32(groups), 1, 1)
+		gl.MemoryBarrier(gl.BUFFER_UPDATE_BARRIER_BIT)
+		gl.GetBufferSubData(gl.SHADER_STORAGE_BUFFER, 0, groups*4, gl.Ptr(partials))
+	})
+	for _, p := range partials {
+		d += int64(p)
+	}
+	return int64(math.Sqrt(float64(d)))
+}
+
+// the vertex shader maps the corners to the framebuffer and passes on the
+// color
+const vertexShader = `#version 430 core
+layout(location = 0) in vec2 position;
+layout(location = 1) in vec4 color;
+out vec4 fill;
+void main() {
+	gl_Position = vec4(position, 0.0, 1.0);
+	fill = color;
+}`
+
+// the fragment shader fills the triangle with its color
+const fragmentShader = `#version 430 core
+in vec4 fill;
+out vec4 pixel;
+void main() {
+	pixel = fill;
+}`
+
+// the compute shader adds up the square differences of the channels of the
+// pixels in each work group, writing a partial sum for each group
+const computeShader = `#version 430 core
+layout(local_size_x = 256) in;
+layout(rgba8, binding = 0) readonly uniform image2D picture;
+layout(rgba8, binding = 1) readonly uniform image2D target;
+layout(std430, binding = 0) writeonly buffer Partials { uint partials[]; };
+shared uint sums[256];
+void main() {
+	ivec2 size = imageSize(picture);
+	uint i = gl_GlobalInvocationID.x;
+	uint sum = 0u;
+	if (i < uint(size.x * size.y)) {
+		ivec2 p = ivec2(int(i) % size.x, int(i) / size.x);
+		uvec4 a = uvec4(round(imageLoad(picture, p) * 255.0));
+		uvec4 b = uvec4(round(imageLoad(target, p) * 255.0));
+		ivec4 d = ivec4(a) - ivec4(b);
+		sum = uint(dot(vec4(d * d), vec4(1.0)));
+	}
+	sums[gl_LocalInvocationID.x] = sum;
+	barrier();
+	for (uint n = 128u; n > 0u; n >>= 1) {
+		if (gl_LocalInvocationID.x < n) {
+			sums[gl_LocalInvocationID.x] += sums[gl_LocalInvocationID.x + n];
+		}
+		barrier();
+	}
+	if (gl_LocalInvocationID.x == 0u) {
+		partials[gl_WorkGroupID.x] = sums[0];
+	}
+}`
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/llgcode/draw2d/draw2dimg"
+)
+
+// GreedyCandidates is the number of random triangles tried for each one placed
+// by the greedy warm start
+var GreedyCandidates = 50
+
+// GreedyAlpha is the alpha of the triangles placed by the greedy warm start
+var GreedyAlpha = 128
+
+// place n triangles on a picture one at a time, as primitive does, each the
+// best of GreedyCandidates random ones in the color that brings it closest to
+// the target, over the average color of the target if the organisms have a
+// background
+func greedy(target *image.RGBA, n int, p Params) (genome Genome) {
+	w, h := target.Rect.Dx(), target.Rect.Dy()
+	if p.Background {
+		genome.Background = averageColor(target)
+	}
+	current := image.NewRGBA(target.Rect)
+	fill(current, genome.Background)
+	for i := 0; i < n; i++ {
+		var best *image.RGBA
+		var bestTriangle Triangle
+		var bestFitness int64
+		for k := 0; k < GreedyCandidates; k++ {
+			lo, hi := p.triangleSizes(i, 0)
+			t := createTriangle(w, h, nil, lo, hi)
+			if len(p.Palette) > 0 {
+				// the colors of the palette are opaque
+				t.Index = nearestColor(p.Palette, bestColor(target, current, t, 255))
+				t.Color = p.Palette[t.Index]
+			} else {
+				t.Color = bestColor(target, current, t, GreedyAlpha)
+			}
+			candidate := drawOn(current, t)
+			fitness := diff(candidate, target)
+			if best == nil || fitness < bestFitness {
+				best, bestTriangle, bestFitness = candidate, t, fitness
+			}
+		}
+		current = best
+		genome.Triangles = append(genome.Triangles, bestTriangle)
+	}
+	return
+}
+
+// a copy of the picture with the triangle drawn on it
+func drawOn(picture *image.RGBA, t Triangle) *image.RGBA {
+	img := image.NewRGBA(picture.Rect)
+	copy(img.Pix, picture.Pix)
+	gc := draw2dimg.NewGraphicContext(img)
+	gc.SetFillColor(t.Color)
+	gc.MoveTo(float64(t.P1.X), float64(t.P1.Y))
+	gc.LineTo(float64(t.P2.X), float64(t.P2.Y))
+	gc.LineTo(float64(t.P3.X), float64(t.P3.Y))
+	gc.Close()
+	gc.Fill()
+	return img
+}
+
+// the color of the triangle, with the alpha, that makes the pixels under it
+// closest to the target once it's drawn over the current picture. Each pixel
+// would be best with the color that blends with the current one into the
+// target, and the best single color is the average of those
+func bestColor(target, current *image.RGBA, t Triangle, alpha int) color.RGBA {
+	a := float64(alpha) / 255
+	var sum [3]float64
+	count := 0
+	forEachPixel(t, target.Rect, func(x, y int) {
+		i := target.PixOffset(x, y)
+		for c := 0; c < 3; c++ {
+			sum[c] += (float64(target.Pix[i+c]) - (1-a)*float64(current.Pix[i+c])) / a
+		}
+		count++
+	})
+	if count == 0 {
+		return color.RGBA{}
+	}
+	// the colors of the triangles are premultiplied by their alpha
+	channel := func(c int) uint8 {
+		return uint8(math.Max(0, math.Min(255, sum[c]/float64(count))) * a)
+	}
+	return color.RGBA{channel(0), channel(1), channel(2), uint8(alpha)}
+}
+
+// call f with each pixel inside the triangle and the bounds
+func forEachPixel(t Triangle, bounds image.Rectangle, f func(x, y int)) {
+	r := image.Rect(min(t.P1.X, t.P2.X, t.P3.X), min(t.P1.Y, t.P2.Y, t.P3.Y), max(t.P1.X, t.P2.X, t.P3.X)+1, max(t.P1.Y, t.P2.Y, t.P3.Y)+1).Intersect(bounds)
+	// which side of the edge from a to b the point is on
+	side := func(a, b Point, x, y int) int {
+		return (b.X-a.X)*(y-a.Y) - (b.Y-a.Y)*(x-a.X)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			d1, d2, d3 := side(t.P1, t.P2, x, y), side(t.P2, t.P3, x, y), side(t.P3, t.P1, x, y)
+			negative := d1 < 0 || d2 < 0 || d3 < 0
+			positive := d1 > 0 || d2 > 0 || d3 > 0
+			if !(negative && positive) {
+				f(x, y)
+			}
+		}
+	}
+}
+
+// the average color of the image
+func averageColor(img *image.RGBA) color.RGBA {
+	var sum [3]int
+	n := img.Rect.Dx() * img.Rect.Dy()
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for i := img.PixOffset(img.Rect.Min.X, y); i < img.PixOffset(img.Rect.Max.X, y); i += 4 {
+			sum[0] += int(img.Pix[i])
+			sum[1] += int(img.Pix[i+1])
+			sum[2] += int(img.Pix[i+2])
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n), 255}
+}
+
+// the position of the color in the palette nearest to the color
+func nearestColor(palette []color.RGBA, c color.RGBA) (nearest int) {
+	best := -1
+	for i, p := range palette {
+		dr, dg, db := int(p.R)-int(c.R), int(p.G)-int(c.G), int(p.B)-int(c.B)
+		d := dr*dr + dg*dg + db*db
+		if best < 0 || d < best {
+			nearest, best = i, d
+		}
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/sausheong/ga/imgdiff"
+)
+
+// TileGrid is the number of tiles across and down the fitness is broken into
+// for the heatmap
+var TileGrid = 16
+
+// the differences between the picture and the target for each tile of the grid
+func tiles(picture, target *image.RGBA) imgdiff.Tiles {
+	return imgdiff.TileSumsImages(picture, target, TileGrid, TileGrid)
+}
+
+// the root mean square difference of each byte of the tile
+func tileError(t imgdiff.Tiles, col, row int) float64 {
+	r := t.Rect(col, row)
+	return math.Sqrt(float64(t.At(col, row)) / float64(r.Dx()*r.Dy()*4))
+}
+
+// the worst error of any tile, which heatmaps are drawn to the scale of
+func worstTileError(t imgdiff.Tiles) float64 {
+	col, row := t.Worst()
+	return tileError(t, col, row)
+}
+
+// a heatmap of the tiles, each filled from black where the picture is the
+// same as the target, through red, to yellow where the error is the scale or
+// more. Drawn to the same scale each time, the tiles fade as they improve
+func heatmap(t imgdiff.Tiles, scale float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, t.Width, t.Height))
+	for row := 0; row < t.Rows; row++ {
+		for col := 0; col < t.Cols; col++ {
+			v := 0.0
+			if scale > 0 {
+				v = math.Min(tileError(t, col, row)/scale, 1)
+			}
+			c := color.RGBA{uint8(255 * math.Min(2*v, 1)), uint8(255 * math.Max(2*v-1, 0)), 0, 255}
+			r := t.Rect(col, row)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					img.SetRGBA(x, y, c)
+				}
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// InitMix is the fraction of a new population made each way other than with
+// random triangles, which make up whatever is left
+type InitMix struct {
+	// Sampled organisms have random triangles in the color of the target
+	// under the middle of each
+	Sampled float64 `json:"sampled,omitempty"`
+	// Greedy organisms each have their own triangles placed one at a time by
+	// the greedy warm start
+	Greedy float64 `json:"greedy,omitempty"`
+	// Imported organisms start from the imported triangles
+	Imported float64 `json:"imported,omitempty"`
+}
+
+// Init is the fraction of a new population made each way other than with
+// random triangles
+var Init InitMix
+
+// GreedyTriangles is the number of triangles placed greedily in each greedy
+// organism of the mix, or all of them if it's 0
+var GreedyTriangles = 0
+
+// Set sets the fractions from a list like sampled=0.3,greedy=0.1,imported=0.1,
+// leaving the rest random
+func (m *InitMix) Set(s string) error {
+	mix := InitMix{}
+	for _, field := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return fmt.Errorf("%q isn't like sampled=0.3", field)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "random":
+			// random organisms are whatever the others leave
+		case "sampled":
+			mix.Sampled = v
+		case "greedy":
+			mix.Greedy = v
+		case "imported":
+			mix.Imported = v
+		default:
+			return fmt.Errorf("no way of making organisms called %q, only random, sampled, greedy and imported", name)
+		}
+	}
+	*m = mix
+	return m.validate()
+}
+
+// validate the fractions, which together can't be more than the population
+func (m InitMix) validate() error {
+	if m.Sampled < 0 || m.Greedy < 0 || m.Imported < 0 || m.Sampled+m.Greedy+m.Imported > 1 {
+		return fmt.Errorf("need fractions of init >= 0 that add up to at most 1")
+	}
+	return nil
+}
+
+// validate the mix of ways of making the first population of the parameters,
+// which can only import triangles there are enough of each organism for
+func (p Params) validateInit() error {
+	if err := p.Init.validate(); err != nil {
+		return err
+	}
+	if _, _, imported := p.Init.counts(p.PopSize); imported > 0 && len(p.Imported.Triangles) == 0 && p.Imported.Background.A == 0 {
+		return fmt.Errorf("need shapes to import for the imported organisms")
+	}
+	if n := len(p.Imported.Triangles); n > p.NumTriangles {
+		return fmt.Errorf("%d imported triangles is more than the %d triangles of each organism", n, p.NumTriangles)
+	}
+	return nil
+}
+
+// the number of organisms of the population made each way, sampled, greedy
+// and imported, rounded so they don't add up to more than the population
+func (m InitMix) counts(popSize int) (sampled, greedy, imported int) {
+	round := func(f float64) int {
+		return int(f*float64(popSize) + 0.5)
+	}
+	sampled = min(round(m.Sampled), popSize)
+	greedy = min(round(m.Greedy), popSize-sampled)
+	imported = min(round(m.Imported), popSize-sampled-greedy)
+	return
+}
+
+// create the population, made in the mix of ways of the parameters, the
+// sampled, greedy and imported organisms first and the random ones after them
+func createPopulation(target *image.RGBA, p Params) (population []Organism) {
+	sampled, greedyCount, imported := p.Init.counts(p.PopSize)
+	population = make([]Organism, 0, p.PopSize)
+	for i := 0; i < sampled; i++ {
+		population = append(population, createSampledOrganism(target, p))
+	}
+	n := GreedyTriangles
+	if n <= 0 || n > p.NumTriangles {
+		n = p.NumTriangles
+	}
+	for i := 0; i < greedyCount; i++ {
+		population = append(population, seeded(target, greedy(target, n, p), p))
+	}
+	for i := 0; i < imported; i++ {
+		population = append(population, seeded(target, p.Imported, p))
+	}
+	for len(population) < p.PopSize {
+		population = append(population, createOrganism(target, p))
+	}
+	return
+}
+
+// create an organism with random triangles, each in the color of the target
+// under its middle with a random alpha, or the nearest color in the palette
+func createSampledOrganism(target *image.RGBA, p Params) Organism {
+	o := createOrganism(target, p)
+	bounds := target.Rect
+	for i, t := range o.Triangles {
+		x := max(bounds.Min.X, min(bounds.Max.X-1, (t.P1.X+t.P2.X+t.P3.X)/3))
+		y := max(bounds.Min.Y, min(bounds.Max.Y-1, (t.P1.Y+t.P2.Y+t.P3.Y)/3))
+		c := target.RGBAAt(x, y)
+		if len(p.Palette) > 0 {
+			o.Triangles[i].Index = nearestColor(p.Palette, c)
+			o.Triangles[i].Color = p.Palette[o.Triangles[i].Index]
+			continue
+		}
+		// the colors of the triangles are premultiplied by their alpha
+		c.A = uint8(rng.Intn(256))
+		o.Triangles[i].Color = color.RGBAModel.Convert(color.NRGBA(c)).(color.RGBA)
+	}
+	o.DNA = draw(bounds.Dx(), bounds.Dy(), o.Background, o.Triangles)
+	o.calcFitness(target)
+	return o
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NumCandidates is the number of candidates shown when picking favorites
+var NumCandidates = 9
+
+// FavoriteBonus is the fraction taken off the fitness of a picked favorite,
+// making it more likely to be selected for the next generation
+var FavoriteBonus = 0.2
+
+// show the best candidates of the run with the show function and let the user
+// pick their favorites
+func pickFavorites(run *Run, in *bufio.Reader, show func(image.Image)) {
+	indices := make([]int, len(run.Population))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return run.Population[indices[i]].Fitness < run.Population[indices[j]].Fitness
+	})
+	if len(indices) > NumCandidates {
+		indices = indices[:NumCandidates]
+	}
+	candidates := make([]Organism, len(indices))
+	for i, index := range indices {
+		candidates[i] = run.Population[index]
+		candidates[i].redraw(run.Target.Rect.Dx(), run.Target.Rect.Dy())
+	}
+
+	fmt.Printf("\nGeneration %d, candidates are numbered left to right, top to bottom\n", run.Generation)
+	show(contactSheet(candidates, 3))
+	fmt.Printf("Pick favorites (1-%d separated by spaces, enter to skip): ", len(candidates))
+	line, _ := in.ReadString('\n')
+	for _, field := range strings.Fields(line) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(candidates) {
+			fmt.Println("Ignoring", field)
+			continue
+		}
+		favorite := &run.Population[indices[n-1]]
+		favorite.Fitness -= int64(float64(favorite.Fitness) * FavoriteBonus)
+	}
+}
+
+// lay out the organisms in a grid with the given number of columns
+func contactSheet(organisms []Organism, cols int) *image.RGBA {
+	if len(organisms) == 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+	gap := 2
+	w, h := organisms[0].DNA.Rect.Dx(), organisms[0].DNA.Rect.Dy()
+	rows := (len(organisms) + cols - 1) / cols
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*(w+gap)-gap, rows*(h+gap)-gap))
+	// white gaps between the candidates
+	for i := range sheet.Pix {
+		sheet.Pix[i] = 255
+	}
+	for i, organism := range organisms {
+		x, y := (i%cols)*(w+gap), (i/cols)*(h+gap)
+		for row := 0; row < h; row++ {
+			copy(sheet.Pix[sheet.PixOffset(x, y+row):], organism.DNA.Pix[organism.DNA.PixOffset(0, row):organism.DNA.PixOffset(w, row)])
+		}
+	}
+	return sheet
+}
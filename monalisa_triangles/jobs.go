@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaxJobs is the number of jobs evolved at the same time, the rest wait in
+// the queue
+var MaxJobs = 2
+
+// the states a job goes through
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobPaused    = "paused"
+	jobCancelled = "cancelled"
+	jobDone      = "done"
+)
+
+// Job is an evolution of a target image started through the API
+type Job struct {
+	ID     string
+	Params Params
+	target *image.RGBA
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	cancelled chan struct{}
+	state     string
+	// evolution is the run once it has started, whose best organism and
+	// stats are read while it steps
+	evolution *Run
+	started   time.Time
+	finished  time.Time
+}
+
+// JobStatus is what the API reports about a job
+type JobStatus struct {
+	ID         string `json:"id"`
+	State      string `json:"state"`
+	Generation int    `json:"generation"`
+	Fitness    int64  `json:"fitness"`
+	Elapsed    string `json:"elapsed"`
+	Phases     Phases `json:"phases"`
+	Params     Params `json:"params"`
+}
+
+// create a job and queue it to evolve the target once one of the slots is free
+func startJob(id string, target *image.RGBA, p Params, slots chan struct{}) *Job {
+	job := &Job{
+		ID:        id,
+		Params:    p,
+		target:    target,
+		state:     jobQueued,
+		cancelled: make(chan struct{}),
+	}
+	job.cond = sync.NewCond(&job.mu)
+	go job.run(slots)
+	return job
+}
+
+// wait for a slot then run the evolution until the fitness limit is reached or
+// the job is cancelled
+func (j *Job) run(slots chan struct{}) {
+	select {
+	case slots <- struct{}{}:
+	case <-j.cancelled:
+		return
+	}
+	defer func() { <-slots }()
+
+	j.mu.Lock()
+	if j.state == jobQueued {
+		j.state = jobRunning
+	}
+	j.started = time.Now()
+	j.mu.Unlock()
+
+	run := newRun(j.target, j.Params)
+	j.mu.Lock()
+	j.evolution = run
+	j.mu.Unlock()
+	for {
+		j.mu.Lock()
+		for j.state == jobPaused {
+			j.cond.Wait()
+		}
+		if j.state == jobCancelled {
+			j.mu.Unlock()
+			return
+		}
+		j.mu.Unlock()
+
+		_, done := run.Step()
+		if done {
+			j.mu.Lock()
+			j.state = jobDone
+			j.finished = time.Now()
+			j.mu.Unlock()
+			return
+		}
+	}
+}
+
+// change the state of the job, if it is in one of the given states
+func (j *Job) transition(to string, from ...string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, state := range from {
+		if j.state == state {
+			j.state = to
+			if to == jobCancelled {
+				j.finished = time.Now()
+				close(j.cancelled)
+			}
+			j.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// the current status of the job
+func (j *Job) status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var elapsed time.Duration
+	if !j.started.IsZero() {
+		elapsed = time.Since(j.started)
+	}
+	if !j.finished.IsZero() && !j.started.IsZero() {
+		elapsed = j.finished.Sub(j.started)
+	}
+	var stats Stats
+	if j.evolution != nil {
+		stats = j.evolution.Stats()
+	}
+	return JobStatus{
+		ID:         j.ID,
+		State:      j.state,
+		Generation: stats.Generation,
+		Fitness:    stats.Fitness,
+		Elapsed:    elapsed.Round(time.Second).String(),
+		Phases:     stats.Phases,
+		Params:     j.Params,
+	}
+}
+
+// JobServer serves the API for starting and controlling jobs, keeping
+// finished jobs so their results can still be downloaded
+type JobServer struct {
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	nextID int
+	slots  chan struct{}
+}
+
+// serve the job API at the address
+func serveJobs(addr string) {
+	js := &JobServer{
+		jobs:  make(map[string]*Job),
+		slots: make(chan struct{}, MaxJobs),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", js.create)
+	mux.HandleFunc("GET /jobs", js.list)
+	mux.HandleFunc("GET /jobs/{id}/status", js.status)
+	mux.HandleFunc("GET /jobs/{id}/best.png", js.bestImage)
+	mux.HandleFunc("POST /jobs/{id}/pause", js.control(jobPaused, jobRunning))
+	mux.HandleFunc("POST /jobs/{id}/resume", js.control(jobRunning, jobPaused))
+	mux.HandleFunc("POST /jobs/{id}/cancel", js.control(jobCancelled, jobQueued, jobRunning, jobPaused))
+	fmt.Printf("Serving job API on %s, running up to %d jobs at a time\n", addr, MaxJobs)
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		fmt.Println("Cannot serve job API:", err)
+	}
+}
+
+// start a job from a multipart form with the target image and optional parameters
+func (js *JobServer) create(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("target")
+	if err != nil {
+		http.Error(w, "target image is required: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		http.Error(w, "cannot decode target image: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := paramsFromForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	js.mu.Lock()
+	js.nextID++
+	id := strconv.Itoa(js.nextID)
+	job := startJob(id, toRGBA(img), p, js.slots)
+	js.jobs[id] = job
+	js.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(job.status())
+}
+
+// list the status of all the jobs
+func (js *JobServer) list(w http.ResponseWriter, r *http.Request) {
+	js.mu.Lock()
+	statuses := make([]JobStatus, 0, len(js.jobs))
+	for i := 1; i <= js.nextID; i++ {
+		if job, ok := js.jobs[strconv.Itoa(i)]; ok {
+			statuses = append(statuses, job.status())
+		}
+	}
+	js.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// the status of a job
+func (js *JobServer) status(w http.ResponseWriter, r *http.Request) {
+	job := js.find(w, r)
+	if job == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.status())
+}
+
+// the best image of a job so far
+func (js *JobServer) bestImage(w http.ResponseWriter, r *http.Request) {
+	job := js.find(w, r)
+	if job == nil {
+		return
+	}
+	job.mu.Lock()
+	run := job.evolution
+	job.mu.Unlock()
+	var best *image.RGBA
+	if run != nil {
+		best = run.Best().DNA
+	}
+	if best == nil {
+		http.Error(w, "no image yet", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	png.Encode(w, best)
+}
+
+// a handler that moves a job into a state if it is in one of the from states
+func (js *JobServer) control(to string, from ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job := js.find(w, r)
+		if job == nil {
+			return
+		}
+		if !job.transition(to, from...) {
+			http.Error(w, fmt.Sprintf("job is %s", job.status().State), http.StatusConflict)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job.status())
+	}
+}
+
+// find the job in the request path, or respond with not found
+func (js *JobServer) find(w http.ResponseWriter, r *http.Request) *Job {
+	js.mu.Lock()
+	job, ok := js.jobs[r.PathValue("id")]
+	js.mu.Unlock()
+	if !ok {
+		http.Error(w, "no such job", http.StatusNotFound)
+		return nil
+	}
+	return job
+}
+
+// the parameters of a job, starting from the defaults and overridden by the
+// form fields given
+func paramsFromForm(r *http.Request) (p Params, err error) {
+	p = defaultParams()
+	fields := []struct {
+		name  string
+		parse func(string) error
+	}{
+		{"mutation_rate", func(v string) (err error) { p.MutationRate, err = strconv.ParseFloat(v, 64); return }},
+		{"crossover_rate", func(v string) (err error) { p.CrossoverRate, err = strconv.ParseFloat(v, 64); return }},
+		{"parents", func(v string) (err error) { p.Parents, err = strconv.Atoi(v); return }},
+		{"pop_size", func(v string) (err error) { p.PopSize, err = strconv.Atoi(v); return }},
+		{"pool_size", func(v string) (err error) { p.PoolSize, err = strconv.Atoi(v); return }},
+		{"triangles", func(v string) (err error) { p.NumTriangles, err = strconv.Atoi(v); return }},
+		{"min_size", func(v string) (err error) { p.MinSize, err = strconv.Atoi(v); return }},
+		{"max_size", func(v string) (err error) { p.MaxSize, err = strconv.Atoi(v); return }},
+		{"size_generations", func(v string) (err error) { p.SizeGenerations, err = strconv.Atoi(v); return }},
+		{"large_triangles", func(v string) (err error) { p.LargeTriangles, err = strconv.Atoi(v); return }},
+		{"large_size", func(v string) (err error) { p.LargeSize, err = strconv.Atoi(v); return }},
+		{"background", func(v string) (err error) { p.Background, err = strconv.ParseBool(v); return }},
+		{"blend", p.Blend.Set},
+		{"evolve_blend", func(v string) (err error) { p.EvolveBlend, err = strconv.ParseBool(v); return }},
+		{"style", p.Style.Set},
+		{"evolve_style", func(v string) (err error) { p.EvolveStyle, err = strconv.ParseBool(v); return }},
+		{"stroke_width", func(v string) (err error) { p.StrokeWidth, err = strconv.ParseFloat(v, 64); return }},
+		{"early_reject", func(v string) (err error) { p.EarlyReject, err = strconv.ParseFloat(v, 64); return }},
+		{"min_improvement_per_minute", func(v string) (err error) { p.MinImprovementPerMinute, err = strconv.ParseFloat(v, 64); return }},
+		{"min_improvement_per_1000", func(v string) (err error) { p.MinImprovementPer1000, err = strconv.ParseFloat(v, 64); return }},
+		{"fitness_limit", func(v string) (err error) { p.FitnessLimit, err = strconv.ParseInt(v, 10, 64); return }},
+		{"init", p.Init.Set},
+	}
+	for _, field := range fields {
+		v := r.FormValue(field.name)
+		if v == "" {
+			continue
+		}
+		if err = field.parse(v); err != nil {
+			err = fmt.Errorf("invalid %s: %v", field.name, err)
+			return
+		}
+	}
+	err = p.validate()
+	return
+}
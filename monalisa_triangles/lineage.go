@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// the last organism ID given out. It's shared by every run, so an ID is never
+// given out twice and a larger ID is always a later organism
+var lastID atomic.Int64
+
+// a new organism ID
+func newID() int64 {
+	return lastID.Add(1)
+}
+
+// the IDs of the parents, each once, in the order they're given
+func parentIDs(parents ...Organism) (ids []int64) {
+	for _, p := range parents {
+		seen := false
+		for _, id := range ids {
+			if id == p.ID {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			ids = append(ids, p.ID)
+		}
+	}
+	return
+}
+
+// Ancestor is an organism in the lineage of a run
+type Ancestor struct {
+	ID      int64   `json:"id"`
+	Parents []int64 `json:"parents,omitempty"`
+	// Generation is the generation the organism was born in
+	Generation int   `json:"generation"`
+	Fitness    int64 `json:"fitness"`
+}
+
+// Lineage records the organisms of a run and their parents. Only the ancestors
+// of the current population are kept, so it doesn't grow with every child
+// that leaves no descendants
+type Lineage struct {
+	ancestors map[int64]Ancestor
+}
+
+// start a lineage with the population of the generation
+func newLineage(population []Organism, generation int) *Lineage {
+	l := &Lineage{ancestors: make(map[int64]Ancestor)}
+	l.Add(population, generation)
+	return l
+}
+
+// Add records the organisms of the population born in the generation, and
+// forgets those that are no longer ancestors of any of the population
+func (l *Lineage) Add(population []Organism, generation int) {
+	for _, o := range population {
+		if _, ok := l.ancestors[o.ID]; !ok {
+			l.ancestors[o.ID] = Ancestor{ID: o.ID, Parents: o.Parents, Generation: generation, Fitness: o.Fitness}
+		}
+	}
+	kept := make(map[int64]bool, len(l.ancestors))
+	for _, o := range population {
+		l.walk(o.ID, kept)
+	}
+	for id := range l.ancestors {
+		if !kept[id] {
+			delete(l.ancestors, id)
+		}
+	}
+}
+
+// Ancestry is the organism and all its ancestors, oldest first
+func (l *Lineage) Ancestry(id int64) (ancestry []Ancestor) {
+	seen := make(map[int64]bool)
+	l.walk(id, seen)
+	for id := range seen {
+		ancestry = append(ancestry, l.ancestors[id])
+	}
+	sort.Slice(ancestry, func(i, j int) bool {
+		return ancestry[i].ID < ancestry[j].ID
+	})
+	return
+}
+
+// mark the organism and its ancestors as seen, stopping at those already seen
+func (l *Lineage) walk(id int64, seen map[int64]bool) {
+	stack := []int64{id}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		a, ok := l.ancestors[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		stack = append(stack, a.Parents...)
+	}
+}
+
+// write the ancestry as a Graphviz graph if the file ends with .dot, as
+// GraphML if it ends with .graphml, or as JSON
+func writeAncestry(filePath string, ancestry []Ancestor) error {
+	var data []byte
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".dot", ".gv":
+		data = []byte(dot(ancestry))
+	case ".graphml":
+		data = []byte(graphML(ancestry))
+	default:
+		var err error
+		data, err = json.MarshalIndent(ancestry, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	return Output.Write(filePath, data)
+}
+
+// the ancestry as a Graphviz graph, with an arrow from each parent to its
+// child and the last organism, the descendant of all the others, filled in
+func dot(ancestry []Ancestor) string {
+	var sb strings.Builder
+	sb.WriteString("digraph lineage {\n")
+	sb.WriteString("\tnode [shape=box, fontname=\"Helvetica\"];\n")
+	for i, a := range ancestry {
+		fmt.Fprintf(&sb, "\t%d [label=\"%d\\ngeneration %d\\nfitness %d\"", a.ID, a.ID, a.Generation, a.Fitness)
+		if i == len(ancestry)-1 {
+			sb.WriteString(", style=filled, fillcolor=gold")
+		}
+		sb.WriteString("];\n")
+	}
+	for _, a := range ancestry {
+		for _, p := range a.Parents {
+			fmt.Fprintf(&sb, "\t%d -> %d;\n", p, a.ID)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// the ancestry as a GraphML graph, with the generation and fitness of each
+// organism as data of its node
+func graphML(ancestry []Ancestor) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString("<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	sb.WriteString("  <key id=\"generation\" for=\"node\" attr.name=\"generation\" attr.type=\"int\"/>\n")
+	sb.WriteString("  <key id=\"fitness\" for=\"node\" attr.name=\"fitness\" attr.type=\"long\"/>\n")
+	sb.WriteString("  <graph id=\"lineage\" edgedefault=\"directed\">\n")
+	for _, a := range ancestry {
+		fmt.Fprintf(&sb, "    <node id=\"n%d\">\n", a.ID)
+		fmt.Fprintf(&sb, "      <data key=\"generation\">%d</data>\n", a.Generation)
+		fmt.Fprintf(&sb, "      <data key=\"fitness\">%d</data>\n", a.Fitness)
+		sb.WriteString("    </node>\n")
+	}
+	for _, a := range ancestry {
+		for _, p := range a.Parents {
+			fmt.Fprintf(&sb, "    <edge source=\"n%d\" target=\"n%d\"/>\n", p, a.ID)
+		}
+	}
+	sb.WriteString("  </graph>\n</graphml>\n")
+	return sb.String()
+}
@@ -2,25 +2,34 @@ package main
 
 import (
 	"bytes"
-	"encoding/base64"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"math"
-	"math/rand"
 	"os"
 	"sort"
-	"time"
 
-	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/sausheong/ga/blend"
+	"github.com/sausheong/ga/imgdiff"
+	"github.com/sausheong/ga/output"
+	"github.com/sausheong/ga/rng"
 )
 
-const escape = "\x1b"
+// Output is the sink the files of a run are written to
+var Output output.Sink = output.Local{}
 
 // MutationRate is the rate of mutation
 var MutationRate = 0.021
 
+// CrossoverRate is the chance that a child is bred from 2 parents, otherwise
+// it's a mutated copy of one parent
+var CrossoverRate = 1.0
+
+// Parents is the number of parents of each child bred by crossover. With more
+// than 2 the triangles are cut into a segment from each parent
+var Parents = 2
+
 // PopSize is the size of the population
 var PopSize = 100
 
@@ -30,49 +39,233 @@ var PoolSize = 20
 // NumTriangles is the number of triangles to draw in each picture
 var NumTriangles = 150
 
+// MinSize is the size of the smallest triangles, the furthest their corners
+// can be from the first corner
+var MinSize = 15
+
+// MaxSize is the size of the largest triangles
+var MaxSize = 15
+
+// SizeGenerations is the number of generations over which the smallest size
+// allowed falls from MaxSize to MinSize, so the run starts with large
+// triangles and only later uses small ones for the details. With 0 all sizes
+// are allowed from the start
+var SizeGenerations = 0
+
+// LargeTriangles is the number of triangles in the layer of large triangles,
+// which are drawn first and under the rest, the layer of detail triangles.
+// Crossover only mixes triangles within a layer, so the coarse shape of the
+// picture isn't broken up while the details evolve. With 0 there are no layers
+var LargeTriangles = 0
+
+// LargeSize is the size of the largest triangles in the large layer, which
+// are at least MaxSize
+var LargeSize = 40
+
+// Background is whether each organism evolves a color to fill the picture
+// with before the triangles are drawn, instead of starting from transparent
+var Background = false
+
+// Blend is how new triangles are blended onto the picture, unless each
+// triangle evolves a mode of its own
+var Blend = blend.Normal
+
+// EvolveBlend is whether each triangle has a blend mode of its own, picked at
+// random when the triangle is made and evolving with it
+var EvolveBlend = false
+
+// EarlyReject is how much worse than the median of the population, as a
+// fraction of it, the fitness of a child estimated from a small picture must
+// be for the child to be dropped before it's drawn in full, with its first
+// parent taking its place. The population is estimated the same way for the
+// median. With 0 every child is drawn in full
+var EarlyReject = 0.0
+
+// ScreenScale is how many times smaller the picture that children are
+// screened with is than the target
+var ScreenScale = 8
+
 // FitnessLimit is the fitness of the evolved image we are satisfied with
 var FitnessLimit int64 = 7500
 
-func main() {
-	start := time.Now()
-	rand.Seed(time.Now().UTC().UnixNano())
-	target := load("./ml.png")
-	printImage(target.SubImage(target.Rect))
-
-	population := createPopulation(target)
-
-	found := false
-	generation := 0
-	for !found {
-		generation++
-		bestOrganism := getBest(population)
-		if bestOrganism.Fitness < FitnessLimit {
-			found = true
-		} else {
-			pool := createPool(population, target)
-			population = naturalSelection(pool, population, target)
-			sofar := time.Since(start)
-			if generation%10 == 0 {
-				save("./evolved.png", bestOrganism.DNA)
-				fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | pool size: %d", sofar, generation, bestOrganism.Fitness, len(pool))
-				fmt.Println()
-				printImage(bestOrganism.DNA.SubImage(bestOrganism.DNA.Rect))
-			}
+// ChannelWeights are how much the differences in red, green, blue and alpha
+// count in the fitness. The target is usually opaque everywhere, so the alpha
+// says little about how close the colors are, and a weight of 0 leaves it
+// out. With imgdiff.Luminance the colors count by how bright they look
+var ChannelWeights = imgdiff.Even
+
+// Generations is the most generations to evolve, or 0 to evolve until the
+// fitness is under FitnessLimit
+var Generations = 0
+
+// MinImprovementPerMinute stops a run once the best fitness improves by less
+// than this a minute, and MinImprovementPer1000 once it improves by less than
+// this every 1000 evaluations, so an unattended run doesn't go on for hours
+// for very little. Neither stops a run when it's 0
+var (
+	MinImprovementPerMinute = 0.0
+	MinImprovementPer1000   = 0.0
+)
+
+// RateWindow is how many minutes, or thousands of evaluations, the rate of
+// improvement is measured over, so a single generation that's worse than the
+// last doesn't stop the run
+var RateWindow = 10
+
+// CheckpointFile is where the population is saved when the run is paused
+var CheckpointFile = "./checkpoint.gob"
+
+// MontageScale is how many times smaller each picture is in the contact sheet
+// of the population saved with each checkpoint
+var MontageScale = 1
+
+// Params are the parameters of a run
+type Params struct {
+	MutationRate    float64 `json:"mutation_rate"`
+	CrossoverRate   float64 `json:"crossover_rate"`
+	Parents         int     `json:"parents"`
+	PopSize         int     `json:"pop_size"`
+	PoolSize        int     `json:"pool_size"`
+	NumTriangles    int     `json:"triangles"`
+	FitnessLimit    int64   `json:"fitness_limit"`
+	MinSize         int     `json:"min_size"`
+	MaxSize         int     `json:"max_size"`
+	SizeGenerations int     `json:"size_generations"`
+	LargeTriangles  int     `json:"large_triangles"`
+	LargeSize       int     `json:"large_size"`
+	Background      bool    `json:"background"`
+	EarlyReject     float64 `json:"early_reject"`
+	// Blend is how new triangles are blended onto the picture, unless
+	// EvolveBlend gives each a random mode of its own
+	Blend       blend.Mode `json:"blend"`
+	EvolveBlend bool       `json:"evolve_blend"`
+	// Style is whether new triangles are filled, outlined or both, unless
+	// EvolveStyle gives each a random style of its own, and StrokeWidth is
+	// the widest an outline is
+	Style       Style   `json:"style"`
+	EvolveStyle bool    `json:"evolve_style"`
+	StrokeWidth float64 `json:"stroke_width"`
+	// MinImprovementPerMinute and MinImprovementPer1000 stop the run once
+	// it improves too slowly, unless they're 0
+	MinImprovementPerMinute float64 `json:"min_improvement_per_minute"`
+	MinImprovementPer1000   float64 `json:"min_improvement_per_1000"`
+	// Mutations are the rates of the smaller changes to the triangles
+	Mutations MutationRates `json:"mutations"`
+	// Palette constrains the colors of the triangles when it's not empty
+	Palette []color.RGBA `json:"palette,omitempty"`
+	// Init is the fraction of the first population made each way other than
+	// with random triangles, and Imported is the genome the imported
+	// organisms start from
+	Init     InitMix `json:"init"`
+	Imported Genome  `json:"-"`
+}
+
+// the parameters set by the package variables
+func defaultParams() Params {
+	return Params{
+		MutationRate:    MutationRate,
+		CrossoverRate:   CrossoverRate,
+		Parents:         Parents,
+		PopSize:         PopSize,
+		PoolSize:        PoolSize,
+		NumTriangles:    NumTriangles,
+		FitnessLimit:    FitnessLimit,
+		Mutations:       Mutations,
+		MinSize:         MinSize,
+		MaxSize:         MaxSize,
+		SizeGenerations: SizeGenerations,
+		LargeTriangles:  LargeTriangles,
+		LargeSize:       LargeSize,
+		Background:      Background,
+		EarlyReject:     EarlyReject,
+		Blend:           Blend,
+		EvolveBlend:     EvolveBlend,
+		Style:           TriangleStyle,
+		EvolveStyle:     EvolveStyle,
+		StrokeWidth:     StrokeWidth,
+		Init:            Init,
+
+		MinImprovementPerMinute: MinImprovementPerMinute,
+		MinImprovementPer1000:   MinImprovementPer1000,
+	}
+}
+
+// validate the parameters, naming them as they are in JSON
+func (p Params) validate() error {
+	if err := p.validateRun(); err != nil {
+		return err
+	}
+	return p.validateInit()
+}
+
+// validate the parameters other than how the first population is made, which
+// on the command line can't be until the shapes to import have been read
+func (p Params) validateRun() error {
+	if p.PoolSize < 1 || p.PopSize <= p.PoolSize || p.NumTriangles < 1 {
+		return fmt.Errorf("need 0 < pool_size < pop_size and triangles > 0")
+	}
+	if p.MinSize < 0 || p.MaxSize < p.MinSize {
+		return fmt.Errorf("need 0 <= min_size <= max_size")
+	}
+	if p.LargeTriangles < 0 || p.LargeTriangles > p.NumTriangles || p.LargeSize < p.MaxSize {
+		return fmt.Errorf("need 0 <= large_triangles <= triangles and large_size >= max_size")
+	}
+	if p.EarlyReject < 0 {
+		return fmt.Errorf("need early_reject >= 0")
+	}
+	if p.StrokeWidth < 1 {
+		return fmt.Errorf("need stroke_width >= 1")
+	}
+	if p.MinImprovementPerMinute < 0 || p.MinImprovementPer1000 < 0 {
+		return fmt.Errorf("need min_improvement_per_minute >= 0 and min_improvement_per_1000 >= 0")
+	}
+	return nil
+}
+
+// the range of sizes of the triangles created at the generation
+func (p Params) sizes(generation int) (lo, hi int) {
+	lo, hi = min(p.MinSize, p.MaxSize), p.MaxSize
+	if p.SizeGenerations > 0 && generation < p.SizeGenerations {
+		lo = hi - (hi-lo)*generation/p.SizeGenerations
+	}
+	return
+}
+
+// the range of sizes of the triangle at i created at the generation, which in
+// the large layer is from MaxSize to LargeSize
+func (p Params) triangleSizes(i, generation int) (lo, hi int) {
+	if i < p.LargeTriangles {
+		return p.MaxSize, p.LargeSize
+	}
+	return p.sizes(generation)
+}
+
+// the start and end of each layer of n triangles that has any in it
+func (p Params) layers(n int) (layers [][2]int) {
+	for _, l := range [][2]int{{0, p.LargeTriangles}, {p.LargeTriangles, n}} {
+		if l[1] > l[0] {
+			layers = append(layers, l)
 		}
+	}
+	return
+}
 
+// the start and end of the layer the triangle at i is in
+func (p Params) layer(i, n int) (start, end int) {
+	if i < p.LargeTriangles {
+		return 0, p.LargeTriangles
 	}
-	elapsed := time.Since(start)
-	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+	return p.LargeTriangles, n
 }
 
+// save the image as a PNG file to the output
 func save(filePath string, rgba *image.RGBA) {
-	imgFile, err := os.Create(filePath)
-	defer imgFile.Close()
+	var buf bytes.Buffer
+	png.Encode(&buf, rgba.SubImage(rgba.Rect))
+	err := Output.Write(filePath, buf.Bytes())
 	if err != nil {
-		fmt.Println("Cannot create file:", err)
+		fmt.Println("Cannot write file:", err)
 	}
-
-	png.Encode(imgFile, rgba.SubImage(rgba.Rect))
 }
 
 func getImage(filePath string) image.Image {
@@ -92,39 +285,61 @@ func getImage(filePath string) image.Image {
 
 func load(filePath string) *image.RGBA {
 	img := getImage(filePath)
-	return img.(*image.RGBA)
+	return toRGBA(img)
 }
 
-func diff(a, b *image.RGBA) (d int64) {
-	d = 0
-	for i := 0; i < len(a.Pix); i++ {
-		d += int64(squareDifference(a.Pix[i], b.Pix[i]))
+// convert any image to RGBA, as decoded PNGs are often NRGBA or paletted
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
 	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			rgba.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return rgba
+}
 
-	return int64(math.Sqrt(float64(d)))
+func diff(a, b *image.RGBA) int64 {
+	if evaluator != nil {
+		return evaluator.diff(a, b)
+	}
+	// a target that's a view of a bigger image is compared a row at a time
+	if !tight(a) || !tight(b) {
+		return int64(math.Sqrt(imgdiff.WeightedSumSquaresImages(a, b, ChannelWeights)))
+	}
+	// a renderer that compares too weighs every channel the same
+	if d, ok := Render.(differ); ok && ChannelWeights == imgdiff.Even {
+		return d.diff(a, b)
+	}
+	// b is the target, which is packed once for all the comparisons with it
+	return int64(math.Sqrt(imgdiff.Packed(b.Pix).WeightedSumSquares(a.Pix, ChannelWeights)))
 }
 
-func squareDifference(x, y uint8) uint64 {
-	d := uint64(x) - uint64(y)
-	return d * d
+// whether the rows of the image follow each other with no gaps between them
+func tight(img *image.RGBA) bool {
+	return img.Stride == img.Rect.Dx()*4
 }
 
 // create the reproduction pool that creates the next generation
-func createPool(population []Organism, target *image.RGBA) (pool []Organism) {
+func createPool(population []Organism, target *image.RGBA, p Params) (pool []Organism) {
 	pool = make([]Organism, 0)
 
 	// get top 10 best fitting DNAs
 	sort.SliceStable(population, func(i, j int) bool {
 		return population[i].Fitness < population[j].Fitness
 	})
-	top := population[0 : PoolSize+1]
+	top := population[0 : p.PoolSize+1]
 	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
 		pool = population
 		return
 	}
 	// create a pool for next generation
 	for i := 0; i < len(top)-1; i++ {
-		num := (top[PoolSize].Fitness - top[i].Fitness)
+		num := (top[p.PoolSize].Fitness - top[i].Fitness)
 		for n := int64(0); n < num; n++ {
 			pool = append(pool, top[i])
 		}
@@ -132,45 +347,197 @@ func createPool(population []Organism, target *image.RGBA) (pool []Organism) {
 	return
 }
 
-// perform natural selection to create the next generation
-func naturalSelection(pool []Organism, population []Organism, target *image.RGBA) []Organism {
-	next := make([]Organism, len(population))
+// perform natural selection to create the next generation, returning it with
+// the number of children whose fitness was worked out in full
+func naturalSelection(pool []Organism, population []Organism, target *image.RGBA, p Params, generation int, cache *FitnessCache, phases *Phases) (next []Organism, evaluations int) {
+	next = make([]Organism, len(population))
+	w, h := target.Rect.Dx(), target.Rect.Dy()
+	watch := startStopwatch()
+	var small *image.RGBA
+	var threshold int64
+	if p.EarlyReject > 0 {
+		small = shrink(target, ScreenScale)
+		threshold = int64(float64(medianEstimate(population, small)) * (1 + p.EarlyReject))
+		watch.lap(&phases.Render)
+	}
 
 	for i := 0; i < len(population); i++ {
 		// fmt.Println("pool:", len(pool))
-		r1, r2 := rand.Intn(len(pool)), rand.Intn(len(pool))
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
 		a := pool[r1]
 		b := pool[r2]
-
-		child := crossover(a, b)
-		child.mutate()
+		watch.lap(&phases.Selection)
+
+		var child Organism
+		if rng.Float64() < p.CrossoverRate {
+			if p.Parents > 2 {
+				parents := []Organism{a, b}
+				for len(parents) < p.Parents {
+					parents = append(parents, pool[rng.Intn(len(pool))])
+				}
+				child = multiCrossover(parents, p)
+			} else {
+				child = crossover(a, b, p)
+			}
+		} else {
+			child = clone(a)
+		}
+		watch.lap(&phases.Crossover)
+		child.mutate(w, h, p, generation)
+		watch.lap(&phases.Mutation)
+		if cache != nil {
+			// the picture of a child from the cache is only drawn if it's needed
+			fitness, ok := cache.get(child)
+			watch.lap(&phases.Diff)
+			if ok {
+				child.Fitness = fitness
+				next[i] = child
+				continue
+			}
+		}
+		if small != nil {
+			rejected := child.overEstimate(small, threshold)
+			watch.lap(&phases.Render)
+			if rejected {
+				next[i] = a
+				continue
+			}
+		}
+		child.DNA = draw(w, h, child.Background, child.Triangles)
+		watch.lap(&phases.Render)
 		child.calcFitness(target)
+		evaluations++
+		if cache != nil {
+			cache.put(child)
+		}
+		watch.lap(&phases.Diff)
 
 		next[i] = child
 	}
-	return next
+	return
 }
 
-// creates the initial population
-func createPopulation(target *image.RGBA) (population []Organism) {
-	population = make([]Organism, PopSize)
-	for i := 0; i < PopSize; i++ {
-		population[i] = createOrganism(target)
+// the median of the estimated fitness of the population. Children are compared
+// with the estimates rather than the fitness, as the estimates are a little
+// off, but off in much the same way for every organism
+func medianEstimate(population []Organism, small *image.RGBA) int64 {
+	estimates := make([]int64, len(population))
+	for i := range population {
+		estimates[i] = population[i].estimate(small)
 	}
-	return
+	sort.Slice(estimates, func(i, j int) bool { return estimates[i] < estimates[j] })
+	return estimates[len(estimates)/2]
 }
 
-// Get the best organism
-func getBest(population []Organism) Organism {
-	best := int64(0)
-	index := 0
-	for i := 0; i < len(population); i++ {
-		if population[i].Fitness > best {
-			index = i
-			best = population[i].Fitness
+// estimate the fitness of the organism from a picture of it drawn ScreenScale
+// times smaller, compared with the target shrunk as much
+func (d *Organism) estimate(small *image.RGBA) int64 {
+	picture := d.smallPicture(small)
+	// each small pixel stands for ScreenScale squared pixels
+	return int64(math.Sqrt(imgdiff.WeightedSumSquares(picture.Pix, small.Pix, ChannelWeights))) * int64(ScreenScale)
+}
+
+// whether the estimate of the fitness of the organism is over the threshold.
+// With even weights it stops comparing as soon as the sum so far is too much
+// for the estimate to be under it, which for most rejected children is well
+// before the end of the picture
+func (d *Organism) overEstimate(small *image.RGBA, threshold int64) bool {
+	if ChannelWeights != imgdiff.Even {
+		return d.estimate(small) > threshold
+	}
+	// the estimate is over the threshold once the square root of the sum,
+	// rounded down, is over the threshold divided by ScreenScale
+	limit := uint64(threshold/int64(ScreenScale) + 1)
+	_, ok := imgdiff.SumSquaresBelow(d.smallPicture(small).Pix, small.Pix, limit*limit-1)
+	return !ok
+}
+
+// the organism drawn ScreenScale times smaller, the size of the small target
+func (d *Organism) smallPicture(small *image.RGBA) *image.RGBA {
+	picture := image.NewRGBA(small.Rect)
+	fill(picture, d.Background)
+	for _, t := range d.Triangles {
+		if t.Style != Stroke {
+			fillTriangle(picture, ScreenScale, t)
+		}
+		if t.Style != Fill {
+			strokeTriangle(picture, ScreenScale, t)
+		}
+	}
+	return picture
+}
+
+// fill the triangle on the picture, which is scale times smaller than the
+// triangle. It's much quicker than drawing with draw2d for small pictures, as
+// each pixel is either in the triangle or not, without anti-aliasing
+func fillTriangle(img *image.RGBA, scale int, t Triangle) {
+	s := float64(scale)
+	x1, y1 := float64(t.P1.X)/s, float64(t.P1.Y)/s
+	x2, y2 := float64(t.P2.X)/s, float64(t.P2.Y)/s
+	x3, y3 := float64(t.P3.X)/s, float64(t.P3.Y)/s
+	area := (x2-x1)*(y3-y1) - (x3-x1)*(y2-y1)
+	if area == 0 {
+		return
+	}
+	r, g, b, a := t.Color.RGBA()
+	// the color is premultiplied, as it is in image.RGBA
+	cr, cg, cb, ca := r>>8, g>>8, b>>8, a>>8
+	c := color.RGBA{uint8(cr), uint8(cg), uint8(cb), uint8(ca)}
+	minX := max(0, int(math.Floor(min(x1, x2, x3))))
+	maxX := min(img.Rect.Dx()-1, int(math.Ceil(max(x1, x2, x3))))
+	minY := max(0, int(math.Floor(min(y1, y2, y3))))
+	maxY := min(img.Rect.Dy()-1, int(math.Ceil(max(y1, y2, y3))))
+	for y := minY; y <= maxY; y++ {
+		py := float64(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float64(x) + 0.5
+			// the centre of the pixel is in the triangle if it's on the same
+			// side of every edge as the triangle is
+			e1 := ((x2-x1)*(py-y1) - (px-x1)*(y2-y1)) * area
+			e2 := ((x3-x2)*(py-y2) - (px-x2)*(y3-y2)) * area
+			e3 := ((x1-x3)*(py-y3) - (px-x3)*(y1-y3)) * area
+			if e1 < 0 || e2 < 0 || e3 < 0 {
+				continue
+			}
+			i := img.PixOffset(x, y)
+			p := img.Pix[i : i+4 : i+4]
+			if t.Blend != blend.Normal {
+				blend.Pixel(p, c, 0xffff, t.Blend)
+				continue
+			}
+			p[0] = uint8(cr + uint32(p[0])*(255-ca)/255)
+			p[1] = uint8(cg + uint32(p[1])*(255-ca)/255)
+			p[2] = uint8(cb + uint32(p[2])*(255-ca)/255)
+			p[3] = uint8(ca + uint32(p[3])*(255-ca)/255)
 		}
 	}
-	return population[index]
+}
+
+// shrink the image to one scale times smaller, each pixel the average of the
+// pixels it stands for
+func shrink(img *image.RGBA, scale int) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	small := image.NewRGBA(image.Rect(0, 0, (w+scale-1)/scale, (h+scale-1)/scale))
+	for y := 0; y < small.Rect.Dy(); y++ {
+		for x := 0; x < small.Rect.Dx(); x++ {
+			var sum [4]int
+			n := 0
+			for yy := y * scale; yy < min(h, (y+1)*scale); yy++ {
+				for xx := x * scale; xx < min(w, (x+1)*scale); xx++ {
+					i := img.PixOffset(img.Rect.Min.X+xx, img.Rect.Min.Y+yy)
+					for c := 0; c < 4; c++ {
+						sum[c] += int(img.Pix[i+c])
+					}
+					n++
+				}
+			}
+			i := small.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				small.Pix[i+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return small
 }
 
 // Point represents a position in the image
@@ -185,45 +552,99 @@ type Triangle struct {
 	P2    Point
 	P3    Point
 	Color color.Color
+	// Index is the position of the color in the palette, if there is one
+	Index int
+	// Blend is how the triangle is blended onto the picture
+	Blend blend.Mode
+	// Style is whether the triangle is filled, outlined or both, and Width
+	// is how wide its outline is
+	Style Style
+	Width float64
 }
 
 // Organism represents an individual in the population
 type Organism struct {
 	DNA       *image.RGBA
 	Triangles []Triangle
-	Fitness   int64
+	// Background is the color the picture is filled with first, which is
+	// transparent unless it evolves
+	Background color.RGBA
+	Fitness    int64
+	// ID tells the organism apart from every other, and Parents are the IDs
+	// of the organisms it was bred or copied from
+	ID      int64
+	Parents []int64
 }
 
 // create an organism
-func createOrganism(target *image.RGBA) (organism Organism) {
-	// randomly make triangles
-	triangles := make([]Triangle, NumTriangles)
-	for i := 0; i < NumTriangles; i++ {
-		triangles[i] = createTriangle(target.Rect.Dx(), target.Rect.Dy())
+func createOrganism(target *image.RGBA, p Params) (organism Organism) {
+	// randomly make triangles, as large as they are allowed at the start
+	triangles := make([]Triangle, p.NumTriangles)
+	for i := 0; i < p.NumTriangles; i++ {
+		lo, hi := p.triangleSizes(i, 0)
+		triangles[i] = createTriangle(target.Rect.Dx(), target.Rect.Dy(), p.Palette, lo, hi)
+		triangles[i].Blend = p.blendMode()
+		triangles[i].Style, triangles[i].Width = p.newStyle()
 	}
 
+	var background color.RGBA
+	if p.Background {
+		background = randomBackground()
+	}
 	organism = Organism{
-		DNA:       draw(target.Rect.Dx(), target.Rect.Dy(), triangles),
-		Triangles: triangles,
-		Fitness:   0,
+		DNA:        draw(target.Rect.Dx(), target.Rect.Dy(), background, triangles),
+		Triangles:  triangles,
+		Background: background,
+		Fitness:    0,
+		ID:         newID(),
 	}
 	organism.calcFitness(target)
 	return
 }
 
-func createTriangle(w int, h int) (t Triangle) {
-	p1 := Point{X: rand.Intn(w), Y: rand.Intn(h)}
-	p2 := Point{X: p1.X + (rand.Intn(30) - 15), Y: p1.Y + (rand.Intn(30) - 15)}
-	p3 := Point{X: p1.X + (rand.Intn(30) - 15), Y: p1.Y + (rand.Intn(30) - 15)}
+// a random opaque background color
+func randomBackground() color.RGBA {
+	return color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+}
+
+// the color with each channel changed a little, so the background shifts
+// gradually instead of jumping to another color
+func nudge(c color.RGBA) color.RGBA {
+	channel := func(v uint8) uint8 {
+		return uint8(max(0, min(255, int(v)+rng.Intn(33)-16)))
+	}
+	return color.RGBA{channel(c.R), channel(c.G), channel(c.B), 255}
+}
+
+// create a triangle with its other corners up to a size between lo and hi
+// away from the first
+func createTriangle(w int, h int, palette []color.RGBA, lo, hi int) (t Triangle) {
+	size := lo + rng.Intn(hi-lo+1)
+	p1 := Point{X: rng.Intn(w), Y: rng.Intn(h)}
+	p2 := Point{X: p1.X + (rng.Intn(2*size+1) - size), Y: p1.Y + (rng.Intn(2*size+1) - size)}
+	p3 := Point{X: p1.X + (rng.Intn(2*size+1) - size), Y: p1.Y + (rng.Intn(2*size+1) - size)}
 	t = Triangle{
 		P1:    p1,
 		P2:    p2,
 		P3:    p3,
-		Color: color.RGBA{uint8(rand.Intn(255)), uint8(rand.Intn(255)), uint8(rand.Intn(255)), uint8(rand.Intn(255))},
+		Color: color.RGBA{uint8(rng.Intn(255)), uint8(rng.Intn(255)), uint8(rng.Intn(255)), uint8(rng.Intn(255))},
+	}
+	if len(palette) > 0 {
+		t.Index = rng.Intn(len(palette))
+		t.Color = palette[t.Index]
 	}
 	return
 }
 
+// the blend mode of a new triangle, a random one if each triangle evolves its
+// own
+func (p Params) blendMode() blend.Mode {
+	if p.EvolveBlend {
+		return blend.Modes[rng.Intn(len(blend.Modes))]
+	}
+	return p.Blend
+}
+
 // calculates the fitness of the Organism to the target string
 func (d *Organism) calcFitness(target *image.RGBA) {
 	difference := diff(d.DNA, target)
@@ -234,59 +655,107 @@ func (d *Organism) calcFitness(target *image.RGBA) {
 
 }
 
-// crosses over 2 organisms
-func crossover(d1 Organism, d2 Organism) Organism {
+// crosses over 2 organisms, cutting each layer of triangles in its own place
+func crossover(d1 Organism, d2 Organism, p Params) Organism {
 
 	child := Organism{
-		Triangles: make([]Triangle, len(d1.Triangles)),
-		Fitness:   0,
+		Triangles:  make([]Triangle, len(d1.Triangles)),
+		Background: d1.Background,
+		Fitness:    0,
+		ID:         newID(),
+		Parents:    parentIDs(d1, d2),
+	}
+	if rng.Intn(2) == 0 {
+		child.Background = d2.Background
 	}
 
-	mid := rand.Intn(len(d1.Triangles))
-	for i := 0; i < len(d1.Triangles); i++ {
-		if i > mid {
-			child.Triangles[i] = d1.Triangles[i]
-		} else {
-			child.Triangles[i] = d2.Triangles[i]
+	for _, l := range p.layers(len(d1.Triangles)) {
+		mid := l[0] + rng.Intn(l[1]-l[0])
+		for i := l[0]; i < l[1]; i++ {
+			if i > mid {
+				child.Triangles[i] = d1.Triangles[i]
+			} else {
+				child.Triangles[i] = d2.Triangles[i]
+			}
 		}
-
 	}
-	child.DNA = draw(d1.DNA.Rect.Dx(), d1.DNA.Rect.Dy(), child.Triangles)
 	return child
 }
 
-// mutate the organism
-func (d *Organism) mutate() {
-	for i := 0; i < len(d.Triangles); i++ {
-		if rand.Float64() < MutationRate {
-			d.Triangles[i] = createTriangle(d.DNA.Rect.Dx(), d.DNA.Rect.Dy())
+// crosses over more than 2 organisms, cutting each layer of triangles at
+// random places into a segment from each parent in turn
+func multiCrossover(parents []Organism, p Params) Organism {
+	n := len(parents[0].Triangles)
+	child := Organism{
+		Triangles:  make([]Triangle, n),
+		Background: parents[rng.Intn(len(parents))].Background,
+		Fitness:    0,
+		ID:         newID(),
+		Parents:    parentIDs(parents...),
+	}
+	for _, l := range p.layers(n) {
+		cuts := make([]int, len(parents)-1)
+		for i := range cuts {
+			cuts[i] = l[0] + rng.Intn(l[1]-l[0])
+		}
+		sort.Ints(cuts)
+		k := 0
+		for i := l[0]; i < l[1]; i++ {
+			for k < len(cuts) && i >= cuts[k] {
+				k++
+			}
+			child.Triangles[i] = parents[k].Triangles[i]
 		}
 	}
-	d.DNA = draw(d.DNA.Rect.Dx(), d.DNA.Rect.Dy(), d.Triangles)
+	return child
 }
 
-func draw(w int, h int, triangles []Triangle) *image.RGBA {
-	dest := image.NewRGBA(image.Rect(0, 0, w, h))
-	gc := draw2dimg.NewGraphicContext(dest)
-
-	for _, triangle := range triangles {
-		gc.SetFillColor(triangle.Color)
-		gc.SetStrokeColor(triangle.Color)
-		gc.MoveTo(float64(triangle.P1.X), float64(triangle.P1.Y))
-		gc.LineTo(float64(triangle.P2.X), float64(triangle.P2.Y))
-		gc.LineTo(float64(triangle.P3.X), float64(triangle.P3.Y))
-		gc.Close()
-		gc.Fill()
+// copies the organism, so the copy can be mutated without changing it
+func clone(d Organism) Organism {
+	child := Organism{
+		Triangles:  make([]Triangle, len(d.Triangles)),
+		Background: d.Background,
+		Fitness:    0,
+		ID:         newID(),
+		Parents:    []int64{d.ID},
 	}
+	copy(child.Triangles, d.Triangles)
+	return child
+}
 
-	return dest
+// mutate the organism, which is w by h pixels. Its picture isn't drawn again,
+// as a child is mutated before it's drawn
+func (d *Organism) mutate(w, h int, p Params, generation int) {
+	for i := 0; i < len(d.Triangles); i++ {
+		if rng.Float64() < p.MutationRate {
+			if len(p.Palette) > 0 && rng.Intn(2) == 0 {
+				// only change the color to another one in the palette
+				d.Triangles[i].Index = rng.Intn(len(p.Palette))
+				d.Triangles[i].Color = p.Palette[d.Triangles[i].Index]
+			} else {
+				lo, hi := p.triangleSizes(i, generation)
+				d.Triangles[i] = createTriangle(w, h, p.Palette, lo, hi)
+				d.Triangles[i].Blend = p.blendMode()
+				d.Triangles[i].Style, d.Triangles[i].Width = p.newStyle()
+			}
+		}
+		d.mutateFields(i, p)
+	}
+	if p.Background && rng.Float64() < p.MutationRate {
+		d.Background = nudge(d.Background)
+	}
 }
 
-// this only works for iTerm!
+func draw(w int, h int, background color.RGBA, triangles []Triangle) *image.RGBA {
+	return Render.Draw(w, h, background, triangles)
+}
 
-func printImage(img image.Image) {
-	var buf bytes.Buffer
-	png.Encode(&buf, img)
-	imgBase64Str := base64.StdEncoding.EncodeToString(buf.Bytes())
-	fmt.Printf("%s]1337;File=inline=1:%s\a\n", escape, imgBase64Str)
+// fill the image with the color
+func fill(img *image.RGBA, c color.RGBA) {
+	if c.A == 0 {
+		return
+	}
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
 }
@@ -0,0 +1,145 @@
+//go:build !js
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// the header of a raw target, its magic, width, height and 4 bytes to spare,
+// before its pixels a row at a time
+var rawMagic = [4]byte{'G', 'A', 'R', 'W'}
+
+const rawHeader = 16
+
+// load the target, memory mapped if it's to be, as a view of the region if
+// there is one
+func loadTarget(filePath string, mapped bool, region string) (target *image.RGBA, err error) {
+	if mapped {
+		target, err = mapTarget(filePath)
+		if err != nil {
+			return
+		}
+	} else {
+		target = load(filePath)
+	}
+	if region == "" {
+		return
+	}
+	r, err := parseRegion(region)
+	if err != nil {
+		return nil, err
+	}
+	return view(target, r)
+}
+
+// load the target from a raw copy of its decoded pixels next to it, named
+// like ml.png.rgba, which is memory mapped rather than read. The copy is made
+// the first time, and again whenever the target is newer, so a very large
+// target is only decoded once and its pixels are paged in by the system as
+// they're needed rather than read into the run's own memory
+func mapTarget(filePath string) (*image.RGBA, error) {
+	rawPath := filePath + ".rgba"
+	source, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.Stat(rawPath)
+	if err != nil || raw.ModTime().Before(source.ModTime()) {
+		if err = writeRaw(filePath, rawPath); err != nil {
+			return nil, err
+		}
+		// give back the decoded image before the pixels are mapped
+		debug.FreeOSMemory()
+	}
+	data, err := mapFile(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < rawHeader || [4]byte(data[:4]) != rawMagic {
+		return nil, fmt.Errorf("%s isn't a raw target", rawPath)
+	}
+	w, h := int(binary.BigEndian.Uint32(data[4:])), int(binary.BigEndian.Uint32(data[8:]))
+	if len(data) != rawHeader+w*h*4 {
+		return nil, fmt.Errorf("%s is %d bytes, not the %d of a %dx%d target", rawPath, len(data), rawHeader+w*h*4, w, h)
+	}
+	return &image.RGBA{Pix: data[rawHeader:], Stride: w * 4, Rect: image.Rect(0, 0, w, h)}, nil
+}
+
+// decode the image and write its pixels as a raw target, to a temporary file
+// that's renamed once it's whole so a run that's stopped halfway through
+// doesn't leave half a target behind
+func writeRaw(filePath, rawPath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return err
+	}
+	rgba := toRGBA(img)
+	out, err := os.Create(rawPath + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(rawPath + ".tmp")
+	header := make([]byte, rawHeader)
+	copy(header, rawMagic[:])
+	binary.BigEndian.PutUint32(header[4:], uint32(rgba.Rect.Dx()))
+	binary.BigEndian.PutUint32(header[8:], uint32(rgba.Rect.Dy()))
+	w := bufio.NewWriter(out)
+	w.Write(header)
+	for y := rgba.Rect.Min.Y; y < rgba.Rect.Max.Y; y++ {
+		w.Write(rgba.Pix[rgba.PixOffset(rgba.Rect.Min.X, y):rgba.PixOffset(rgba.Rect.Max.X, y)])
+	}
+	if err = w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(rawPath+".tmp", rawPath)
+}
+
+// parse a region of the target like x,y,width,height
+func parseRegion(s string) (image.Rectangle, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 4 {
+		return image.Rectangle{}, fmt.Errorf("%q isn't like x,y,width,height", s)
+	}
+	var v [4]int
+	for i, f := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return image.Rectangle{}, err
+		}
+		v[i] = n
+	}
+	return image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]), nil
+}
+
+// a view of the region of the image, which shares its pixels rather than
+// copying them, with its top left corner at 0, 0 as the organisms are drawn.
+// Its rows are as far apart as those of the image, so it's compared with the
+// organisms a row at a time
+func view(img *image.RGBA, r image.Rectangle) (*image.RGBA, error) {
+	r = r.Add(img.Rect.Min)
+	if r.Empty() || !r.In(img.Rect) {
+		return nil, fmt.Errorf("region %v isn't inside the %dx%d target", r.Sub(img.Rect.Min), img.Rect.Dx(), img.Rect.Dy())
+	}
+	return &image.RGBA{
+		Pix:    img.Pix[img.PixOffset(r.Min.X, r.Min.Y):],
+		Stride: img.Stride,
+		Rect:   image.Rect(0, 0, r.Dx(), r.Dy()),
+	}, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// there is no mmap on Windows or in the browser so the file is read instead
+func mapFile(filePath string) ([]byte, error) {
+	return os.ReadFile(filePath)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+// Morph is a target that moves from one image to another over a number of
+// generations, so the evolved image morphs along with it
+type Morph struct {
+	From        *image.RGBA
+	To          *image.RGBA
+	Generations int
+}
+
+// create a morph between 2 images of the same size
+func newMorph(from, to *image.RGBA, generations int) (*Morph, error) {
+	if from.Rect.Dx() != to.Rect.Dx() || from.Rect.Dy() != to.Rect.Dy() {
+		return nil, fmt.Errorf("images are %dx%d and %dx%d, they need to be the same size",
+			from.Rect.Dx(), from.Rect.Dy(), to.Rect.Dx(), to.Rect.Dy())
+	}
+	if generations < 1 {
+		generations = 1
+	}
+	return &Morph{From: from, To: to, Generations: generations}, nil
+}
+
+// the target at a generation, linearly blended between the 2 images
+func (m *Morph) At(generation int) *image.RGBA {
+	t := float64(generation) / float64(m.Generations)
+	if t > 1 {
+		t = 1
+	}
+	// either image can be a view with gaps between its rows
+	w, h := m.From.Rect.Dx(), m.From.Rect.Dy()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		from := m.From.Pix[m.From.PixOffset(m.From.Rect.Min.X, m.From.Rect.Min.Y+y):]
+		to := m.To.Pix[m.To.PixOffset(m.To.Rect.Min.X, m.To.Rect.Min.Y+y):]
+		row := img.Pix[img.PixOffset(0, y):img.PixOffset(w, y)]
+		for i := range row {
+			row[i] = uint8(float64(from[i])*(1-t) + float64(to[i])*t + 0.5)
+		}
+	}
+	return img
+}
+
+// whether the target is still changing at the generation
+func (m *Morph) Moving(generation int) bool {
+	return generation <= m.Generations
+}
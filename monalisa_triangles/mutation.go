@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+	"os"
+
+	"github.com/sausheong/ga/blend"
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRates are the chances of each of the smaller changes to a triangle,
+// made independently of each other and of replacing the whole triangle
+type MutationRates struct {
+	// Move moves one of the corners a little
+	Move float64 `json:"move"`
+	// Recolor changes the color but not how see-through it is
+	Recolor float64 `json:"recolor"`
+	// Resize makes the triangle bigger or smaller around its middle
+	Resize float64 `json:"resize"`
+	// Alpha changes how see-through the triangle is
+	Alpha float64 `json:"alpha"`
+	// Reorder swaps the triangle with another, so it's drawn above or below
+	// different triangles
+	Reorder float64 `json:"reorder"`
+	// Stroke widens or narrows the outline of a triangle that has one
+	Stroke float64 `json:"stroke"`
+	// Blend gives the triangle another blend mode, if each triangle evolves
+	// its own
+	Blend float64 `json:"blend"`
+}
+
+// Mutations are the rates of the smaller changes to the triangles, which are
+// all off by default
+var Mutations = MutationRates{}
+
+// MoveSize is the furthest a corner is moved by the move mutation
+var MoveSize = 10
+
+// read the mutation rates from a JSON file, keeping the current rates for
+// any that aren't in it
+func readMutations(filePath string, rates *MutationRates) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, rates)
+}
+
+// make the smaller changes to the triangle at i, each with its own chance
+func (d *Organism) mutateFields(i int, p Params) {
+	t := &d.Triangles[i]
+	m := p.Mutations
+	if rng.Float64() < m.Move {
+		corner := []*Point{&t.P1, &t.P2, &t.P3}[rng.Intn(3)]
+		corner.X += rng.Intn(2*MoveSize+1) - MoveSize
+		corner.Y += rng.Intn(2*MoveSize+1) - MoveSize
+	}
+	if rng.Float64() < m.Recolor {
+		if len(p.Palette) > 0 {
+			t.Index = rng.Intn(len(p.Palette))
+			t.Color = p.Palette[t.Index]
+		} else {
+			c := color.RGBAModel.Convert(t.Color).(color.RGBA)
+			t.Color = color.RGBA{uint8(rng.Intn(255)), uint8(rng.Intn(255)), uint8(rng.Intn(255)), c.A}
+		}
+	}
+	if rng.Float64() < m.Resize {
+		// scale by up to 25% either way around the middle
+		scale := 0.8 + rng.Float64()*0.45
+		cx, cy := (t.P1.X+t.P2.X+t.P3.X)/3, (t.P1.Y+t.P2.Y+t.P3.Y)/3
+		for _, corner := range []*Point{&t.P1, &t.P2, &t.P3} {
+			corner.X = cx + int(float64(corner.X-cx)*scale)
+			corner.Y = cy + int(float64(corner.Y-cy)*scale)
+		}
+	}
+	if rng.Float64() < m.Alpha && len(p.Palette) == 0 {
+		c := color.RGBAModel.Convert(t.Color).(color.RGBA)
+		c.A = uint8(rng.Intn(255))
+		t.Color = c
+	}
+	// only a triangle with an outline takes a chance, so runs without any
+	// go on as they did before there were outlines
+	if m.Stroke > 0 && t.Style != Fill && rng.Float64() < m.Stroke {
+		t.restroke(p)
+	}
+	if m.Blend > 0 && p.EvolveBlend && rng.Float64() < m.Blend {
+		t.Blend = blend.Modes[rng.Intn(len(blend.Modes))]
+	}
+	if rng.Float64() < m.Reorder {
+		// only within the layer, so large triangles stay under the details
+		start, end := p.layer(i, len(d.Triangles))
+		j := start + rng.Intn(end-start)
+		d.Triangles[i], d.Triangles[j] = d.Triangles[j], d.Triangles[i]
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// number of rounds of k-means used to find the palette
+const paletteRounds = 10
+
+// extract a palette of k colors from the image with k-means clustering
+func extractPalette(img *image.RGBA, k int) []color.RGBA {
+	// cluster a sample of the pixels, which is plenty to find the main colors
+	var pixels [][3]float64
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	step := w*h/4096 + 1
+	for i := 0; i < w*h; i += step {
+		p := img.PixOffset(img.Rect.Min.X+i%w, img.Rect.Min.Y+i/w)
+		pixels = append(pixels, [3]float64{float64(img.Pix[p]), float64(img.Pix[p+1]), float64(img.Pix[p+2])})
+	}
+	if k > len(pixels) {
+		k = len(pixels)
+	}
+	centers := make([][3]float64, k)
+	for i := range centers {
+		centers[i] = pixels[rng.Intn(len(pixels))]
+	}
+
+	for round := 0; round < paletteRounds; round++ {
+		sums := make([][3]float64, k)
+		counts := make([]int, k)
+		for _, px := range pixels {
+			nearest, best := 0, -1.0
+			for c, center := range centers {
+				d := (px[0]-center[0])*(px[0]-center[0]) + (px[1]-center[1])*(px[1]-center[1]) + (px[2]-center[2])*(px[2]-center[2])
+				if best < 0 || d < best {
+					nearest, best = c, d
+				}
+			}
+			for j := 0; j < 3; j++ {
+				sums[nearest][j] += px[j]
+			}
+			counts[nearest]++
+		}
+		for c := range centers {
+			if counts[c] == 0 {
+				// restart empty clusters from a random pixel
+				centers[c] = pixels[rng.Intn(len(pixels))]
+				continue
+			}
+			for j := 0; j < 3; j++ {
+				centers[c][j] = sums[c][j] / float64(counts[c])
+			}
+		}
+	}
+
+	palette := make([]color.RGBA, k)
+	for i, center := range centers {
+		palette[i] = color.RGBA{uint8(center[0] + 0.5), uint8(center[1] + 0.5), uint8(center[2] + 0.5), 255}
+	}
+	return palette
+}
+
+// parse a comma separated list of hex colors like #ff8800,#000000
+func parsePalette(s string) (palette []color.RGBA, err error) {
+	for _, hex := range strings.Split(s, ",") {
+		var c color.RGBA
+		c.A = 255
+		_, err = fmt.Sscanf(strings.TrimSpace(hex), "#%02x%02x%02x", &c.R, &c.G, &c.B)
+		if err != nil {
+			err = fmt.Errorf("invalid color %q, colors need to look like #ff8800", hex)
+			return
+		}
+		palette = append(palette, c)
+	}
+	return
+}
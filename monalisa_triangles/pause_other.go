@@ -0,0 +1,8 @@
+//go:build windows || js
+
+package main
+
+import "os"
+
+// there is no SIGUSR1 on Windows or in the browser so the run can't be paused
+func notifyPause(c chan os.Signal) {}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Phases are how long a run has spent in each phase of breeding, to see where
+// a configuration spends its time without a profiler
+type Phases struct {
+	// Selection is making the pool and picking the parents from it
+	Selection time.Duration
+	// Crossover is breeding the children from their parents, or copying them
+	Crossover time.Duration
+	// Mutation is mutating the children
+	Mutation time.Duration
+	// Render is drawing the children, including the small pictures drawn to
+	// reject them early
+	Render time.Duration
+	// Diff is comparing the children with the target, and looking them up in
+	// the fitness cache
+	Diff time.Duration
+}
+
+// phase is the name of a phase and the time spent in it
+type phase struct {
+	name string
+	d    *time.Duration
+}
+
+// the phases in the order they happen
+func (ph *Phases) named() []phase {
+	return []phase{
+		{"selection", &ph.Selection},
+		{"crossover", &ph.Crossover},
+		{"mutation", &ph.Mutation},
+		{"render", &ph.Render},
+		{"diff", &ph.Diff},
+	}
+}
+
+// Total is the time spent in all the phases
+func (ph Phases) Total() (total time.Duration) {
+	for _, p := range ph.named() {
+		total += *p.d
+	}
+	return
+}
+
+// String is the time of each phase and its share of the total, like
+// selection 40ms (1%), crossover 70ms (1%), ...
+func (ph Phases) String() string {
+	total := ph.Total()
+	var parts []string
+	for _, p := range ph.named() {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(*p.d) / float64(total)
+		}
+		parts = append(parts, fmt.Sprintf("%s %s (%.0f%%)", p.name, p.d.Round(time.Millisecond), share))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// MarshalJSON writes the time of each phase as a duration like 1.5s, as the
+// wall time of a summary is
+func (ph Phases) MarshalJSON() ([]byte, error) {
+	times := make(map[string]string)
+	for _, p := range ph.named() {
+		times[p.name] = p.d.Round(time.Millisecond).String()
+	}
+	return json.Marshal(times)
+}
+
+// stopwatch adds the time since its last lap to a phase at each lap
+type stopwatch struct {
+	last time.Time
+}
+
+// a stopwatch started now
+func startStopwatch() stopwatch {
+	return stopwatch{last: time.Now()}
+}
+
+// add the time since the last lap to the phase
+func (s *stopwatch) lap(phase *time.Duration) {
+	now := time.Now()
+	*phase += now.Sub(s.last)
+	s.last = now
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sync"
+
+	"github.com/sausheong/ga/imgdiff"
+	"github.com/sausheong/ga/remote"
+)
+
+// evaluator, if it's set, works out the fitness of each picture on another
+// machine instead of comparing it with the target here
+var evaluator *remoteEvaluator
+
+// remoteEvaluator sends each picture to an evaluator over gRPC, as the bytes
+// of its pixels, 4 to a pixel, a row after another. The evaluator has the
+// target already and answers with the fitness of the picture, a number of at
+// least 0 that is smaller the closer the picture is to the target
+type remoteEvaluator struct {
+	client *remote.Client
+	mu     sync.Mutex
+	// err is the first error, after which the pictures are compared here
+	// until the run stops
+	err error
+}
+
+// the fitness of the picture a, from the evaluator
+func (r *remoteEvaluator) diff(a, b *image.RGBA) int64 {
+	if r.failed() == nil {
+		pix := a.Pix
+		if !tight(a) {
+			// a view of a bigger picture is sent without the gaps
+			row := a.Rect.Dx() * 4
+			pix = make([]byte, 0, row*a.Rect.Dy())
+			for y := 0; y < a.Rect.Dy(); y++ {
+				pix = append(pix, a.Pix[y*a.Stride:y*a.Stride+row]...)
+			}
+		}
+		fitnesses, err := r.client.EvaluateBatch([]remote.Genome{{Data: pix}})
+		if err == nil {
+			f := fitnesses[0]
+			// a NaN fails both
+			if f >= 0 && f < 1<<62 {
+				return int64(math.Round(f))
+			}
+			err = fmt.Errorf("evaluator answered a fitness of %v", f)
+		}
+		r.fail(err)
+	}
+	return int64(math.Sqrt(imgdiff.WeightedSumSquaresImages(a, b, ChannelWeights)))
+}
+
+// the first error of the evaluator, or nil if there's been none
+func (r *remoteEvaluator) failed() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// remember the error, unless there's been one already
+func (r *remoteEvaluator) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
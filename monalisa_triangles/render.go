@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/sausheong/ga/blend"
+	"github.com/sausheong/ga/imgdiff"
+)
+
+// Renderer draws the pictures of organisms, which are w by h pixels filled
+// with the background and then each of the triangles in turn
+type Renderer interface {
+	Draw(w, h int, background color.RGBA, triangles []Triangle) *image.RGBA
+}
+
+// differ is a renderer that also compares pictures with the target itself,
+// like the GPU, which weighs every channel the same
+type differ interface {
+	diff(a, b *image.RGBA) int64
+}
+
+// Renderers are the renderers that can be picked by name. The GPU is only
+// one of them if the demo was built with the gpu build tag
+var Renderers = map[string]Renderer{
+	"draw2d":   draw2dRenderer{},
+	"scanline": scanlineRenderer{},
+	"null":     nullRenderer{},
+}
+
+// Render is the renderer the organisms are drawn with, draw2d unless the GPU
+// was started
+var Render Renderer = draw2dRenderer{}
+
+// set the renderer by its name
+func setRenderer(name string) error {
+	r, ok := Renderers[name]
+	if !ok {
+		return fmt.Errorf("no renderer called %q, only %s", name, strings.Join(rendererNames(), ", "))
+	}
+	Render = r
+	return nil
+}
+
+// the names of the renderers in order
+func rendererNames() []string {
+	var names []string
+	for name := range Renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// draw2dRenderer draws anti-aliased triangles with draw2d
+type draw2dRenderer struct{}
+
+// Draw draws the triangles with draw2d
+func (draw2dRenderer) Draw(w, h int, background color.RGBA, triangles []Triangle) *image.RGBA {
+	dest := image.NewRGBA(image.Rect(0, 0, w, h))
+	fill(dest, background)
+	gc := draw2dimg.NewGraphicContext(dest)
+	// the triangles that aren't drawn normally are drawn onto a layer first,
+	// which is only made if there are any
+	var layer *blend.Layer
+	var lc *draw2dimg.GraphicContext
+
+	for _, triangle := range triangles {
+		if triangle.Blend == blend.Normal || triangle.Color == nil {
+			gc.SetFillColor(triangle.Color)
+			gc.SetStrokeColor(triangle.Color)
+			triangle.paint(gc)
+			continue
+		}
+		if layer == nil {
+			layer = blend.NewLayer(dest.Rect)
+			lc = draw2dimg.NewGraphicContext(layer.Mask)
+			lc.SetFillColor(color.White)
+			lc.SetStrokeColor(color.White)
+		}
+		triangle.paint(lc)
+		layer.Blend(dest, triangle.bounds(), color.RGBAModel.Convert(triangle.Color).(color.RGBA), triangle.Blend)
+	}
+
+	return dest
+}
+
+// trace the outline of the triangle, ready to be filled
+func (t Triangle) path(gc *draw2dimg.GraphicContext) {
+	gc.MoveTo(float64(t.P1.X), float64(t.P1.Y))
+	gc.LineTo(float64(t.P2.X), float64(t.P2.Y))
+	gc.LineTo(float64(t.P3.X), float64(t.P3.Y))
+	gc.Close()
+}
+
+// the rectangle around the triangle and its outline, with a pixel more on
+// each side for their anti-aliased edges
+func (t Triangle) bounds() image.Rectangle {
+	pad := 1 + int(math.Ceil(t.Width/2))
+	return image.Rect(
+		min(t.P1.X, t.P2.X, t.P3.X)-pad, min(t.P1.Y, t.P2.Y, t.P3.Y)-pad,
+		max(t.P1.X, t.P2.X, t.P3.X)+pad+1, max(t.P1.Y, t.P2.Y, t.P3.Y)+pad+1)
+}
+
+// scanlineRenderer fills the triangles a row at a time, each pixel whose
+// center is inside a triangle taking its color over what's under it, and
+// outlines the same way, each pixel whose center is within half the width of
+// an edge. The edges aren't anti-aliased, as they aren't on the GPU, but it's
+// much faster than draw2d
+type scanlineRenderer struct{}
+
+// Draw draws the triangles a row at a time
+func (scanlineRenderer) Draw(w, h int, background color.RGBA, triangles []Triangle) *image.RGBA {
+	dest := image.NewRGBA(image.Rect(0, 0, w, h))
+	fill(dest, background)
+	for _, t := range triangles {
+		if t.Color == nil {
+			continue
+		}
+		if t.Style != Stroke {
+			scanFill(dest, t)
+		}
+		if t.Style != Fill {
+			strokeTriangle(dest, 1, t)
+		}
+	}
+	return dest
+}
+
+// fill the triangle a row at a time
+func scanFill(dest *image.RGBA, t Triangle) {
+	w, h := dest.Rect.Dx(), dest.Rect.Dy()
+	// the colors are premultiplied by their alpha, so a color is drawn over
+	// another as image/draw does
+	sr, sg, sb, sa := t.Color.RGBA()
+	if sa == 0 {
+		return
+	}
+	c := color.RGBA{uint8(sr >> 8), uint8(sg >> 8), uint8(sb >> 8), uint8(sa >> 8)}
+	a := (0xffff - sa) * 0x101
+	top := max(min(t.P1.Y, t.P2.Y, t.P3.Y), 0)
+	bottom := min(max(t.P1.Y, t.P2.Y, t.P3.Y), h)
+	for y := top; y < bottom; y++ {
+		left, right, ok := span(t, float64(y)+0.5)
+		if !ok {
+			continue
+		}
+		// the pixels whose centers are from left up to right
+		x0 := max(int(math.Ceil(left-0.5)), 0)
+		x1 := min(int(math.Ceil(right-0.5)), w)
+		for i := dest.PixOffset(x0, y); x0 < x1; x0, i = x0+1, i+4 {
+			p := dest.Pix[i : i+4 : i+4]
+			if t.Blend != blend.Normal {
+				blend.Pixel(p, c, 0xffff, t.Blend)
+				continue
+			}
+			p[0] = uint8((uint32(p[0])*a/0xffff + sr) >> 8)
+			p[1] = uint8((uint32(p[1])*a/0xffff + sg) >> 8)
+			p[2] = uint8((uint32(p[2])*a/0xffff + sb) >> 8)
+			p[3] = uint8((uint32(p[3])*a/0xffff + sa) >> 8)
+		}
+	}
+}
+
+// where the row at y crosses the edges of the triangle, from left to right,
+// or false if it doesn't. Each edge covers the rows from its top up to its
+// bottom, so a corner between 2 edges is only counted once
+func span(t Triangle, y float64) (left, right float64, ok bool) {
+	left, right = math.Inf(1), math.Inf(-1)
+	for _, e := range [3][2]Point{{t.P1, t.P2}, {t.P2, t.P3}, {t.P3, t.P1}} {
+		p, q := e[0], e[1]
+		if p.Y == q.Y {
+			continue
+		}
+		if p.Y > q.Y {
+			p, q = q, p
+		}
+		if y < float64(p.Y) || y >= float64(q.Y) {
+			continue
+		}
+		x := float64(p.X) + (y-float64(p.Y))*float64(q.X-p.X)/float64(q.Y-p.Y)
+		left, right = math.Min(left, x), math.Max(right, x)
+	}
+	return left, right, left < right
+}
+
+// nullRenderer draws only the background, to time everything but the drawing
+type nullRenderer struct{}
+
+// Draw fills a picture with the background
+func (nullRenderer) Draw(w, h int, background color.RGBA, triangles []Triangle) *image.RGBA {
+	dest := image.NewRGBA(image.Rect(0, 0, w, h))
+	fill(dest, background)
+	return dest
+}
+
+// time each renderer drawing the same n random organisms, and compare their
+// pictures with those of draw2d, printing a line for each
+func benchmarkRenderers(target *image.RGBA, p Params, n int) {
+	w, h := target.Rect.Dx(), target.Rect.Dy()
+	organisms := make([]Organism, n)
+	for i := range organisms {
+		for j := 0; j < p.NumTriangles; j++ {
+			lo, hi := p.triangleSizes(j, 0)
+			t := createTriangle(w, h, p.Palette, lo, hi)
+			t.Blend = p.blendMode()
+			t.Style, t.Width = p.newStyle()
+			organisms[i].Triangles = append(organisms[i].Triangles, t)
+		}
+	}
+	reference := make([]*image.RGBA, n)
+	for i, o := range organisms {
+		reference[i] = draw2dRenderer{}.Draw(w, h, o.Background, o.Triangles)
+	}
+	fmt.Printf("Drawing %d organisms of %d triangles, %dx%d pixels\n", n, p.NumTriangles, w, h)
+	for _, name := range rendererNames() {
+		r := Renderers[name]
+		pictures := make([]*image.RGBA, n)
+		start := time.Now()
+		for i, o := range organisms {
+			pictures[i] = r.Draw(w, h, o.Background, o.Triangles)
+		}
+		elapsed := time.Since(start)
+		// how far each picture is from that of draw2d, as the fitness is with
+		// even weights. It's compared directly rather than with diff, which
+		// would pack each reference as if it were the target
+		var off int64
+		for i := range pictures {
+			off += int64(math.Sqrt(float64(imgdiff.SumSquares(pictures[i].Pix, reference[i].Pix))))
+		}
+		fmt.Printf("%-8s | %8s a picture | %6.0f pictures a second | %d off draw2d on average\n",
+			name, (elapsed / time.Duration(n)).Round(time.Microsecond), float64(n)/elapsed.Seconds(), off/int64(n))
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"image"
+	"sync"
+	"time"
+)
+
+// Run is an evolution of a population of organisms towards a target image,
+// kept apart from any I/O so it can be driven from the terminal, the job API
+// or the browser.
+//
+// A run is driven by one goroutine, which calls Step, and may read and change
+// its fields between steps. Inject and Retarget can be called from any
+// goroutine, and wait for the step going on to finish. Best, Stats, Pause,
+// Resume and Paused can also be called from any goroutine, like an HTTP
+// handler, while a step is going on, and don't wait for it
+type Run struct {
+	Target     *image.RGBA
+	Params     Params
+	Population []Organism
+	Generation int
+	PoolSize   int
+	// Evaluations is the number of times the fitness has been worked out
+	Evaluations int
+	// Lineage, if it's set, records the ancestors of the population
+	Lineage *Lineage
+	// Stalled is whether the run stopped as it was improving too slowly
+	Stalled bool
+	// Cache, if it's set, is the fitness of the genomes seen so far, and the
+	// organisms whose fitness came from it don't have their pictures drawn
+	// until they're needed
+	Cache *FitnessCache
+	// Phases are how long the run has spent in each phase of breeding
+	Phases Phases
+	// samples are the best fitness over the last of the run, to work out how
+	// fast it's improving
+	samples []sample
+
+	// stepping is held for each step, and by anything that changes the
+	// population from another goroutine
+	stepping sync.Mutex
+	// mu guards what the other goroutines read, the copies of the fields in
+	// stats and the best organism, and whether the run is paused
+	mu      sync.Mutex
+	stats   Stats
+	best    Organism
+	paused  bool
+	resumed *sync.Cond
+}
+
+// Stats are the numbers of a run as of its last step
+type Stats struct {
+	Generation  int   `json:"generation"`
+	Evaluations int   `json:"evaluations"`
+	PoolSize    int   `json:"pool_size"`
+	Fitness     int64 `json:"fitness"`
+	Stalled     bool  `json:"stalled"`
+	Paused      bool  `json:"paused"`
+	// Phases are how long the run has spent in each phase of breeding
+	Phases Phases `json:"phases"`
+}
+
+// sample is the best fitness of a run at a point in it
+type sample struct {
+	at          time.Time
+	evaluations int
+	fitness     int64
+}
+
+// Display shows a run as it evolves
+type Display interface {
+	// Target shows the image the run is evolving towards
+	Target(img *image.RGBA)
+	// Show shows the best organism of a generation
+	Show(generation int, fitness int64, poolSize int, best *image.RGBA)
+}
+
+// start a run with a random population
+func newRun(target *image.RGBA, p Params) *Run {
+	r := &Run{
+		Target:      target,
+		Params:      p,
+		Population:  createPopulation(target, p),
+		Evaluations: p.PopSize,
+	}
+	r.publish()
+	return r
+}
+
+// Step moves the run on by a generation, returning the best organism of the
+// current population and whether it is good enough to stop. While the run is
+// paused it waits until it's resumed
+func (r *Run) Step() (best Organism, done bool) {
+	r.waitWhilePaused()
+	r.stepping.Lock()
+	defer r.stepping.Unlock()
+	defer r.publish()
+	r.Generation++
+	best = fittest(r.Population)
+	best.redraw(r.Target.Rect.Dx(), r.Target.Rect.Dy())
+	if best.Fitness < r.Params.FitnessLimit {
+		done = true
+		return
+	}
+	if r.stalled() {
+		r.Stalled, done = true, true
+		return
+	}
+	watch := startStopwatch()
+	pool := createPool(r.Population, r.Target, r.Params)
+	watch.lap(&r.Phases.Selection)
+	r.PoolSize = len(pool)
+	var evaluations int
+	r.Population, evaluations = naturalSelection(pool, r.Population, r.Target, r.Params, r.Generation, r.Cache, &r.Phases)
+	r.Evaluations += evaluations
+	if r.Lineage != nil {
+		r.Lineage.Add(r.Population, r.Generation)
+	}
+	return
+}
+
+// copy what the other goroutines read from the run, once it has changed
+func (r *Run) publish() {
+	best := fittest(r.Population)
+	if r.Target != nil {
+		best.redraw(r.Target.Rect.Dx(), r.Target.Rect.Dy())
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.best = best
+	r.stats = Stats{
+		Generation:  r.Generation,
+		Evaluations: r.Evaluations,
+		PoolSize:    r.PoolSize,
+		Fitness:     best.Fitness,
+		Stalled:     r.Stalled,
+		Phases:      r.Phases,
+	}
+}
+
+// Best is the organism closest to the target as of the last step, which
+// mustn't be changed, as it's still part of the population
+func (r *Run) Best() Organism {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.best
+}
+
+// Stats are the numbers of the run as of the last step
+func (r *Run) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	stats.Paused = r.paused
+	return stats
+}
+
+// Pause pauses the run once the step going on, if any, is done, so the next
+// step waits until it's resumed
+func (r *Run) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paused = true
+}
+
+// Resume lets a paused run go on
+func (r *Run) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paused = false
+	if r.resumed != nil {
+		r.resumed.Broadcast()
+	}
+}
+
+// Paused is whether the run is paused
+func (r *Run) Paused() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.paused
+}
+
+// wait until the run isn't paused
+func (r *Run) waitWhilePaused() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.paused {
+		if r.resumed == nil {
+			r.resumed = sync.NewCond(&r.mu)
+		}
+		r.resumed.Wait()
+	}
+}
+
+// the organism closest to the target
+func fittest(population []Organism) Organism {
+	if len(population) == 0 {
+		return Organism{}
+	}
+	best := population[0]
+	for _, o := range population {
+		if o.Fitness < best.Fitness {
+			best = o
+		}
+	}
+	return best
+}
+
+// Inject puts the organism into the population in place of the one furthest
+// from the target, so it takes part in the next generation like any other
+func (r *Run) Inject(o Organism) {
+	r.stepping.Lock()
+	defer r.stepping.Unlock()
+	defer r.publish()
+	worst := 0
+	for i := range r.Population {
+		if r.Population[i].Fitness > r.Population[worst].Fitness {
+			worst = i
+		}
+	}
+	r.Population[worst] = o
+	r.Evaluations++
+	if r.Lineage != nil {
+		r.Lineage.Add(r.Population, r.Generation)
+	}
+}
+
+// Retarget changes the target of the run, working out the fitness of the
+// population again against the new target
+func (r *Run) Retarget(target *image.RGBA) {
+	r.stepping.Lock()
+	defer r.stepping.Unlock()
+	defer r.publish()
+	r.Target = target
+	for i := 0; i < len(r.Population); i++ {
+		r.Population[i].redraw(target.Rect.Dx(), target.Rect.Dy())
+		r.Population[i].calcFitness(target)
+	}
+	if r.Cache != nil {
+		r.Cache.retarget(target)
+	}
+	r.Evaluations += len(r.Population)
+	// the fitness against the old target says nothing about the new one
+	r.samples = nil
+}
+
+// stalled records the best fitness of the population, and reports whether
+// it has improved more slowly than the parameters allow over the last
+// RateWindow minutes or thousands of evaluations
+func (r *Run) stalled() bool {
+	perMinute, per1000 := r.Params.MinImprovementPerMinute, r.Params.MinImprovementPer1000
+	if perMinute <= 0 && per1000 <= 0 {
+		return false
+	}
+	now := sample{time.Now(), r.Evaluations, lowestFitness(r.Population)}
+	r.samples = append(r.samples, now)
+	minutes := time.Duration(RateWindow) * time.Minute
+	evaluations := RateWindow * 1000
+	// the window of each rate is from the last sample at least that far back
+	before := func(s sample) (minute, thousand bool) {
+		return now.at.Sub(s.at) >= minutes, now.evaluations-s.evaluations >= evaluations
+	}
+	// forget the samples before the last that's far enough back for both
+	for len(r.samples) > 1 {
+		minute, thousand := before(r.samples[1])
+		if (perMinute > 0 && !minute) || (per1000 > 0 && !thousand) {
+			break
+		}
+		r.samples = r.samples[1:]
+	}
+	for i := len(r.samples) - 1; i >= 0; i-- {
+		s := r.samples[i]
+		minute, thousand := before(s)
+		if perMinute > 0 && minute {
+			if float64(s.fitness-now.fitness)/now.at.Sub(s.at).Minutes() < perMinute {
+				return true
+			}
+			perMinute = 0
+		}
+		if per1000 > 0 && thousand {
+			if float64(s.fitness-now.fitness)*1000/float64(now.evaluations-s.evaluations) < per1000 {
+				return true
+			}
+			per1000 = 0
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/sausheong/ga/blend"
+	"github.com/sausheong/ga/rng"
+)
+
+// Style is whether a triangle is filled, only outlined, or both. Outlines
+// alone make pictures that look sketched rather than painted
+type Style uint8
+
+const (
+	// Fill fills the triangle, as it always was
+	Fill Style = iota
+	// Stroke only draws the outline of the triangle
+	Stroke
+	// FillStroke fills the triangle and draws its outline over it, in the
+	// same color
+	FillStroke
+)
+
+var styleNames = [...]string{"fill", "stroke", "both"}
+
+// String is the name of the style
+func (s Style) String() string {
+	if int(s) < len(styleNames) {
+		return styleNames[s]
+	}
+	return fmt.Sprintf("Style(%d)", s)
+}
+
+// Set sets the style by its name, so it can be a flag
+func (s *Style) Set(name string) error {
+	for i, n := range styleNames {
+		if strings.ToLower(strings.TrimSpace(name)) == n {
+			*s = Style(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("no style called %q, only %s", name, strings.Join(styleNames[:], ", "))
+}
+
+// MarshalText writes the style as its name, as in the parameters of a job
+func (s Style) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads the style from its name
+func (s *Style) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
+}
+
+// TriangleStyle is the style of new triangles, unless each triangle evolves a
+// style of its own
+var TriangleStyle = Fill
+
+// EvolveStyle is whether each triangle has a style of its own, picked at
+// random when the triangle is made and evolving with it
+var EvolveStyle = false
+
+// StrokeWidth is the widest the outline of a triangle is, in pixels. Each
+// outline is from 1 pixel up to it
+var StrokeWidth = 3.0
+
+// the style and stroke width of a new triangle
+func (p Params) newStyle() (Style, float64) {
+	s := p.Style
+	if p.EvolveStyle {
+		s = Style(rng.Intn(len(styleNames)))
+	}
+	if s == Fill {
+		return s, 0
+	}
+	return s, 1 + rng.Float64()*(p.StrokeWidth-1)
+}
+
+// widen or narrow the outline of the triangle by up to half a pixel
+func (t *Triangle) restroke(p Params) {
+	t.Width = max(1, min(p.StrokeWidth, t.Width+rng.Float64()-0.5))
+}
+
+// fill the triangle, outline it or both, as its style says, in the colors
+// already set
+func (t Triangle) paint(gc *draw2dimg.GraphicContext) {
+	t.path(gc)
+	switch t.Style {
+	case Stroke:
+		gc.SetLineWidth(t.Width)
+		gc.Stroke()
+	case FillStroke:
+		gc.SetLineWidth(t.Width)
+		gc.FillStroke()
+	default:
+		gc.Fill()
+	}
+}
+
+// call f with the pixels of the outline of the triangle on a picture w by h,
+// those whose centers are within half pixels of an edge, a row at a time from
+// x0 up to x1. The triangle is scale times bigger than the picture
+func outline(t Triangle, scale, half float64, w, h int, f func(y, x0, x1 int)) {
+	var xs, ys [3]float64
+	for i, p := range []Point{t.P1, t.P2, t.P3} {
+		xs[i], ys[i] = float64(p.X)/scale, float64(p.Y)/scale
+	}
+	var edges [3]edge
+	for i := range edges {
+		j := (i + 1) % 3
+		edges[i] = newEdge(xs[i], ys[i], xs[j], ys[j], half)
+	}
+	minY := max(0, int(math.Floor(min(ys[0], ys[1], ys[2])-half)))
+	maxY := min(h-1, int(math.Ceil(max(ys[0], ys[1], ys[2])+half)))
+	for y := minY; y <= maxY; y++ {
+		// the pixels of each edge on the row, which can overlap near the
+		// corners, where they're joined so no pixel is drawn twice
+		var spans [3][2]int
+		n := 0
+		for i := range edges {
+			left, right, ok := edges[i].span(float64(y) + 0.5)
+			if !ok {
+				continue
+			}
+			x0, x1 := max(int(math.Ceil(left-0.5)), 0), min(int(math.Floor(right-0.5))+1, w)
+			if x0 >= x1 {
+				continue
+			}
+			// kept in order of where they start
+			k := n
+			for ; k > 0 && spans[k-1][0] > x0; k-- {
+				spans[k] = spans[k-1]
+			}
+			spans[k] = [2]int{x0, x1}
+			n++
+		}
+		for i := 0; i < n; i++ {
+			x0, x1 := spans[i][0], spans[i][1]
+			for i+1 < n && spans[i+1][0] <= x1 {
+				x1 = max(x1, spans[i+1][1])
+				i++
+			}
+			f(y, x0, x1)
+		}
+	}
+}
+
+// edge is an edge of a triangle widened by half on each side and rounded at
+// its ends, so a rectangle along it with a circle at each end
+type edge struct {
+	ends        [2][2]float64
+	corners     [4][2]float64
+	half        float64
+	top, bottom float64
+}
+
+// the edge from a to b widened by half
+func newEdge(ax, ay, bx, by, half float64) edge {
+	e := edge{
+		ends:   [2][2]float64{{ax, ay}, {bx, by}},
+		half:   half,
+		top:    min(ay, by) - half,
+		bottom: max(ay, by) + half,
+	}
+	if l := math.Hypot(bx-ax, by-ay); l > 0 {
+		nx, ny := -(by-ay)/l*half, (bx-ax)/l*half
+		e.corners = [4][2]float64{{ax + nx, ay + ny}, {bx + nx, by + ny}, {bx - nx, by - ny}, {ax - nx, ay - ny}}
+	}
+	return e
+}
+
+// where the row at y crosses the edge, or false if it doesn't. The rectangle
+// and circles overlap, so together they cross it in one span
+func (e *edge) span(y float64) (left, right float64, ok bool) {
+	if y < e.top || y > e.bottom {
+		return 0, 0, false
+	}
+	left, right = math.Inf(1), math.Inf(-1)
+	for _, end := range e.ends {
+		if d := e.half*e.half - (y-end[1])*(y-end[1]); d >= 0 {
+			d = math.Sqrt(d)
+			left, right = min(left, end[0]-d), max(right, end[0]+d)
+		}
+	}
+	for i, p := range e.corners {
+		q := e.corners[(i+1)%4]
+		if p[1] == q[1] || y < min(p[1], q[1]) || y > max(p[1], q[1]) {
+			continue
+		}
+		x := p[0] + (y-p[1])*(q[0]-p[0])/(q[1]-p[1])
+		left, right = min(left, x), max(right, x)
+	}
+	return left, right, left <= right
+}
+
+// outline the triangle on the picture, which is scale times smaller than the
+// triangle, each pixel either on the outline or not. An outline is at least a
+// pixel wide, so a thin one doesn't vanish from a small picture
+func strokeTriangle(img *image.RGBA, scale int, t Triangle) {
+	c := color.RGBAModel.Convert(t.Color).(color.RGBA)
+	half := max(t.Width/2/float64(scale), 0.5)
+	outline(t, float64(scale), half, img.Rect.Dx(), img.Rect.Dy(), func(y, x0, x1 int) {
+		for i := img.PixOffset(x0, y); x0 < x1; x0, i = x0+1, i+4 {
+			blend.Pixel(img.Pix[i:i+4], c, 0xffff, t.Blend)
+		}
+	})
+}
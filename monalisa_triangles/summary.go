@@ -0,0 +1,70 @@
+//go:build !js
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Summary is what a run did, written at the end of it so the images it left
+// behind can be told apart and the run repeated
+type Summary struct {
+	Target      string    `json:"target"`
+	Seed        int64     `json:"seed"`
+	Params      Params    `json:"params"`
+	Started     time.Time `json:"started"`
+	WallTime    string    `json:"wall_time"`
+	Generations int       `json:"generations"`
+	// Evaluations is the number of times the fitness was worked out in the
+	// run, counting those before it was resumed from a checkpoint
+	Evaluations int `json:"evaluations"`
+	// Phases are how long the run spent in each phase of breeding
+	Phases  Phases `json:"phases"`
+	Fitness int64  `json:"fitness"`
+	// Curve is the best fitness every 10 generations
+	Curve []int64 `json:"curve"`
+	// Artifacts are the files the run wrote
+	Artifacts []string `json:"artifacts"`
+}
+
+// write the summary as Markdown if the file ends with .md, or as JSON
+func writeSummary(filePath string, s Summary) error {
+	var data []byte
+	if strings.ToLower(filepath.Ext(filePath)) == ".md" {
+		data = []byte(s.markdown())
+	} else {
+		var err error
+		data, err = json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	return Output.Write(filePath, data)
+}
+
+// the summary as a Markdown document
+func (s Summary) markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Run of %s\n\n", s.Target)
+	fmt.Fprintf(&sb, "| | |\n|---|---|\n")
+	fmt.Fprintf(&sb, "| Seed | %d |\n", s.Seed)
+	fmt.Fprintf(&sb, "| Started | %s |\n", s.Started.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "| Wall time | %s |\n", s.WallTime)
+	fmt.Fprintf(&sb, "| Generations | %d |\n", s.Generations)
+	fmt.Fprintf(&sb, "| Evaluations | %d |\n", s.Evaluations)
+	fmt.Fprintf(&sb, "| Phases | %s |\n", s.Phases)
+	fmt.Fprintf(&sb, "| Fitness | %d |\n", s.Fitness)
+	fmt.Fprintf(&sb, "\n## Parameters\n\n")
+	params, _ := json.MarshalIndent(s.Params, "", "  ")
+	fmt.Fprintf(&sb, "```json\n%s\n```\n", params)
+	fmt.Fprintf(&sb, "\n## Artifacts\n\n")
+	for _, a := range s.Artifacts {
+		fmt.Fprintf(&sb, "- %s\n", a)
+	}
+	return sb.String()
+}
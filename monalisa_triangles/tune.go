@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// the values of the parameters tried by a grid search
+var (
+	tuneMutationRates = []float64{0.005, 0.01, 0.021, 0.05}
+	tunePopSizes      = []int{50, 100, 200}
+	tunePoolSizes     = []int{10, 20, 40}
+)
+
+// Trial is a run with one set of parameters while tuning, and how it went
+type Trial struct {
+	Params Params
+	// Curve is the best fitness every tenth of the run
+	Curve   []int64
+	Fitness int64
+	Elapsed time.Duration
+}
+
+// the parameters to try, either every combination of the grid values or a
+// number of random ones
+func tuneParams(base Params, search string, samples int) (trials []Params, err error) {
+	switch search {
+	case "grid":
+		for _, m := range tuneMutationRates {
+			for _, pop := range tunePopSizes {
+				for _, pool := range tunePoolSizes {
+					// the pool is taken from the population
+					if pool >= pop {
+						continue
+					}
+					p := base
+					p.MutationRate, p.PopSize, p.PoolSize = m, pop, pool
+					trials = append(trials, p)
+				}
+			}
+		}
+	case "random":
+		for i := 0; i < samples; i++ {
+			p := base
+			// mutation rates are spread evenly on a log scale from 0.001 to 0.1
+			p.MutationRate = math.Pow(10, -3+2*rng.Float64())
+			p.PopSize = 20 + rng.Intn(281)
+			p.PoolSize = 5 + rng.Intn(p.PopSize/2-4)
+			trials = append(trials, p)
+		}
+	default:
+		err = fmt.Errorf("unknown search %q, use grid or random", search)
+	}
+	return
+}
+
+// run every set of parameters for the same number of generations, from the
+// same random seed so they are compared fairly, and report the best
+func tune(target *image.RGBA, params []Params, generations int) {
+	seed := time.Now().UTC().UnixNano()
+	step := max(1, generations/10)
+	trials := make([]Trial, len(params))
+	for i, p := range params {
+		rng.Seed(seed)
+		start := time.Now()
+		run := newRun(target, p)
+		trial := Trial{Params: p}
+		for g := 1; g <= generations; g++ {
+			run.Step()
+			if g%step == 0 {
+				trial.Curve = append(trial.Curve, lowestFitness(run.Population))
+			}
+		}
+		trial.Fitness = lowestFitness(run.Population)
+		trial.Elapsed = time.Since(start)
+		trials[i] = trial
+		fmt.Printf("%d/%d | mutation rate: %.4f | pop size: %d | pool size: %d | fitness: %d | time: %s\n",
+			i+1, len(params), p.MutationRate, p.PopSize, p.PoolSize, trial.Fitness, trial.Elapsed)
+	}
+
+	sort.SliceStable(trials, func(i, j int) bool {
+		return trials[i].Fitness < trials[j].Fitness
+	})
+	fmt.Printf("\nBest after %d generations:\n", generations)
+	for _, t := range trials[:min(5, len(trials))] {
+		fmt.Printf("  -mutation-rate %.4f -pop-size %d -pool-size %d | fitness: %d | time: %s\n",
+			t.Params.MutationRate, t.Params.PopSize, t.Params.PoolSize, t.Fitness, t.Elapsed)
+	}
+	fmt.Printf("\nConvergence of the best:\n%s", curve(trials[0].Curve, step))
+}
+
+// the fitness of the organism closest to the target
+func lowestFitness(population []Organism) int64 {
+	lowest := population[0].Fitness
+	for _, o := range population {
+		lowest = min(lowest, o.Fitness)
+	}
+	return lowest
+}
+
+// the convergence curve as a bar for every step generations, the longer the
+// bar the higher the fitness
+func curve(fitnesses []int64, step int) string {
+	var sb strings.Builder
+	high, low := fitnesses[0], fitnesses[len(fitnesses)-1]
+	for i, f := range fitnesses {
+		bar := 50
+		if high > low {
+			bar = 5 + int(45*(f-low)/(high-low))
+		}
+		fmt.Fprintf(&sb, "%6d %-50s %d\n", (i+1)*step, strings.Repeat("#", bar), f)
+	}
+	return sb.String()
+}
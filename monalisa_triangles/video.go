@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	_ "image/jpeg"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// evolve each frame in the directory in turn, starting each frame from the
+// population evolved for the previous one so the frames are coherent and
+// converge quickly, and save the best organism of each to the out directory
+func evolveVideo(dir, out string, p Params, maxGenerations int, display Display) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext == ".png" || ext == ".jpg" || ext == ".jpeg" {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no frames in %s", dir)
+	}
+	sort.Strings(files)
+
+	var run *Run
+	for i, file := range files {
+		target := load(file)
+		if run == nil {
+			display.Target(target)
+			run = newRun(target, p)
+		} else {
+			if target.Rect.Dx() != run.Target.Rect.Dx() || target.Rect.Dy() != run.Target.Rect.Dy() {
+				return fmt.Errorf("%s is not the same size as the first frame", file)
+			}
+			run.Retarget(target)
+		}
+
+		var bestOrganism Organism
+		for g := 0; g < maxGenerations; g++ {
+			var done bool
+			bestOrganism, done = run.Step()
+			if done {
+				break
+			}
+		}
+		save(filepath.Join(out, fmt.Sprintf("frame_%06d.png", i+1)), bestOrganism.DNA)
+		fmt.Printf("\nFrame %d of %d from %s\n", i+1, len(files), file)
+		display.Show(run.Generation, bestOrganism.Fitness, run.PoolSize, bestOrganism.DNA)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"syscall/js"
+	"time"
+)
+
+// runs the evolution in the browser, reading the target from the canvas with
+// the id target and drawing the best organism on the canvas with the id evolved
+func main() {
+	doc := js.Global().Get("document")
+	display := &canvasDisplay{
+		canvas: doc.Call("getElementById", "evolved"),
+		status: doc.Call("getElementById", "status"),
+	}
+	target := readCanvas(doc.Call("getElementById", "target"))
+	display.Target(target)
+
+	run := newRun(target, defaultParams())
+	for {
+		bestOrganism, done := run.Step()
+		if done || run.Generation%10 == 0 {
+			display.Show(run.Generation, bestOrganism.Fitness, run.PoolSize, bestOrganism.DNA)
+			// give the browser a chance to paint
+			time.Sleep(time.Millisecond)
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// read the pixels of a canvas into an image
+func readCanvas(canvas js.Value) *image.RGBA {
+	w, h := canvas.Get("width").Int(), canvas.Get("height").Int()
+	data := canvas.Call("getContext", "2d").Call("getImageData", 0, 0, w, h).Get("data")
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	js.CopyBytesToGo(img.Pix, data)
+	return img
+}
+
+// shows the run on a canvas in the page
+type canvasDisplay struct {
+	canvas js.Value
+	status js.Value
+}
+
+// size the canvas to the target
+func (c *canvasDisplay) Target(img *image.RGBA) {
+	c.canvas.Set("width", img.Rect.Dx())
+	c.canvas.Set("height", img.Rect.Dy())
+}
+
+// draw the best organism on the canvas
+func (c *canvasDisplay) Show(generation int, fitness int64, poolSize int, best *image.RGBA) {
+	data := js.Global().Get("Uint8ClampedArray").New(len(best.Pix))
+	js.CopyBytesToJS(data, best.Pix)
+	imageData := js.Global().Get("ImageData").New(data, best.Rect.Dx(), best.Rect.Dy())
+	c.canvas.Call("getContext", "2d").Call("putImageData", imageData, 0, 0)
+	c.status.Set("textContent", fmt.Sprintf("generation: %d | fitness: %d | pool size: %d", generation, fitness, poolSize))
+}
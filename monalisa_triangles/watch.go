@@ -0,0 +1,79 @@
+//go:build !js
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchInterval is how often the watch folder is checked for new genomes
+var WatchInterval = time.Second
+
+// watcher finds the genome JSON files dropped into a folder while a run goes
+// on, each of which is put into the population as an immigrant
+type watcher struct {
+	dir string
+	// seen are the files already put in, with when they were changed, so a
+	// file is only put in again if it's written again
+	seen map[string]time.Time
+	last time.Time
+}
+
+// watch the folder for genomes
+func newWatcher(dir string) *watcher {
+	return &watcher{dir: dir, seen: make(map[string]time.Time)}
+}
+
+// put the genomes of the files that are new or changed since the last check
+// into the run, in the order of their names. A file that can't be read is
+// tried again once it's changed, as it may have been read while it was still
+// being written
+func (w *watcher) inject(run *Run) {
+	if time.Since(w.last) < WatchInterval {
+		return
+	}
+	w.last = time.Now()
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		fmt.Println("Cannot watch folder:", err)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		name := filepath.Join(w.dir, entry.Name())
+		if changed, ok := w.seen[name]; ok && changed.Equal(info.ModTime()) {
+			continue
+		}
+		w.seen[name] = info.ModTime()
+		err = w.injectFile(run, name)
+		if err != nil {
+			fmt.Printf("Cannot inject %s: %v\n", name, err)
+		}
+	}
+}
+
+// put the genome of the file into the run
+func (w *watcher) injectFile(run *Run, name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	immigrant, err := injectShapes(run, data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Injected %s at generation %d with a fitness of %d\n", name, run.Generation, immigrant.Fitness)
+	return nil
+}
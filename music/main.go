@@ -0,0 +1,290 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each note
+var MutationRate = 0.05
+
+// PopSize is the size of the population
+var PopSize = 200
+
+// PoolSize is the max size of the pool
+var PoolSize = 30
+
+// Generations is the number of generations to evolve
+var Generations = 2000
+
+// Lowest is the lowest note that can be played, C3
+var Lowest = 48
+
+// Highest is the highest note that can be played, C6
+var Highest = 84
+
+// RestRate is the chance that a random note is a rest
+var RestRate = 0.05
+
+// Leap is the largest interval between notes, in semitones, that doesn't
+// count against a melody evolved with the music theory heuristics
+var Leap = 5
+
+// the first lines of Ode to Joy
+var odeToJoy = "E4 E4 F4 G4 G4 F4 E4 D4 C4 C4 D4 E4 E4 D4 D4 - E4 E4 F4 G4 G4 F4 E4 D4 C4 C4 D4 E4 D4 C4 C4 -"
+
+func main() {
+	target := flag.String("target", odeToJoy, "melody to evolve towards, notes like C4, F#3 or Bb5, and - for a rest, separated by spaces")
+	theory := flag.Bool("theory", false, "evolve a melody that follows the music theory heuristics instead of a target")
+	length := flag.Int("length", 32, "number of notes of a melody evolved with the music theory heuristics")
+	key := flag.String("key", "C", "major key of a melody evolved with the music theory heuristics")
+	tempo := flag.Int("tempo", 120, "tempo of the MIDI file, in beats per minute")
+	out := flag.String("out", "./melody.mid", "MIDI file to write the best melody to")
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+
+	var fitness func([]int) int
+	n := *length
+	if *theory {
+		tonic, err := parseNote(*key + "4")
+		if err != nil {
+			fmt.Println("Cannot find key:", *key)
+			return
+		}
+		fitness = func(melody []int) int { return heuristics(melody, tonic%12) }
+	} else {
+		melody, err := parseMelody(*target)
+		if err != nil || len(melody) == 0 {
+			fmt.Println("Cannot read target melody:", err)
+			return
+		}
+		for _, note := range melody {
+			if note != Rest && (note < Lowest || note > Highest) {
+				fmt.Printf("Cannot evolve melody: %s is out of range\n", noteName(note))
+				return
+			}
+		}
+		fitness = func(m []int) int { return distance(m, melody) }
+		n = len(melody)
+	}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	population := createPopulation(n, fitness)
+
+	var bestOrganism Organism
+	for generation := 1; generation <= Generations; generation++ {
+		bestOrganism = getBest(population)
+		if bestOrganism.Fitness == 0 {
+			if *theory {
+				fmt.Printf("\nFound a melody that follows every rule in generation %d\n", generation)
+			} else {
+				fmt.Printf("\nFound the melody in generation %d\n", generation)
+			}
+			break
+		}
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism, fitness)
+		if generation%100 == 0 {
+			sofar := time.Since(start)
+			fmt.Printf("Time taken so far: %s | generation: %d | fitness: %d | %s\n", sofar, generation, bestOrganism.Fitness, melodyString(bestOrganism.DNA))
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\n%s\n", melodyString(bestOrganism.DNA))
+	if err := writeMIDI(*out, bestOrganism.DNA, *tempo); err != nil {
+		fmt.Println("Cannot write MIDI file:", err)
+	}
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// how far the melody is from the target, the number of semitones each note
+// is out by, and an octave for a note that should be a rest or the other way
+// round
+func distance(melody, target []int) (d int) {
+	for i := range melody {
+		switch {
+		case melody[i] == target[i]:
+		case melody[i] == Rest || target[i] == Rest:
+			d += 12
+		case melody[i] > target[i]:
+			d += melody[i] - target[i]
+		default:
+			d += target[i] - melody[i]
+		}
+	}
+	return
+}
+
+// how badly the melody breaks some simple rules of thumb -- every note in the
+// key, no big leaps or notes played over and over, few rests, and starting
+// and ending on the tonic
+func heuristics(melody []int, tonic int) (d int) {
+	previous := Rest
+	for _, note := range melody {
+		if note == Rest {
+			d += 2
+			continue
+		}
+		if !inKey(note, tonic) {
+			d += 4
+		}
+		if previous != Rest {
+			interval := note - previous
+			if interval < 0 {
+				interval = -interval
+			}
+			if interval == 0 {
+				d++
+			}
+			if interval > Leap {
+				d += interval - Leap
+			}
+		}
+		previous = note
+	}
+	if first := melody[0]; first == Rest || first%12 != tonic {
+		d += 4
+	}
+	if last := melody[len(melody)-1]; last == Rest || last%12 != tonic {
+		d += 4
+	}
+	return
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with at most 100 copies of any organism
+	spread := top[PoolSize].Fitness - top[0].Fitness
+	for i := 0; i < len(top)-1; i++ {
+		num := (top[PoolSize].Fitness - top[i].Fitness) * 100 / spread
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, fitness func([]int) int) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best melody so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.Fitness = fitness(child.DNA)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(n int, fitness func([]int) int) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(n, fitness)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a melody, a MIDI note or a rest for every beat
+type Organism struct {
+	DNA     []int
+	Fitness int
+}
+
+// creates an organism with random notes
+func createOrganism(n int, fitness func([]int) int) (organism Organism) {
+	organism = Organism{
+		DNA:     make([]int, n),
+		Fitness: 0,
+	}
+	for i := range organism.DNA {
+		organism.DNA[i] = randomNote()
+	}
+	organism.Fitness = fitness(organism.DNA)
+	return
+}
+
+// a random note in the range, or now and then a rest
+func randomNote() int {
+	if rng.Float64() < RestRate {
+		return Rest
+	}
+	return Lowest + rng.Intn(Highest-Lowest+1)
+}
+
+// crosses over 2 Organisms
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]int, len(d1.DNA)),
+		Fitness: 0,
+	}
+	mid := rng.Intn(len(d1.DNA))
+	for i := 0; i < len(d1.DNA); i++ {
+		if i > mid {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism, mostly moving notes up or down a little, but sometimes
+// changing them to any note
+func (o *Organism) mutate() {
+	for i, note := range o.DNA {
+		if rng.Float64() >= MutationRate {
+			continue
+		}
+		if note == Rest || rng.Intn(3) == 0 {
+			o.DNA[i] = randomNote()
+			continue
+		}
+		shift := 1 + rng.Intn(2)
+		if rng.Intn(2) == 0 {
+			shift = -shift
+		}
+		o.DNA[i] = max(Lowest, min(Highest, note+shift))
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+)
+
+// TicksPerBeat is the resolution of the MIDI file
+const TicksPerBeat = 480
+
+// write the melody as a single track standard MIDI file, with every note a
+// beat long, at the given tempo in beats per minute
+func writeMIDI(filePath string, melody []int, tempo int) error {
+	var track bytes.Buffer
+	// the tempo, in microseconds per beat
+	usec := 60000000 / tempo
+	track.Write([]byte{0x00, 0xFF, 0x51, 0x03, byte(usec >> 16), byte(usec >> 8), byte(usec)})
+	// a piano
+	track.Write([]byte{0x00, 0xC0, 0x00})
+	delta := 0
+	for _, note := range melody {
+		if note == Rest {
+			delta += TicksPerBeat
+			continue
+		}
+		writeVarInt(&track, delta)
+		track.Write([]byte{0x90, byte(note), 96})
+		writeVarInt(&track, TicksPerBeat)
+		track.Write([]byte{0x80, byte(note), 0})
+		delta = 0
+	}
+	writeVarInt(&track, delta)
+	track.Write([]byte{0xFF, 0x2F, 0x00})
+
+	var file bytes.Buffer
+	file.WriteString("MThd")
+	// the header is 6 bytes long, format 0 with 1 track
+	binary.Write(&file, binary.BigEndian, uint32(6))
+	binary.Write(&file, binary.BigEndian, []uint16{0, 1, TicksPerBeat})
+	file.WriteString("MTrk")
+	binary.Write(&file, binary.BigEndian, uint32(track.Len()))
+	file.Write(track.Bytes())
+	return os.WriteFile(filePath, file.Bytes(), 0644)
+}
+
+// write a number as a MIDI variable length quantity, 7 bits a byte with the
+// top bit set on all but the last byte
+func writeVarInt(buf *bytes.Buffer, n int) {
+	b := []byte{byte(n & 0x7F)}
+	for n >>= 7; n > 0; n >>= 7 {
+		b = append([]byte{byte(n&0x7F) | 0x80}, b...)
+	}
+	buf.Write(b)
+}
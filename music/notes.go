@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rest is a note that isn't played
+const Rest = -1
+
+// the semitones above C of each note name
+var semitones = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// the names of the notes in an octave
+var names = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// the steps of the major scale, in semitones above the tonic
+var majorScale = []int{0, 2, 4, 5, 7, 9, 11}
+
+// parse a note like C4, F#3 or Bb5 into its MIDI number, where C4 is 60, or
+// a - into a rest
+func parseNote(s string) (int, error) {
+	if s == "-" {
+		return Rest, nil
+	}
+	if len(s) < 2 {
+		return 0, fmt.Errorf("cannot parse note %q", s)
+	}
+	semitone, ok := semitones[strings.ToUpper(s)[0]]
+	if !ok {
+		return 0, fmt.Errorf("cannot parse note %q", s)
+	}
+	rest := s[1:]
+	switch rest[0] {
+	case '#':
+		semitone++
+		rest = rest[1:]
+	case 'b':
+		semitone--
+		rest = rest[1:]
+	}
+	octave, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse note %q", s)
+	}
+	return (octave+1)*12 + semitone, nil
+}
+
+// parse a melody of notes separated by spaces
+func parseMelody(s string) (melody []int, err error) {
+	for _, field := range strings.Fields(s) {
+		note, err := parseNote(field)
+		if err != nil {
+			return nil, err
+		}
+		melody = append(melody, note)
+	}
+	return
+}
+
+// the name of the note, or - for a rest
+func noteName(note int) string {
+	if note == Rest {
+		return "-"
+	}
+	return fmt.Sprintf("%s%d", names[note%12], note/12-1)
+}
+
+// the melody written out as note names
+func melodyString(melody []int) string {
+	notes := make([]string, len(melody))
+	for i, note := range melody {
+		notes[i] = noteName(note)
+	}
+	return strings.Join(notes, " ")
+}
+
+// whether the note is in the major key with the given tonic, which is a
+// number of semitones above C
+func inKey(note, tonic int) bool {
+	step := ((note-tonic)%12 + 12) % 12
+	for _, s := range majorScale {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
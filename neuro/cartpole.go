@@ -0,0 +1,54 @@
+package main
+
+import "math"
+
+// CartPole is the classic pole balancing simulation, a pole hinged on a cart
+// that can be pushed left or right along a track
+type CartPole struct {
+	X        float64 // position of the cart
+	XDot     float64 // velocity of the cart
+	Theta    float64 // angle of the pole from upright, in radians
+	ThetaDot float64 // angular velocity of the pole
+}
+
+// the physics of the simulation, as in Barto, Sutton and Anderson (1983)
+const (
+	gravity    = 9.8
+	cartMass   = 1.0
+	poleMass   = 0.1
+	poleLength = 0.5 // half the length of the pole
+	pushForce  = 10.0
+	timeStep   = 0.02
+	trackLimit = 2.4
+	angleLimit = 12 * math.Pi / 180
+)
+
+// Step pushes the cart right if right is true, or left otherwise, and moves
+// the simulation forward by one time step
+func (c *CartPole) Step(right bool) {
+	force := -pushForce
+	if right {
+		force = pushForce
+	}
+	totalMass := cartMass + poleMass
+	cos, sin := math.Cos(c.Theta), math.Sin(c.Theta)
+	temp := (force + poleMass*poleLength*c.ThetaDot*c.ThetaDot*sin) / totalMass
+	thetaAcc := (gravity*sin - cos*temp) / (poleLength * (4.0/3.0 - poleMass*cos*cos/totalMass))
+	xAcc := temp - poleMass*poleLength*thetaAcc*cos/totalMass
+
+	c.X += timeStep * c.XDot
+	c.XDot += timeStep * xAcc
+	c.Theta += timeStep * c.ThetaDot
+	c.ThetaDot += timeStep * thetaAcc
+}
+
+// Failed is true when the pole has fallen too far or the cart has run off
+// the end of the track
+func (c *CartPole) Failed() bool {
+	return math.Abs(c.X) > trackLimit || math.Abs(c.Theta) > angleLimit
+}
+
+// the state, scaled roughly to between -1 and 1 as inputs for the network
+func (c *CartPole) inputs() []float64 {
+	return []float64{c.X / trackLimit, c.XDot / 2, c.Theta / angleLimit, c.ThetaDot / 2}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/nn"
+	"github.com/sausheong/ga/optimize"
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each weight
+var MutationRate = 0.1
+
+// MutationSize is the standard deviation of the change to a mutated weight
+var MutationSize = 0.5
+
+// PopSize is the size of the population
+var PopSize = 100
+
+// PoolSize is the max size of the pool
+var PoolSize = 20
+
+// Generations is the most generations to evolve
+var Generations = 2000
+
+// Hidden is the number of neurons in the hidden layer
+var Hidden = 4
+
+// FitnessLimit is the error of the evolved network we are satisfied with
+var FitnessLimit = 0.01
+
+// Strategy is the way the weights are evolved, ga for the genetic algorithm,
+// or de or cmaes for the optimizers in the optimize package
+var Strategy = "ga"
+
+// WeightLimit is the largest weight, positive or negative, searched by the
+// optimizers other than the genetic algorithm
+var WeightLimit = 5.0
+
+func main() {
+	name := flag.String("task", "xor", "task to evolve the network for, xor or cartpole")
+	flag.IntVar(&Hidden, "hidden", Hidden, "number of neurons in the hidden layer")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve")
+	flag.StringVar(&Strategy, "strategy", Strategy, "ga for the genetic algorithm, de for differential evolution or cmaes for CMA-ES")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	task, ok := tasks[*name]
+	if !ok {
+		fmt.Println("Cannot find task:", *name)
+		return
+	}
+	net := nn.Network{Layers: []int{task.Inputs, Hidden, task.Outputs}}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	problem := optimize.Problem{
+		F: func(weights []float64) float64 {
+			return task.Error(net, weights)
+		},
+		Dimensions: net.NumWeights(),
+		Min:        -WeightLimit,
+		Max:        WeightLimit,
+	}
+	var optimizer optimize.Optimizer
+	switch Strategy {
+	case "ga":
+		optimizer = &geneticAlgorithm{problem: problem, population: createPopulation(problem)}
+	case "de":
+		optimizer = optimize.NewDE(problem, PopSize)
+	case "cmaes":
+		optimizer = optimize.NewCMAES(problem, 0)
+	default:
+		fmt.Println("Cannot find strategy:", Strategy)
+		return
+	}
+
+	generation := 0
+	var weights []float64
+	fitness := math.Inf(1)
+	for generation < Generations && fitness >= FitnessLimit {
+		generation++
+		weights, fitness = optimizer.Step()
+		if generation%10 == 0 {
+			sofar := time.Since(start)
+			fmt.Printf("Time taken so far: %s | generation: %d | error: %g\n", sofar, generation, fitness)
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nBest network after %d generations, with an error of %g\n", generation, fitness)
+	if task.Name == "xor" {
+		for _, row := range xorTable {
+			fmt.Printf("  %2.0f xor %2.0f = %5.2f\n", row[0], row[1], net.Forward(weights, row[:2])[0])
+		}
+	}
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// the genetic algorithm, as an optimizer like the other strategies
+type geneticAlgorithm struct {
+	problem    optimize.Problem
+	population []Organism
+}
+
+// Step evolves the next generation
+func (g *geneticAlgorithm) Step() ([]float64, float64) {
+	pool := createPool(g.population)
+	g.population = naturalSelection(pool, g.population, getBest(g.population), g.problem)
+	best := getBest(g.population)
+	return best.DNA, best.Fitness
+}
+
+// Fitnesses are the fitness of every organism in the population
+func (g *geneticAlgorithm) Fitnesses() []float64 {
+	fitnesses := make([]float64, len(g.population))
+	for i, o := range g.population {
+		fitnesses[i] = o.Fitness
+	}
+	return fitnesses
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	spread := top[PoolSize].Fitness - top[0].Fitness
+	if spread == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with up to 100 copies of the best
+	for i := 0; i < len(top)-1; i++ {
+		num := int(100 * (top[PoolSize].Fitness - top[i].Fitness) / spread)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, problem optimize.Problem) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best network so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.calcFitness(problem)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(problem optimize.Problem) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(problem)
+	}
+	return
+}
+
+// Get the best organism, the one with the lowest error
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a network, its DNA being the weights
+type Organism struct {
+	DNA     []float64
+	Fitness float64
+}
+
+// creates an organism with random weights between -1 and 1
+func createOrganism(problem optimize.Problem) (organism Organism) {
+	weights := make([]float64, problem.Dimensions)
+	for i := range weights {
+		weights[i] = rng.Float64()*2 - 1
+	}
+	organism = Organism{
+		DNA:     weights,
+		Fitness: 0,
+	}
+	organism.calcFitness(problem)
+	return
+}
+
+// calculates the fitness of the Organism, which is the error of the network
+// on the task
+func (o *Organism) calcFitness(problem optimize.Problem) {
+	o.Fitness = problem.F(o.DNA)
+}
+
+// crosses over 2 Organisms
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]float64, len(d1.DNA)),
+		Fitness: 0,
+	}
+	mid := rng.Intn(len(d1.DNA))
+	for i := 0; i < len(d1.DNA); i++ {
+		if i > mid {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism by nudging weights by a normally distributed amount
+func (o *Organism) mutate() {
+	for i := 0; i < len(o.DNA); i++ {
+		if rng.Float64() < MutationRate {
+			o.DNA[i] += rng.NormFloat64() * MutationSize
+		}
+	}
+}
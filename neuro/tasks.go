@@ -0,0 +1,63 @@
+package main
+
+import "github.com/sausheong/ga/nn"
+
+// Task is a problem for the network to solve, with the number of inputs and
+// outputs the network needs and a way to measure its error
+type Task struct {
+	Name    string
+	Inputs  int
+	Outputs int
+	// Error is how badly the network does with the weights, 0 being perfect
+	Error func(net nn.Network, weights []float64) float64
+}
+
+// the tasks the networks can be evolved for
+var tasks = map[string]Task{
+	"xor":      {"xor", 2, 1, xorError},
+	"cartpole": {"cartpole", 4, 1, cartPoleError},
+}
+
+// the truth table of XOR, with -1 for false and 1 for true
+var xorTable = [][3]float64{
+	{-1, -1, -1},
+	{-1, 1, 1},
+	{1, -1, 1},
+	{1, 1, -1},
+}
+
+// the sum of the squared errors over the XOR truth table
+func xorError(net nn.Network, weights []float64) (sum float64) {
+	for _, row := range xorTable {
+		d := net.Forward(weights, row[:2])[0] - row[2]
+		sum += d * d
+	}
+	return
+}
+
+// CartPoleSteps is the number of steps the pole needs to be balanced for
+var CartPoleSteps = 10000
+
+// the starting positions the network has to balance the pole from, the same
+// every time so the fitness isn't noisy
+var cartPoleStarts = []CartPole{
+	{Theta: 0.05},
+	{Theta: -0.15, ThetaDot: 0.5, X: 1.5},
+	{Theta: 0.1, ThetaDot: -0.5, X: -1.5, XDot: -1},
+	{Theta: 0.18, XDot: 1},
+}
+
+// the fraction of the steps the pole didn't stay up for, over all the starts
+func cartPoleError(net nn.Network, weights []float64) float64 {
+	total := 0
+	for _, start := range cartPoleStarts {
+		c := start
+		steps := 0
+		for steps < CartPoleSteps && !c.Failed() {
+			c.Step(net.Forward(weights, c.inputs())[0] > 0)
+			steps++
+		}
+		total += steps
+	}
+	return 1 - float64(total)/float64(CartPoleSteps*len(cartPoleStarts))
+}
@@ -0,0 +1,42 @@
+// Package nn is a tiny neural network, just enough to evolve the weights of
+// small networks that control the agents in the demos.
+package nn
+
+import "math"
+
+// Network is a fully connected feed-forward neural network with a fixed
+// topology, the number of neurons in each layer from the inputs to the
+// outputs. The weights, including a bias for every neuron, are kept apart
+// so they can be evolved as a list of real numbers
+type Network struct {
+	Layers []int
+}
+
+// NumWeights is the number of weights, including the biases, of the network
+func (n Network) NumWeights() (count int) {
+	for i := 1; i < len(n.Layers); i++ {
+		count += (n.Layers[i-1] + 1) * n.Layers[i]
+	}
+	return
+}
+
+// Forward feeds the inputs through the network with the given weights and
+// returns the outputs, each between -1 and 1
+func (n Network) Forward(weights, inputs []float64) []float64 {
+	activations := inputs
+	w := 0
+	for i := 1; i < len(n.Layers); i++ {
+		next := make([]float64, n.Layers[i])
+		for j := range next {
+			sum := weights[w]
+			w++
+			for _, a := range activations {
+				sum += weights[w] * a
+				w++
+			}
+			next[j] = math.Tanh(sum)
+		}
+		activations = next
+	}
+	return activations
+}
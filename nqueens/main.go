@@ -0,0 +1,255 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the chance that a board is mutated
+var MutationRate = 0.8
+
+// PopSize is the size of the population
+var PopSize = 100
+
+// PoolSize is the max size of the pool
+var PoolSize = 20
+
+// Generations is the most generations to evolve before giving up
+var Generations = 100000
+
+// N is the number of queens, and the width and height of the board
+var N = 8
+
+func main() {
+	flag.IntVar(&N, "n", N, "number of queens")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve before giving up")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	if N < 4 {
+		fmt.Println("Cannot place the queens: there are no solutions for 2 or 3 queens, so use at least 4")
+		return
+	}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	population := createPopulation()
+
+	found := false
+	generation := 0
+	var bestOrganism Organism
+	for !found && generation < Generations {
+		generation++
+		bestOrganism = getBest(population)
+		if bestOrganism.Fitness == 0 {
+			found = true
+		} else {
+			pool := createPool(population)
+			population = naturalSelection(pool, population, bestOrganism)
+			if generation%100 == 0 {
+				sofar := time.Since(start)
+				fmt.Printf("\rTime taken so far: %s | generation: %d | conflicts: %d | pool size: %d", sofar, generation, bestOrganism.Fitness, len(pool))
+			}
+		}
+	}
+	elapsed := time.Since(start)
+	if found {
+		fmt.Printf("\nSolved in %d generations:\n", generation)
+		fmt.Print(bestOrganism.board())
+	} else {
+		fmt.Printf("\nGave up after %d generations with %d conflicts\n", generation, bestOrganism.Fitness)
+	}
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation
+	for i := 0; i < len(top)-1; i++ {
+		num := (top[PoolSize].Fitness - top[i].Fitness)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best board so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.calcFitness()
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation() (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism()
+	}
+	return
+}
+
+// Get the best organism, the one with the fewest conflicts
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a board, with the column of the queen in every row. As the
+// columns are a permutation, no 2 queens are ever in the same row or column
+type Organism struct {
+	DNA     []int
+	Fitness int
+}
+
+// creates an organism with the queens in random columns
+func createOrganism() (organism Organism) {
+	organism = Organism{
+		DNA:     rng.Perm(N),
+		Fitness: 0,
+	}
+	organism.calcFitness()
+	return
+}
+
+// count the queens on each diagonal, going down and going up
+func (o *Organism) diagonals() (down, up []int) {
+	down = make([]int, 2*N)
+	up = make([]int, 2*N)
+	for row, col := range o.DNA {
+		down[row-col+N]++
+		up[row+col]++
+	}
+	return
+}
+
+// calculates the fitness of the Organism, which is the number of pairs of
+// queens on the same diagonal
+func (o *Organism) calcFitness() {
+	down, up := o.diagonals()
+	conflicts := 0
+	for i := range down {
+		conflicts += down[i] * (down[i] - 1) / 2
+		conflicts += up[i] * (up[i] - 1) / 2
+	}
+	o.Fitness = conflicts
+}
+
+// a random row with a queen on the same diagonal as another queen, or any
+// row if there is none
+func (o *Organism) conflicted() int {
+	down, up := o.diagonals()
+	rows := make([]int, 0)
+	for row, col := range o.DNA {
+		if down[row-col+N] > 1 || up[row+col] > 1 {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return rng.Intn(N)
+	}
+	return rows[rng.Intn(len(rows))]
+}
+
+// crosses over 2 Organisms with order crossover, which keeps a slice of the
+// first parent and fills in the other columns in the order they come in the
+// second parent, so the columns stay a permutation
+func crossover(d1 Organism, d2 Organism) Organism {
+	lo, hi := rng.Intn(N), rng.Intn(N)
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	child := Organism{
+		DNA:     make([]int, N),
+		Fitness: 0,
+	}
+	used := make([]bool, N)
+	for i := lo; i <= hi; i++ {
+		child.DNA[i] = d1.DNA[i]
+		used[d1.DNA[i]] = true
+	}
+	j := (hi + 1) % N
+	for k := 0; k < N; k++ {
+		col := d2.DNA[(hi+1+k)%N]
+		if !used[col] {
+			child.DNA[j] = col
+			j = (j + 1) % N
+		}
+	}
+	return child
+}
+
+// mutate the Organism by swapping a conflicted row with another row, or
+// reversing the columns between them. Moving the queens that are in conflict,
+// rather than any queen, is what makes hundreds of queens solvable
+func (o *Organism) mutate() {
+	if rng.Float64() >= MutationRate {
+		return
+	}
+	i, j := o.conflicted(), rng.Intn(N)
+	if rng.Intn(2) == 0 {
+		o.DNA[i], o.DNA[j] = o.DNA[j], o.DNA[i]
+		return
+	}
+	if i > j {
+		i, j = j, i
+	}
+	for ; i < j; i, j = i+1, j-1 {
+		o.DNA[i], o.DNA[j] = o.DNA[j], o.DNA[i]
+	}
+}
+
+// the board, drawn when it's small enough, otherwise just the columns
+func (o *Organism) board() string {
+	var sb strings.Builder
+	if N > 40 {
+		fmt.Fprintln(&sb, o.DNA)
+		return sb.String()
+	}
+	for _, col := range o.DNA {
+		sb.WriteString(strings.Repeat(". ", col))
+		sb.WriteString("Q ")
+		sb.WriteString(strings.Repeat(". ", N-col-1))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
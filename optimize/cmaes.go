@@ -0,0 +1,275 @@
+package optimize
+
+import (
+	"math"
+	"sort"
+)
+
+// CMAES is the covariance matrix adaptation evolution strategy. It samples
+// each generation from a multivariate normal distribution, then moves the
+// mean towards the best samples and adapts the step size and the shape of
+// the distribution to the directions that worked, so it learns the scale and
+// correlations of the variables by itself
+type CMAES struct {
+	Problem
+
+	lambda  int
+	mu      int
+	weights []float64
+	mueff   float64
+	cc      float64
+	cs      float64
+	c1      float64
+	cmu     float64
+	damps   float64
+	chiN    float64
+
+	// Mean is the center of the distribution the samples are drawn from
+	Mean []float64
+	// Sigma is the step size, how far the samples are spread around the mean
+	Sigma float64
+
+	pc []float64
+	ps []float64
+	// the covariance matrix, and its eigenvectors and the square roots of
+	// its eigenvalues
+	c [][]float64
+	b [][]float64
+	d []float64
+
+	evaluations int
+	decomposed  int
+	fitness     []float64
+	best        []float64
+	bestFitness float64
+}
+
+// NewCMAES creates CMA-ES with the mean at a random point and a step size of
+// 30% of the range of the problem, which can be changed before the first
+// step, sampling lambda points each generation, or the usual 4 + 3 ln(n)
+// points if lambda is 0
+func NewCMAES(p Problem, lambda int) *CMAES {
+	n := float64(p.Dimensions)
+	if lambda <= 0 {
+		lambda = 4 + int(3*math.Log(n))
+	}
+	mu := lambda / 2
+	// the weights of the best mu samples when moving the mean
+	weights := make([]float64, mu)
+	sum, squares := 0.0, 0.0
+	for i := range weights {
+		weights[i] = math.Log(float64(mu)+0.5) - math.Log(float64(i+1))
+		sum += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sum
+		squares += weights[i] * weights[i]
+	}
+	mueff := 1 / squares
+
+	es := &CMAES{
+		Problem: p,
+		lambda:  lambda,
+		mu:      mu,
+		weights: weights,
+		mueff:   mueff,
+		cc:      (4 + mueff/n) / (n + 4 + 2*mueff/n),
+		cs:      (mueff + 2) / (n + mueff + 5),
+		c1:      2 / ((n+1.3)*(n+1.3) + mueff),
+		chiN:    math.Sqrt(n) * (1 - 1/(4*n) + 1/(21*n*n)),
+
+		Mean:        p.random(),
+		Sigma:       0.3 * (p.Max - p.Min),
+		pc:          make([]float64, p.Dimensions),
+		ps:          make([]float64, p.Dimensions),
+		c:           identity(p.Dimensions),
+		b:           identity(p.Dimensions),
+		d:           make([]float64, p.Dimensions),
+		bestFitness: math.Inf(1),
+	}
+	es.cmu = math.Min(1-es.c1, 2*(mueff-2+1/mueff)/((n+2)*(n+2)+mueff))
+	es.damps = 1 + 2*math.Max(0, math.Sqrt((mueff-1)/(n+1))-1) + es.cs
+	for i := range es.d {
+		es.d[i] = 1
+	}
+	return es
+}
+
+// Step samples a generation and updates the distribution from it
+func (es *CMAES) Step() ([]float64, float64) {
+	n := es.Dimensions
+	type sample struct {
+		x       []float64
+		fitness float64
+	}
+	samples := make([]sample, es.lambda)
+	es.fitness = make([]float64, es.lambda)
+	for k := range samples {
+		z := make([]float64, n)
+		for i := range z {
+			z[i] = es.d[i] * es.source().NormFloat64()
+		}
+		x := make([]float64, n)
+		clamped := make([]float64, n)
+		for i := range x {
+			for j := range z {
+				x[i] += es.b[i][j] * z[j]
+			}
+			x[i] = es.Mean[i] + es.Sigma*x[i]
+			clamped[i] = es.clamp(x[i])
+		}
+		samples[k] = sample{x, es.F(clamped)}
+		es.fitness[k] = samples[k].fitness
+		if samples[k].fitness < es.bestFitness {
+			es.best, es.bestFitness = clamped, samples[k].fitness
+		}
+	}
+	es.evaluations += es.lambda
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].fitness < samples[j].fitness
+	})
+
+	// move the mean to the weighted mean of the best samples
+	old := es.Mean
+	es.Mean = make([]float64, n)
+	for k := 0; k < es.mu; k++ {
+		for i := range es.Mean {
+			es.Mean[i] += es.weights[k] * samples[k].x[i]
+		}
+	}
+	step := make([]float64, n)
+	for i := range step {
+		step[i] = (es.Mean[i] - old[i]) / es.Sigma
+	}
+
+	// update the evolution path of the step size, which needs the step
+	// with the shape of the distribution taken out, B D^-1 B^T step
+	whitened := make([]float64, n)
+	for j := 0; j < n; j++ {
+		proj := 0.0
+		for i := 0; i < n; i++ {
+			proj += es.b[i][j] * step[i]
+		}
+		proj /= es.d[j]
+		for i := 0; i < n; i++ {
+			whitened[i] += es.b[i][j] * proj
+		}
+	}
+	norm := 0.0
+	for i := range es.ps {
+		es.ps[i] = (1-es.cs)*es.ps[i] + math.Sqrt(es.cs*(2-es.cs)*es.mueff)*whitened[i]
+		norm += es.ps[i] * es.ps[i]
+	}
+	norm = math.Sqrt(norm)
+
+	// update the evolution path of the covariance, stalling it when the
+	// step size path is too long
+	hsig := 0.0
+	if norm/math.Sqrt(1-math.Pow(1-es.cs, 2*float64(es.evaluations)/float64(es.lambda)))/es.chiN < 1.4+2/float64(n+1) {
+		hsig = 1
+	}
+	for i := range es.pc {
+		es.pc[i] = (1-es.cc)*es.pc[i] + hsig*math.Sqrt(es.cc*(2-es.cc)*es.mueff)*step[i]
+	}
+
+	// update the covariance matrix with the path (rank one) and the best
+	// samples (rank mu)
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			rankMu := 0.0
+			for k := 0; k < es.mu; k++ {
+				rankMu += es.weights[k] * (samples[k].x[i] - old[i]) * (samples[k].x[j] - old[j])
+			}
+			rankMu /= es.Sigma * es.Sigma
+			cij := (1-es.c1-es.cmu)*es.c[i][j] +
+				es.c1*(es.pc[i]*es.pc[j]+(1-hsig)*es.cc*(2-es.cc)*es.c[i][j]) +
+				es.cmu*rankMu
+			es.c[i][j], es.c[j][i] = cij, cij
+		}
+	}
+
+	// grow the step size if the path is longer than a random walk's, shrink
+	// it if shorter
+	es.Sigma *= math.Exp((es.cs / es.damps) * (norm/es.chiN - 1))
+
+	// decomposing the matrix is expensive, so only do it every few
+	// generations
+	if float64(es.evaluations-es.decomposed) > float64(es.lambda)/(es.c1+es.cmu)/float64(n)/10 {
+		es.decomposed = es.evaluations
+		values, vectors := eigen(es.c)
+		for i := range values {
+			es.d[i] = math.Sqrt(math.Max(values[i], 1e-20))
+		}
+		es.b = vectors
+	}
+	return es.best, es.bestFitness
+}
+
+// Fitnesses are the fitness of every sample of the last generation
+func (es *CMAES) Fitnesses() []float64 {
+	return es.fitness
+}
+
+// the n by n identity matrix
+func identity(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		m[i][i] = 1
+	}
+	return m
+}
+
+// the eigenvalues and eigenvectors, as columns, of a symmetric matrix using
+// the cyclic Jacobi method
+func eigen(matrix [][]float64) (values []float64, vectors [][]float64) {
+	n := len(matrix)
+	a := make([][]float64, n)
+	for i := range a {
+		a[i] = append([]float64(nil), matrix[i]...)
+	}
+	vectors = identity(n)
+	for sweep := 0; sweep < 50; sweep++ {
+		off := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += a[i][j] * a[i][j]
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				// the rotation that zeroes a[p][q]
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p], a[k][q] = c*akp-s*akq, s*akp+c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k], a[q][k] = c*apk-s*aqk, s*apk+c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := vectors[k][p], vectors[k][q]
+					vectors[k][p], vectors[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+			}
+		}
+	}
+	values = make([]float64, n)
+	for i := range values {
+		values[i] = a[i][i]
+	}
+	return
+}
@@ -0,0 +1,92 @@
+package optimize
+
+// DE is differential evolution, where each member of the population is
+// challenged by a trial point made by adding the scaled difference between 2
+// other members to a third, and replaced if the trial is better
+type DE struct {
+	Problem
+	// Scale is the weight of the difference between the 2 members, usually
+	// called F
+	Scale float64
+	// CR is the chance of each variable of the trial coming from the mutant
+	// rather than the member it challenges
+	CR float64
+	// Variant is the way the mutant is made, rand/1/bin, best/1/bin or
+	// current-to-best/1/bin
+	Variant string
+
+	population [][]float64
+	fitness    []float64
+	best       int
+}
+
+// NewDE creates differential evolution with a random population, which needs
+// at least 4 members, using rand/1/bin with the usual scale of 0.5 and CR of
+// 0.9
+func NewDE(p Problem, popSize int) *DE {
+	de := &DE{
+		Problem:    p,
+		Scale:      0.5,
+		CR:         0.9,
+		Variant:    "rand/1/bin",
+		population: make([][]float64, popSize),
+		fitness:    make([]float64, popSize),
+	}
+	for i := range de.population {
+		de.population[i] = p.random()
+		de.fitness[i] = p.F(de.population[i])
+		if de.fitness[i] < de.fitness[de.best] {
+			de.best = i
+		}
+	}
+	return de
+}
+
+// Step challenges every member of the population once
+func (de *DE) Step() ([]float64, float64) {
+	n := len(de.population)
+	for i := 0; i < n; i++ {
+		// 3 distinct members other than i
+		r := make([]int, 0, 3)
+		for len(r) < 3 {
+			j := de.source().Intn(n)
+			if j != i && (len(r) < 1 || j != r[0]) && (len(r) < 2 || j != r[1]) {
+				r = append(r, j)
+			}
+		}
+		a, b, c := de.population[r[0]], de.population[r[1]], de.population[r[2]]
+		x, best := de.population[i], de.population[de.best]
+
+		trial := make([]float64, de.Dimensions)
+		// at least one variable always comes from the mutant
+		forced := de.source().Intn(de.Dimensions)
+		for k := range trial {
+			if k != forced && de.source().Float64() >= de.CR {
+				trial[k] = x[k]
+				continue
+			}
+			switch de.Variant {
+			case "best/1/bin":
+				trial[k] = best[k] + de.Scale*(b[k]-c[k])
+			case "current-to-best/1/bin":
+				trial[k] = x[k] + de.Scale*(best[k]-x[k]) + de.Scale*(b[k]-c[k])
+			default:
+				trial[k] = a[k] + de.Scale*(b[k]-c[k])
+			}
+			trial[k] = de.clamp(trial[k])
+		}
+
+		if fitness := de.F(trial); fitness <= de.fitness[i] {
+			de.population[i], de.fitness[i] = trial, fitness
+			if fitness < de.fitness[de.best] {
+				de.best = i
+			}
+		}
+	}
+	return de.population[de.best], de.fitness[de.best]
+}
+
+// Fitnesses are the fitness of every member of the population
+func (de *DE) Fitnesses() []float64 {
+	return de.fitness
+}
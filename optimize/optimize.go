@@ -0,0 +1,69 @@
+// Package optimize has strategies other than the genetic algorithm for
+// minimizing functions of real numbers, so they can be compared with it on
+// the same problems.
+package optimize
+
+import "github.com/sausheong/ga/rng"
+
+// Problem is a function of real numbers to minimize, with the range each
+// variable is searched in
+type Problem struct {
+	F          func(x []float64) float64
+	Dimensions int
+	Min        float64
+	Max        float64
+	// Rand is the source of random numbers, or the shared one of the rng
+	// package if it's nil
+	Rand Rand
+}
+
+// Rand is a source of random numbers, which *rand.Rand is, so a problem can
+// be given its own seeded source and be solved the same way every time
+type Rand interface {
+	Float64() float64
+	Intn(n int) int
+	NormFloat64() float64
+}
+
+// the shared source of random numbers of the rng package
+type sharedRand struct{}
+
+func (sharedRand) Float64() float64     { return rng.Float64() }
+func (sharedRand) Intn(n int) int       { return rng.Intn(n) }
+func (sharedRand) NormFloat64() float64 { return rng.NormFloat64() }
+
+// the source of random numbers of the problem
+func (p Problem) source() Rand {
+	if p.Rand == nil {
+		return sharedRand{}
+	}
+	return p.Rand
+}
+
+// Optimizer evolves solutions to a problem one generation at a time
+type Optimizer interface {
+	// Step evolves a generation and returns the best solution so far
+	Step() (best []float64, fitness float64)
+	// Fitnesses are the fitness of every member of the current generation
+	Fitnesses() []float64
+}
+
+// a random point in the range of the problem
+func (p Problem) random() []float64 {
+	x := make([]float64, p.Dimensions)
+	for i := range x {
+		x[i] = p.Min + p.source().Float64()*(p.Max-p.Min)
+	}
+	return x
+}
+
+// keep x within the range of the problem
+func (p Problem) clamp(x float64) float64 {
+	if x < p.Min {
+		return p.Min
+	}
+	if x > p.Max {
+		return p.Max
+	}
+	return x
+}
@@ -0,0 +1,230 @@
+// Package output is where the picture demos write their files, the evolved
+// image, the frames, the checkpoints and the summary: a local directory, an
+// S3 or Cloud Storage bucket, a URL the files are posted to, or memory.
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink is where the files a run writes end up, like the evolved image, the
+// checkpoints and the summary
+type Sink interface {
+	// Write writes the data to the file with the name, a path relative to
+	// wherever the sink keeps its files
+	Write(name string, data []byte) error
+	// Remove removes the file with the name
+	Remove(name string) error
+}
+
+// ReadSink is a sink the files can be read back from, as a checkpoint is to
+// resume a run from it
+type ReadSink interface {
+	Sink
+	// Read reads the file with the name
+	Read(name string) ([]byte, error)
+}
+
+// Local writes files to a directory on the local filesystem, or the current
+// directory if Dir is empty
+type Local struct {
+	Dir string
+}
+
+// Write writes the file under the directory, making any directories it's in.
+// The file is replaced whole, so it's never left half written
+func (l Local) Write(name string, data []byte) error {
+	path := filepath.Join(l.Dir, name)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	// write a temporary file and rename it over the file, so a crash or
+	// Ctrl-C while writing never leaves the file cut short
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	// once renamed there's no temporary file left to remove
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Remove removes the file from the directory
+func (l Local) Remove(name string) error {
+	return os.Remove(filepath.Join(l.Dir, name))
+}
+
+// Read reads the file from the directory
+func (l Local) Read(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(l.Dir, name))
+}
+
+// HTTP posts each file to a URL, the URL of the sink followed by the name of
+// the file
+type HTTP struct {
+	URL string
+}
+
+// Write posts the file, with its content type worked out from its name
+func (h HTTP) Write(name string, data []byte) error {
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(h.target(name), contentType, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("cannot post %s: %s", name, resp.Status)
+	}
+	return nil
+}
+
+// Remove sends a DELETE for the file
+func (h HTTP) Remove(name string) error {
+	req, err := http.NewRequest(http.MethodDelete, h.target(name), nil)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("cannot delete %s: %s", name, resp.Status)
+	}
+	return nil
+}
+
+// the URL of the file
+func (h HTTP) target(name string) string {
+	return strings.TrimRight(h.URL, "/") + "/" + strings.TrimPrefix(filepath.ToSlash(filepath.Clean(name)), "/")
+}
+
+// Memory keeps the files in memory, for tests and for programs that do
+// something else with them
+type Memory struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// Write keeps a copy of the file, replacing any with the same name
+func (m *Memory) Write(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+	}
+	m.files[filepath.Clean(name)] = append([]byte(nil), data...)
+	return nil
+}
+
+// Remove forgets the file
+func (m *Memory) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[filepath.Clean(name)]; !ok {
+		return fmt.Errorf("no file %s", name)
+	}
+	delete(m.files, filepath.Clean(name))
+	return nil
+}
+
+// File is the data of the file with the name, if it has been written
+func (m *Memory) File(name string) (data []byte, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok = m.files[filepath.Clean(name)]
+	return
+}
+
+// Read is a copy of the file, if it has been written
+func (m *Memory) Read(name string) ([]byte, error) {
+	data, ok := m.File(name)
+	if !ok {
+		return nil, fmt.Errorf("no file %s", name)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Names are the names of the files written, sorted
+func (m *Memory) Names() (names []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Open opens the sink for the location, which is s3://bucket/prefix for S3 or
+// an S3 compatible store like MinIO, gs://bucket/prefix for Google Cloud
+// Storage, an http or https URL to post the files to, or else a local
+// directory. The keys of the stores are taken from the environment
+func Open(location string) (Sink, error) {
+	u, err := url.Parse(location)
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return HTTP{URL: location}, nil
+	}
+	if err != nil || (u.Scheme != "s3" && u.Scheme != "gs") {
+		return Local{Dir: location}, nil
+	}
+	s := &S3{
+		Bucket:       u.Host,
+		Prefix:       strings.Trim(u.Path, "/"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.AccessKey == "" || s.SecretKey == "" {
+		return nil, fmt.Errorf("need AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY to write to %s", location)
+	}
+	if u.Scheme == "gs" {
+		// Cloud Storage takes S3 requests signed with its HMAC keys
+		s.Endpoint, s.Region = "https://storage.googleapis.com", "auto"
+		return s, nil
+	}
+	s.Region = firstSet(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	s.Endpoint = firstSet(os.Getenv("AWS_ENDPOINT_URL"), "https://s3."+s.Region+".amazonaws.com")
+	return s, nil
+}
+
+// the first of the values that isn't empty
+func firstSet(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package output
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 writes files as objects in a bucket of S3, or of a store that speaks
+// the S3 API like MinIO or Google Cloud Storage, signing each request with
+// AWS Signature Version 4
+type S3 struct {
+	// Endpoint is the URL of the store, like https://s3.us-east-1.amazonaws.com
+	Endpoint     string
+	Region       string
+	Bucket       string
+	Prefix       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// Write puts the file in the bucket, under the prefix
+func (s *S3) Write(name string, data []byte) error {
+	_, err := s.do(http.MethodPut, name, data)
+	return err
+}
+
+// Remove deletes the file from the bucket
+func (s *S3) Remove(name string) error {
+	_, err := s.do(http.MethodDelete, name, nil)
+	return err
+}
+
+// Read gets the file back from the bucket
+func (s *S3) Read(name string) ([]byte, error) {
+	return s.do(http.MethodGet, name, nil)
+}
+
+// send a signed request for the object of the file, returning the object if
+// it's a GET
+func (s *S3) do(method, name string, data []byte) ([]byte, error) {
+	key := path.Join(s.Prefix, strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/"))
+	req, err := http.NewRequest(method, strings.TrimRight(s.Endpoint, "/")+"/"+s.Bucket+"/"+escapePath(key), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	s.sign(req, data, time.Now().UTC())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("cannot %s %s in %s: %s", strings.ToLower(method), key, s.Bucket, resp.Status)
+	}
+	if method != http.MethodGet {
+		return nil, nil
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// sign the request with AWS Signature Version 4, covering the host and every
+// header already set on it
+func (s *S3) sign(req *http.Request, payload []byte, now time.Time) {
+	sum := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(sum[:])
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// escape each segment of the path the way S3 does, leaving only letters,
+// digits and -_.~ as they are
+func escapePath(p string) string {
+	var sb strings.Builder
+	for _, b := range []byte(p) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
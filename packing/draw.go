@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/llgcode/draw2d/draw2dkit"
+)
+
+// ImageWidth is the width of the packing image
+var ImageWidth = 400
+
+// draw the sheet and the rectangles placed on it, which are see-through so
+// any overlaps show up darker
+func drawPacking(placements []Placement, rects []Rect, width int) *image.RGBA {
+	margin := 10.0
+	scale := (float64(ImageWidth) - 2*margin) / float64(width)
+	// draw a bit beyond the edges so rectangles off the sheet show up
+	minX, maxX, minY, maxY := 0, width, 0, length(placements, rects)
+	for i, p := range placements {
+		w, _ := p.size(rects[i])
+		minX, maxX, minY = min(minX, p.X), max(maxX, p.X+w), min(minY, p.Y)
+	}
+	w := int(float64(maxX-minX)*scale + 2*margin)
+	h := int(float64(maxY-minY)*scale + 2*margin)
+	// the sheet is drawn with y going up, like a roll being cut from the bottom
+	point := func(x, y int) (float64, float64) {
+		return margin + float64(x-minX)*scale, float64(h) - margin - float64(y-minY)*scale
+	}
+
+	dest := image.NewRGBA(image.Rect(0, 0, w, h))
+	gc := draw2dimg.NewGraphicContext(dest)
+	gc.SetFillColor(color.White)
+	draw2dkit.Rectangle(gc, 0, 0, float64(w), float64(h))
+	gc.Fill()
+
+	gc.SetFillColor(color.RGBA{220, 220, 220, 255})
+	x1, y1 := point(0, 0)
+	x2, y2 := point(width, maxY)
+	draw2dkit.Rectangle(gc, x1, y2, x2, y1)
+	gc.Fill()
+
+	gc.SetStrokeColor(color.Black)
+	gc.SetLineWidth(1)
+	for i, p := range placements {
+		rw, rh := p.size(rects[i])
+		gc.SetFillColor(colors[i%len(colors)])
+		x1, y1 := point(p.X, p.Y)
+		x2, y2 := point(p.X+rw, p.Y+rh)
+		draw2dkit.Rectangle(gc, x1, y2, x2, y1)
+		gc.FillStroke()
+	}
+	return dest
+}
+
+// the colors of the rectangles
+var colors = []color.NRGBA{
+	{230, 25, 75, 160}, {60, 180, 75, 160}, {0, 130, 200, 160}, {245, 130, 48, 160},
+	{145, 30, 180, 160}, {70, 240, 240, 160}, {240, 50, 230, 160}, {210, 245, 60, 160},
+	{0, 128, 128, 160}, {170, 110, 40, 160}, {128, 0, 0, 160}, {255, 225, 25, 160},
+}
+
+// save the image
+func save(filePath string, img image.Image) {
+	imgFile, err := os.Create(filePath)
+	if err != nil {
+		fmt.Println("Cannot create file:", err)
+		return
+	}
+	defer imgFile.Close()
+	png.Encode(imgFile, img)
+}
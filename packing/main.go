@@ -0,0 +1,259 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/preview"
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each placement
+var MutationRate = 0.05
+
+// PopSize is the size of the population
+var PopSize = 200
+
+// PoolSize is the max size of the pool
+var PoolSize = 30
+
+// Generations is the number of generations to evolve
+var Generations = 3000
+
+// SheetWidth is the width of the sheet the rectangles are cut from
+var SheetWidth = 40
+
+// Penalty is how much more each unit of area where rectangles overlap or go
+// off the sheet counts than the wasted area
+var Penalty = 10
+
+func main() {
+	rectsFile := flag.String("rects", "", "CSV file with the width, height and optionally the count of the rectangles")
+	flag.IntVar(&SheetWidth, "width", SheetWidth, "width of the sheet")
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+
+	rects := defaultRects
+	if *rectsFile != "" {
+		var err error
+		rects, err = readRects(*rectsFile)
+		if err != nil {
+			fmt.Println("Cannot read rectangles:", err)
+			return
+		}
+	}
+	for _, r := range rects {
+		if min(r.W, r.H) > SheetWidth {
+			fmt.Printf("Cannot pack rectangles: %dx%d doesn't fit on the sheet\n", r.W, r.H)
+			return
+		}
+	}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	population := createPopulation(rects)
+
+	var bestOrganism Organism
+	for generation := 1; generation <= Generations; generation++ {
+		bestOrganism = getBest(population)
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism, rects)
+		if generation%100 == 0 || generation == Generations {
+			sofar := time.Since(start)
+			fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | length: %d | overlap: %d | waste: %.1f%%",
+				sofar, generation, bestOrganism.Fitness, length(bestOrganism.DNA, rects), penalty(bestOrganism.DNA, rects, SheetWidth), 100*waste(bestOrganism.DNA, rects))
+			img := drawPacking(bestOrganism.DNA, rects, SheetWidth)
+			save("./packing.png", img)
+			fmt.Println()
+			preview.Print(img)
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with at most 100 copies of any organism
+	spread := top[PoolSize].Fitness - top[0].Fitness
+	for i := 0; i < len(top)-1; i++ {
+		num := (top[PoolSize].Fitness - top[i].Fitness) * 100 / spread
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, rects []Rect) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best packing so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate(rects)
+		child.calcFitness(rects)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(rects []Rect) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(rects)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a packing, with a placement for each rectangle
+type Organism struct {
+	DNA     []Placement
+	Fitness int
+}
+
+// creates an organism with the rectangles scattered over the sheet
+func createOrganism(rects []Rect) (organism Organism) {
+	organism = Organism{
+		DNA:     make([]Placement, len(rects)),
+		Fitness: 0,
+	}
+	for i := range organism.DNA {
+		organism.DNA[i] = randomPlacement(rects[i], 2*area(rects)/SheetWidth)
+	}
+	organism.calcFitness(rects)
+	return
+}
+
+// a random placement of the rectangle on the sheet, up to the given length
+func randomPlacement(r Rect, length int) (p Placement) {
+	p.Rotated = rng.Intn(2) == 0
+	w, _ := p.size(r)
+	if w > SheetWidth {
+		p.Rotated = !p.Rotated
+		w, _ = p.size(r)
+	}
+	p.X = rng.Intn(SheetWidth - w + 1)
+	p.Y = rng.Intn(length + 1)
+	return
+}
+
+// calculates the fitness of the Organism, which is the area where rectangles
+// overlap or go off the sheet, weighted by Penalty, plus the wasted area
+// between the rectangles, up to the furthest one
+func (o *Organism) calcFitness(rects []Rect) {
+	o.Fitness = Penalty*penalty(o.DNA, rects, SheetWidth) + SheetWidth*length(o.DNA, rects) - area(rects)
+}
+
+// the fraction of the sheet used that is wasted
+func waste(placements []Placement, rects []Rect) float64 {
+	used := SheetWidth * length(placements, rects)
+	return float64(used-area(rects)) / float64(used)
+}
+
+// the total area of the rectangles
+func area(rects []Rect) (a int) {
+	for _, r := range rects {
+		a += r.W * r.H
+	}
+	return
+}
+
+// crosses over 2 Organisms, taking each placement from either parent
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]Placement, len(d1.DNA)),
+		Fitness: 0,
+	}
+	for i := range child.DNA {
+		if rng.Intn(2) == 0 {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism by nudging, turning, sliding or moving rectangles
+func (o *Organism) mutate(rects []Rect) {
+	for i := range o.DNA {
+		if rng.Float64() >= MutationRate {
+			continue
+		}
+		p := &o.DNA[i]
+		switch rng.Intn(5) {
+		case 0:
+			p.X += rng.Intn(7) - 3
+		case 1:
+			p.Y += rng.Intn(7) - 3
+		case 2:
+			p.Rotated = !p.Rotated
+		case 3:
+			o.slide(i, rects)
+		case 4:
+			*p = randomPlacement(rects[i], length(o.DNA, rects))
+		}
+	}
+}
+
+// slide the rectangle down, and then left, until it hits another rectangle
+// or the edge of the sheet
+func (o *Organism) slide(i int, rects []Rect) {
+	p := &o.DNA[i]
+	w, h := p.size(rects[i])
+	floor := 0
+	for j, q := range o.DNA {
+		qw, qh := q.size(rects[j])
+		if j != i && q.X < p.X+w && p.X < q.X+qw && q.Y+qh <= p.Y {
+			floor = max(floor, q.Y+qh)
+		}
+	}
+	p.Y = min(p.Y, max(floor, 0))
+	wall := 0
+	for j, q := range o.DNA {
+		qw, qh := q.size(rects[j])
+		if j != i && q.Y < p.Y+h && p.Y < q.Y+qh && q.X+qw <= p.X {
+			wall = max(wall, q.X+qw)
+		}
+	}
+	p.X = min(p.X, max(wall, 0))
+}
@@ -0,0 +1,61 @@
+package main
+
+// Placement is where a rectangle goes on the sheet, and whether it's turned
+// on its side
+type Placement struct {
+	X       int
+	Y       int
+	Rotated bool
+}
+
+// the width and height of the rectangle as placed
+func (p Placement) size(r Rect) (int, int) {
+	if p.Rotated {
+		return r.H, r.W
+	}
+	return r.W, r.H
+}
+
+// the area where 2 placed rectangles overlap
+func overlap(a Placement, ra Rect, b Placement, rb Rect) int {
+	aw, ah := a.size(ra)
+	bw, bh := b.size(rb)
+	w := min(a.X+aw, b.X+bw) - max(a.X, b.X)
+	h := min(a.Y+ah, b.Y+bh) - max(a.Y, b.Y)
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	return w * h
+}
+
+// the area of the placed rectangle that is off the sheet, which is width wide
+// and as long as it needs to be
+func outside(p Placement, r Rect, width int) int {
+	w, h := p.size(r)
+	inW := min(p.X+w, width) - max(p.X, 0)
+	inH := p.Y + h - max(p.Y, 0)
+	if inW <= 0 || inH <= 0 {
+		return w * h
+	}
+	return w*h - inW*inH
+}
+
+// the total overlap and area off the sheet of all the placed rectangles
+func penalty(placements []Placement, rects []Rect, width int) (area int) {
+	for i, p := range placements {
+		area += outside(p, rects[i], width)
+		for j := i + 1; j < len(placements); j++ {
+			area += overlap(p, rects[i], placements[j], rects[j])
+		}
+	}
+	return
+}
+
+// how far up the sheet the placed rectangles go
+func length(placements []Placement, rects []Rect) (l int) {
+	for i, p := range placements {
+		_, h := p.size(rects[i])
+		l = max(l, p.Y+h)
+	}
+	return
+}
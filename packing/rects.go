@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Rect is a rectangle to be cut from the sheet
+type Rect struct {
+	W int
+	H int
+}
+
+// the rectangles to pack when no file is given, which fit exactly into a
+// 40 by 40 square
+var defaultRects = []Rect{
+	{24, 10}, {12, 14}, {12, 14}, {10, 16}, {14, 16},
+	{16, 8}, {8, 12}, {8, 12}, {16, 6}, {6, 14}, {10, 14},
+}
+
+// read the rectangles from a CSV file, with the width, height and optionally
+// how many of them there are in each row, skipping the header row if there is
+// one
+func readRects(filePath string) (rects []Rect, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return
+	}
+	for n, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: need a width and height", n+1)
+		}
+		w, werr := strconv.Atoi(strings.TrimSpace(record[0]))
+		h, herr := strconv.Atoi(strings.TrimSpace(record[1]))
+		if werr != nil || herr != nil {
+			if n == 0 {
+				// the header
+				continue
+			}
+			return nil, fmt.Errorf("line %d: cannot parse width and height", n+1)
+		}
+		if w <= 0 || h <= 0 {
+			return nil, fmt.Errorf("line %d: width and height must be positive", n+1)
+		}
+		count := 1
+		if len(record) > 2 {
+			count, err = strconv.Atoi(strings.TrimSpace(record[2]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: cannot parse count", n+1)
+			}
+		}
+		for i := 0; i < count; i++ {
+			rects = append(rects, Rect{W: w, H: h})
+		}
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package preview
+
+import "time"
+
+// Cadence decides when a run shows or saves its progress. A generation takes
+// microseconds in one demo and seconds in another, so a fixed number of
+// generations is too often for some and too seldom for others, and an
+// interval of time can be set instead
+type Cadence struct {
+	// Generations is how many generations apart the output is
+	Generations int
+	// Interval, if it's set, is how long apart the output is instead
+	Interval time.Duration
+	last     time.Time
+}
+
+// Due reports whether the output is due at the generation. With an interval
+// the first call starts the clock, and each call that's due starts it again
+func (c *Cadence) Due(generation int) bool {
+	if c.Interval <= 0 {
+		return c.Generations > 0 && generation%c.Generations == 0
+	}
+	now := time.Now()
+	if c.last.IsZero() {
+		c.last = now
+		return false
+	}
+	if now.Sub(c.last) < c.Interval {
+		return false
+	}
+	c.last = now
+	return true
+}
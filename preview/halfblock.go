@@ -0,0 +1,63 @@
+package preview
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"os"
+)
+
+// BlockWidth is the number of terminal columns used by the half-block preview
+var BlockWidth = 80
+
+// prints a downscaled image using the upper half block character, with the
+// foreground color as the top pixel and the background color as the bottom
+func printHalfBlock(img image.Image) {
+	bounds := img.Bounds()
+	cols := BlockWidth
+	if bounds.Dx() < cols {
+		cols = bounds.Dx()
+	}
+	if cols <= 0 {
+		return
+	}
+	// each character cell covers a square of scale x scale source pixels
+	// on top and the same below
+	scale := float64(bounds.Dx()) / float64(cols)
+	rows := int(float64(bounds.Dy()) / scale)
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for y := 0; y+1 < rows; y += 2 {
+		for x := 0; x < cols; x++ {
+			tr, tg, tb := average(img, bounds, x, y, scale)
+			br, bg, bb := average(img, bounds, x, y+1, scale)
+			fmt.Fprintf(w, "%s[38;2;%d;%d;%dm%s[48;2;%d;%d;%dm▀", escape, tr, tg, tb, escape, br, bg, bb)
+		}
+		fmt.Fprintf(w, "%s[0m\n", escape)
+	}
+}
+
+// average color of the block of source pixels for a downscaled pixel
+func average(img image.Image, bounds image.Rectangle, x, y int, scale float64) (r, g, b uint32) {
+	x0, y0 := bounds.Min.X+int(float64(x)*scale), bounds.Min.Y+int(float64(y)*scale)
+	x1, y1 := bounds.Min.X+int(float64(x+1)*scale), bounds.Min.Y+int(float64(y+1)*scale)
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	var n uint32
+	for j := y0; j < y1 && j < bounds.Max.Y; j++ {
+		for i := x0; i < x1 && i < bounds.Max.X; i++ {
+			pr, pg, pb, _ := img.At(i, j).RGBA()
+			r, g, b = r+pr>>8, g+pg>>8, b+pb>>8
+			n++
+		}
+	}
+	if n > 0 {
+		r, g, b = r/n, g/n, b/n
+	}
+	return
+}
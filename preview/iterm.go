@@ -0,0 +1,19 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+)
+
+const escape = "\x1b"
+
+// this only works for iTerm!
+func printITerm(img image.Image) {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	imgBase64Str := base64.StdEncoding.EncodeToString(buf.Bytes())
+	fmt.Printf("%s]1337;File=inline=1:%s\a\n", escape, imgBase64Str)
+}
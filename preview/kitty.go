@@ -0,0 +1,39 @@
+package preview
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+)
+
+// the kitty protocol takes the payload in chunks of at most 4096 bytes
+const kittyChunk = 4096
+
+// prints the image using the kitty graphics protocol
+func printKitty(img image.Image) {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for i := 0; i < len(data); i += kittyChunk {
+		end := i + kittyChunk
+		more := 1
+		if end >= len(data) {
+			end = len(data)
+			more = 0
+		}
+		if i == 0 {
+			// f=100 is PNG data and a=T transmits and displays it
+			fmt.Fprintf(w, "%s_Gf=100,a=T,m=%d;%s%s\\", escape, more, data[i:end], escape)
+		} else {
+			fmt.Fprintf(w, "%s_Gm=%d;%s%s\\", escape, more, data[i:end], escape)
+		}
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,96 @@
+// Package preview displays images inline in the terminal so the evolution of
+// the image demos can be watched as it happens.
+package preview
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"strings"
+)
+
+// Protocol is a way of displaying images inline in a terminal
+type Protocol int
+
+const (
+	// ITerm is the iTerm2 inline image escape sequence
+	ITerm Protocol = iota
+	// Sixel is the DEC sixel graphics format
+	Sixel
+	// Kitty is the kitty terminal graphics protocol
+	Kitty
+	// HalfBlock draws the image with 24-bit colored half-block characters,
+	// which works on terminals without any image protocol
+	HalfBlock
+)
+
+// the protocol used by Print
+var current = Detect()
+
+// Print displays the image using the protocol set for this terminal
+func Print(img image.Image) {
+	PrintWith(current, img)
+}
+
+// Set chooses the protocol used by Print by name, where auto detects it from
+// the terminal
+func Set(name string) error {
+	switch name {
+	case "auto":
+		current = Detect()
+	case "iterm":
+		current = ITerm
+	case "sixel":
+		current = Sixel
+	case "kitty":
+		current = Kitty
+	case "blocks":
+		current = HalfBlock
+	default:
+		return fmt.Errorf("unknown preview protocol %q", name)
+	}
+	return nil
+}
+
+// PrintWith displays the image using the given protocol
+func PrintWith(p Protocol, img image.Image) {
+	switch p {
+	case Sixel:
+		printSixel(img)
+	case Kitty:
+		printKitty(img)
+	case HalfBlock:
+		printHalfBlock(img)
+	default:
+		printITerm(img)
+	}
+}
+
+// Detect guesses the image protocol the terminal supports from its environment
+func Detect() Protocol {
+	if os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty" {
+		return Kitty
+	}
+	if os.Getenv("LC_TERMINAL") == "iTerm2" {
+		return ITerm
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app":
+		return ITerm
+	case "WezTerm":
+		return Sixel
+	case "ghostty":
+		return Kitty
+	}
+	term := os.Getenv("TERM")
+	for _, t := range []string{"mlterm", "foot", "yaft", "contour"} {
+		if strings.HasPrefix(term, t) {
+			return Sixel
+		}
+	}
+	// xterm only does sixel in VT340 mode, usually set up with a sixel TERM
+	if strings.Contains(term, "sixel") {
+		return Sixel
+	}
+	return HalfBlock
+}
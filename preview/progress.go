@@ -0,0 +1,96 @@
+package preview
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// number of recent samples the decay curve is fitted to
+const progressWindow = 50
+
+// Progress is a compact, escape-sequence free progress line for runs where
+// the image preview is turned off, with an ETA to reach the fitness limit
+type Progress struct {
+	limit   int64
+	samples []progressSample
+}
+
+// progressSample is the best fitness at a generation
+type progressSample struct {
+	generation int
+	at         time.Time
+	fitness    int64
+}
+
+// NewProgress creates a progress line for a run that stops at the fitness limit
+func NewProgress(limit int64) *Progress {
+	return &Progress{limit: limit}
+}
+
+// Record records the best fitness at the generation
+func (p *Progress) Record(generation int, fitness int64) {
+	p.samples = append(p.samples, progressSample{generation, time.Now(), fitness})
+	if len(p.samples) > progressWindow {
+		p.samples = p.samples[1:]
+	}
+}
+
+// Estimate predicts the generation the run reaches the fitness limit at, and
+// how long until then. The fitness falls quickly at first and more and more
+// slowly after, so rather than going on at the latest rate, it fits a decay
+// curve, fitness = a * generation^-b, to the recent samples, which is a
+// straight line on a log-log scale, and follows the curve down to the limit
+func (p *Progress) Estimate() (generation int, eta time.Duration, ok bool) {
+	n := len(p.samples)
+	if n < 3 || p.limit <= 0 {
+		return
+	}
+	first, last := p.samples[0], p.samples[n-1]
+	if last.fitness <= p.limit {
+		return last.generation, 0, true
+	}
+	if last.generation <= first.generation {
+		return
+	}
+	// fit the line by least squares
+	var sx, sy, sxx, sxy float64
+	for _, s := range p.samples {
+		if s.generation < 1 || s.fitness < 1 {
+			return
+		}
+		x, y := math.Log(float64(s.generation)), math.Log(float64(s.fitness))
+		sx, sy, sxx, sxy = sx+x, sy+y, sxx+x*x, sxy+x*y
+	}
+	count := float64(n)
+	slope := (count*sxy - sx*sy) / (count*sxx - sx*sx)
+	intercept := (sy - slope*sx) / count
+	// a curve that isn't falling never gets there
+	if !(slope < 0) {
+		return
+	}
+	at := math.Exp((math.Log(float64(p.limit)) - intercept) / slope)
+	// so far off it's no estimate at all
+	if at > 1e7 {
+		return
+	}
+	generation = max(int(math.Ceil(at)), last.generation)
+	perGeneration := last.at.Sub(first.at) / time.Duration(last.generation-first.generation)
+	eta = time.Duration(generation-last.generation) * perGeneration
+	return generation, eta, true
+}
+
+// ETA is the estimate as text, or unknown if there isn't one
+func (p *Progress) ETA() string {
+	generation, eta, ok := p.Estimate()
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s at generation %d", eta.Round(time.Second), generation)
+}
+
+// Line records the current best fitness and returns the progress line
+func (p *Progress) Line(generation int, fitness int64) string {
+	p.Record(generation, fitness)
+	return fmt.Sprintf("generation: %d | fitness: %d | limit: %d | eta: %s", generation, fitness, p.limit, p.ETA())
+}
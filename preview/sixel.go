@@ -0,0 +1,96 @@
+package preview
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"os"
+)
+
+// number of levels per channel in the sixel color cube palette
+const levels = 6
+
+// prints the image as sixels, using a fixed 6x6x6 color cube palette
+func printSixel(img image.Image) {
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	writeSixel(w, img)
+}
+
+// writes the sixel escape sequence for the image
+func writeSixel(w *bufio.Writer, img image.Image) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// index every pixel into the palette
+	idx := make([]int, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			idx[y*width+x] = paletteIndex(r>>8, g>>8, b>>8)
+		}
+	}
+
+	fmt.Fprintf(w, "%sPq\"1;1;%d;%d", escape, width, height)
+	for i := 0; i < levels*levels*levels; i++ {
+		r, g, b := i/(levels*levels), (i/levels)%levels, i%levels
+		fmt.Fprintf(w, "#%d;2;%d;%d;%d", i, r*100/(levels-1), g*100/(levels-1), b*100/(levels-1))
+	}
+
+	// each band is 6 pixels high, written once for each color used in it
+	bits := make([]byte, width)
+	for top := 0; top < height; top += 6 {
+		used := make(map[int]bool)
+		for y := top; y < top+6 && y < height; y++ {
+			for x := 0; x < width; x++ {
+				used[idx[y*width+x]] = true
+			}
+		}
+		first := true
+		for c := range used {
+			for x := 0; x < width; x++ {
+				bits[x] = 0
+				for y := top; y < top+6 && y < height; y++ {
+					if idx[y*width+x] == c {
+						bits[x] |= 1 << uint(y-top)
+					}
+				}
+			}
+			if !first {
+				w.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(w, "#%d", c)
+			writeRuns(w, bits)
+		}
+		w.WriteByte('-')
+	}
+	fmt.Fprintf(w, "%s\\\n", escape)
+}
+
+// writes the sixel characters for a band, run length encoded
+func writeRuns(w *bufio.Writer, bits []byte) {
+	for x := 0; x < len(bits); {
+		n := 1
+		for x+n < len(bits) && bits[x+n] == bits[x] {
+			n++
+		}
+		ch := bits[x] + 63
+		if n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, ch)
+		} else {
+			for i := 0; i < n; i++ {
+				w.WriteByte(ch)
+			}
+		}
+		x += n
+	}
+}
+
+// the nearest color in the color cube palette
+func paletteIndex(r, g, b uint32) int {
+	q := func(v uint32) int {
+		return int((v*(levels-1) + 127) / 255)
+	}
+	return q(r)*levels*levels + q(g)*levels + q(b)
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each token
+var MutationRate = 0.1
+
+// PopSize is the size of the population
+var PopSize = 300
+
+// PoolSize is the max size of the pool
+var PoolSize = 30
+
+// Generations is the most generations to evolve
+var Generations = 2000
+
+// MaxTokens is the most tokens a regular expression can have
+var MaxTokens = 20
+
+// Shorten is the number of generations to keep going after a regular
+// expression gets every example right, to find a shorter one
+var Shorten = 100
+
+// Restart is the number of generations without any improvement before the
+// population is started again from scratch, as it's stuck
+var Restart = 200
+
+// Parsimony is the fitness penalty for every character of the regular
+// expression, so shorter ones win when they are just as good
+var Parsimony = 0.001
+
+// the examples to use when none are given, dates to match and things that
+// aren't quite dates to reject
+var (
+	defaultMatch  = "2019-01-15,1999-12-31,2024-06-01,2000-02-29"
+	defaultReject = "2019/01/15,19-01-15,hello,2019-1-5,20190115,x2019-01-15"
+)
+
+func main() {
+	match := flag.String("match", defaultMatch, "comma separated examples the regular expression should match")
+	reject := flag.String("reject", defaultReject, "comma separated examples the regular expression should not match")
+	matchFile := flag.String("match-file", "", "file with an example to match on every line, instead of -match")
+	rejectFile := flag.String("reject-file", "", "file with an example to reject on every line, instead of -reject")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	positives, err := examples(*match, *matchFile)
+	if err != nil {
+		fmt.Println("Cannot read examples:", err)
+		return
+	}
+	negatives, err := examples(*reject, *rejectFile)
+	if err != nil {
+		fmt.Println("Cannot read examples:", err)
+		return
+	}
+	tokens := grammar(positives)
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	population := createPopulation(tokens, positives, negatives)
+
+	found := 0
+	generation := 0
+	lastImproved, bestCorrect := 0, -1
+	var bestOrganism Organism
+	for generation < Generations && (found == 0 || generation-found < Shorten) {
+		generation++
+		bestOrganism = getBest(population)
+		if found == 0 && bestOrganism.Correct == len(positives)+len(negatives) {
+			found = generation
+			fmt.Printf("All examples right at generation %d with %s, shortening it\n", generation, bestOrganism.regexp())
+		}
+		if bestOrganism.Correct > bestCorrect {
+			lastImproved, bestCorrect = generation, bestOrganism.Correct
+		}
+		if found == 0 && generation-lastImproved > Restart {
+			fmt.Printf("Stuck at %d correct with %s, starting again\n", bestCorrect, bestOrganism.regexp())
+			population = createPopulation(tokens, positives, negatives)
+			lastImproved, bestCorrect = generation, -1
+			continue
+		}
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism, tokens, positives, negatives)
+		if generation%10 == 0 {
+			sofar := time.Since(start)
+			fmt.Printf("Time taken so far: %s | generation: %d | correct: %d of %d | %s\n", sofar, generation, bestOrganism.Correct, len(positives)+len(negatives), bestOrganism.regexp())
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nBest regular expression after %d generations, correct for %d of %d examples:\n  %s\n", generation, bestOrganism.Correct, len(positives)+len(negatives), bestOrganism.regexp())
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// the examples, either from the comma separated list or from the file
+func examples(list, filePath string) ([]string, error) {
+	if filePath == "" {
+		return strings.Split(list, ","), nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(string(data), "\r\n"), "\n"), nil
+}
+
+// the atoms regular expressions are built from, the character classes and
+// any of the characters in the examples to match
+func grammar(positives []string) []string {
+	atoms := []string{`\d`, `\w`, `\s`, `.`, `[a-z]`, `[A-Z]`, `[0-9]`}
+	seen := make(map[rune]bool)
+	for _, p := range positives {
+		for _, r := range p {
+			if !seen[r] {
+				seen[r] = true
+				atoms = append(atoms, regexp.QuoteMeta(string(r)))
+			}
+		}
+	}
+	return atoms
+}
+
+// the quantifiers that can follow an atom, mostly none
+var quantifiers = []string{"", "", "", "", "*", "+", "?", "{2}", "{4}"}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness > population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	spread := top[0].Fitness - top[PoolSize].Fitness
+	if spread == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with up to 100 copies of the best
+	for i := 0; i < len(top)-1; i++ {
+		num := int(100 * (top[i].Fitness - top[PoolSize].Fitness) / spread)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, tokens, positives, negatives []string) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best regular expression so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate(tokens)
+		child.calcFitness(positives, negatives)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(tokens, positives, negatives []string) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(tokens, positives, negatives)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness > population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Token is an atom followed by a quantifier, like \d+ or a?
+type Token struct {
+	Atom       string
+	Quantifier string
+}
+
+// Organism is a regular expression. As it's built from tokens of the
+// grammar, and optionally anchored at the start and end, it's always valid
+type Organism struct {
+	Start   bool
+	DNA     []Token
+	End     bool
+	Correct int
+	Fitness float64
+}
+
+// a random token from the grammar
+func randomToken(tokens []string) Token {
+	return Token{
+		Atom:       tokens[rng.Intn(len(tokens))],
+		Quantifier: quantifiers[rng.Intn(len(quantifiers))],
+	}
+}
+
+// creates an organism with a few random tokens
+func createOrganism(tokens, positives, negatives []string) (organism Organism) {
+	organism = Organism{
+		Start: rng.Intn(2) == 0,
+		DNA:   make([]Token, 1+rng.Intn(5)),
+		End:   rng.Intn(2) == 0,
+	}
+	for i := range organism.DNA {
+		organism.DNA[i] = randomToken(tokens)
+	}
+	organism.calcFitness(positives, negatives)
+	return
+}
+
+// the regular expression the tokens spell out
+func (o *Organism) regexp() string {
+	var sb strings.Builder
+	if o.Start {
+		sb.WriteString("^")
+	}
+	for _, t := range o.DNA {
+		sb.WriteString(t.Atom + t.Quantifier)
+	}
+	if o.End {
+		sb.WriteString("$")
+	}
+	return sb.String()
+}
+
+// calculates the fitness of the Organism, which is the number of examples it
+// gets right, less a little for every character
+func (o *Organism) calcFitness(positives, negatives []string) {
+	expr := o.regexp()
+	o.Correct = 0
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		o.Fitness = 0
+		return
+	}
+	for _, p := range positives {
+		if re.MatchString(p) {
+			o.Correct++
+		}
+	}
+	for _, n := range negatives {
+		if !re.MatchString(n) {
+			o.Correct++
+		}
+	}
+	o.Fitness = float64(o.Correct) - Parsimony*float64(len(expr))
+}
+
+// crosses over 2 Organisms, cutting both at a token boundary
+func crossover(d1 Organism, d2 Organism) Organism {
+	mid1, mid2 := rng.Intn(len(d1.DNA)+1), rng.Intn(len(d2.DNA)+1)
+	child := Organism{
+		Start: d1.Start,
+		DNA:   make([]Token, 0, mid1+len(d2.DNA)-mid2),
+		End:   d2.End,
+	}
+	child.DNA = append(child.DNA, d1.DNA[:mid1]...)
+	child.DNA = append(child.DNA, d2.DNA[mid2:]...)
+	if len(child.DNA) > MaxTokens {
+		child.DNA = child.DNA[:MaxTokens]
+	}
+	return child
+}
+
+// mutate the Organism by changing, inserting or deleting tokens, or
+// flipping the anchors
+func (o *Organism) mutate(tokens []string) {
+	dna := make([]Token, 0, len(o.DNA)+1)
+	for _, t := range o.DNA {
+		if rng.Float64() >= MutationRate {
+			dna = append(dna, t)
+			continue
+		}
+		switch rng.Intn(4) {
+		case 0:
+			dna = append(dna, randomToken(tokens))
+		case 1:
+			t.Quantifier = quantifiers[rng.Intn(len(quantifiers))]
+			dna = append(dna, t)
+		case 2:
+			dna = append(dna, randomToken(tokens), t)
+		case 3:
+			// deleted, by not copying it over
+		}
+	}
+	if len(dna) == 0 {
+		dna = append(dna, randomToken(tokens))
+	}
+	if len(dna) > MaxTokens {
+		dna = dna[:MaxTokens]
+	}
+	o.DNA = dna
+	if rng.Float64() < MutationRate {
+		o.Start = !o.Start
+	}
+	if rng.Float64() < MutationRate {
+		o.End = !o.End
+	}
+}
@@ -0,0 +1,229 @@
+// Package remote works out the fitness of genomes on another machine, with
+// the EvaluateBatch call of the Evaluator gRPC service in evaluator.proto.
+// It speaks gRPC with the standard library, encoding the few messages of the
+// service by hand, so the demos don't need the gRPC and protobuf modules.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Timeout is how long to wait for a batch to be evaluated
+var Timeout = time.Minute
+
+// Genome is the DNA of an organism, real numbers or bytes
+type Genome struct {
+	Values []float64
+	Data   []byte
+}
+
+// Client calls an evaluator
+type Client struct {
+	// Addr is the host and port of the evaluator
+	Addr string
+	// TLS is whether to talk to the evaluator over TLS, otherwise it's
+	// HTTP/2 without encryption
+	TLS    bool
+	client *http.Client
+}
+
+// NewClient makes a client of the evaluator at the address, which is the host
+// and port, or a URL starting with https:// to use TLS
+func NewClient(addr string) *Client {
+	c := &Client{Addr: addr}
+	if u, err := url.Parse(addr); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		c.Addr, c.TLS = u.Host, u.Scheme == "https"
+	}
+	protocols := new(http.Protocols)
+	if c.TLS {
+		protocols.SetHTTP2(true)
+	} else {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	c.client = &http.Client{Transport: &http.Transport{
+		Protocols:       protocols,
+		TLSClientConfig: &tls.Config{NextProtos: []string{"h2"}},
+	}}
+	return c
+}
+
+// EvaluateBatch works out the fitness of every genome, in the same order
+func (c *Client) EvaluateBatch(genomes []Genome) ([]float64, error) {
+	scheme := "http"
+	if c.TLS {
+		scheme = "https"
+	}
+	// a gRPC message is a byte for whether it's compressed, its length and
+	// the message
+	msg := encodeRequest(genomes)
+	body := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(msg)))
+	body = append(body, msg...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", scheme+"://"+c.Addr+"/ga.Evaluator/EvaluateBatch", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc+proto")
+	req.Header.Set("TE", "trailers")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("evaluator answered %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// the status is in the trailers, or in the headers if there's no message
+	if err = status(resp.Trailer); err != nil {
+		return nil, err
+	}
+	if err = status(resp.Header); err != nil {
+		return nil, err
+	}
+	if len(data) < 5 {
+		return nil, fmt.Errorf("no message from evaluator")
+	}
+	if data[0] != 0 {
+		return nil, fmt.Errorf("cannot read compressed message")
+	}
+	n := binary.BigEndian.Uint32(data[1:5])
+	if int(n) > len(data)-5 {
+		return nil, fmt.Errorf("message from evaluator is cut short")
+	}
+	fitnesses, err := decodeResponse(data[5 : 5+n])
+	if err != nil {
+		return nil, err
+	}
+	if len(fitnesses) != len(genomes) {
+		return nil, fmt.Errorf("evaluator answered %d fitnesses for %d genomes", len(fitnesses), len(genomes))
+	}
+	return fitnesses, nil
+}
+
+// the error in the gRPC status of the header, if it isn't OK
+func status(h http.Header) error {
+	code := h.Get("Grpc-Status")
+	if code == "" || code == "0" {
+		return nil
+	}
+	message, err := url.PathUnescape(h.Get("Grpc-Message"))
+	if err != nil {
+		message = h.Get("Grpc-Message")
+	}
+	if n, err := strconv.Atoi(code); err == nil && n < len(codes) {
+		code = codes[n]
+	}
+	return fmt.Errorf("evaluator failed with %s: %s", code, message)
+}
+
+// the names of the gRPC status codes
+var codes = []string{"OK", "Canceled", "Unknown", "InvalidArgument",
+	"DeadlineExceeded", "NotFound", "AlreadyExists", "PermissionDenied",
+	"ResourceExhausted", "FailedPrecondition", "Aborted", "OutOfRange",
+	"Unimplemented", "Internal", "Unavailable", "DataLoss", "Unauthenticated"}
+
+// the wire types of protobuf fields
+const (
+	wireVarint = 0
+	wire64     = 1
+	wireBytes  = 2
+	wire32     = 5
+)
+
+// encode an EvaluateBatchRequest, with each genome as field 1
+func encodeRequest(genomes []Genome) []byte {
+	var msg []byte
+	for _, g := range genomes {
+		var genome []byte
+		if len(g.Values) > 0 {
+			// the values are packed, as repeated numbers are in proto3
+			values := make([]byte, 0, 8*len(g.Values))
+			for _, v := range g.Values {
+				values = binary.LittleEndian.AppendUint64(values, math.Float64bits(v))
+			}
+			genome = appendBytes(genome, 1, values)
+		}
+		if len(g.Data) > 0 {
+			genome = appendBytes(genome, 2, g.Data)
+		}
+		msg = appendBytes(msg, 1, genome)
+	}
+	return msg
+}
+
+// append a length delimited field
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|wireBytes))
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// decode an EvaluateBatchResponse, whose fitnesses are field 1, either packed
+// or one at a time, and skip any other fields
+func decodeResponse(msg []byte) (fitnesses []float64, err error) {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return nil, fmt.Errorf("cannot read field")
+		}
+		msg = msg[n:]
+		field, wire := key>>3, key&7
+		switch wire {
+		case wireVarint:
+			_, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return nil, fmt.Errorf("cannot read field %d", field)
+			}
+			msg = msg[n:]
+		case wire64:
+			if len(msg) < 8 {
+				return nil, fmt.Errorf("cannot read field %d", field)
+			}
+			if field == 1 {
+				fitnesses = append(fitnesses, math.Float64frombits(binary.LittleEndian.Uint64(msg)))
+			}
+			msg = msg[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || size > uint64(len(msg)-n) {
+				return nil, fmt.Errorf("cannot read field %d", field)
+			}
+			data := msg[n : n+int(size)]
+			msg = msg[n+int(size):]
+			if field != 1 {
+				continue
+			}
+			if len(data)%8 != 0 {
+				return nil, fmt.Errorf("cannot read packed fitnesses")
+			}
+			for i := 0; i < len(data); i += 8 {
+				fitnesses = append(fitnesses, math.Float64frombits(binary.LittleEndian.Uint64(data[i:])))
+			}
+		case wire32:
+			if len(msg) < 4 {
+				return nil, fmt.Errorf("cannot read field %d", field)
+			}
+			msg = msg[4:]
+		default:
+			return nil, fmt.Errorf("cannot read field %d of wire type %d", field, wire)
+		}
+	}
+	return
+}
@@ -0,0 +1,166 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// a genome of real numbers and one of bytes
+var genomes = []Genome{{Values: []float64{1.5}}, {Data: []byte("hi")}}
+
+// the EvaluateBatchRequest of the genomes, as protoc encodes it
+var request = []byte{
+	0x0a, 0x0a, // genome 1, 10 bytes
+	0x0a, 0x08, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, // values, packed, 1.5
+	0x0a, 0x04, // genome 2, 4 bytes
+	0x12, 0x02, 'h', 'i', // data
+}
+
+// EvaluateBatchResponses with the fitnesses 2 and 0.5
+var responses = map[string][]byte{
+	"packed": {
+		0x0a, 0x10, 0, 0, 0, 0, 0, 0, 0, 0x40, 0, 0, 0, 0, 0, 0, 0xe0, 0x3f,
+	},
+	"unpacked": {
+		0x09, 0, 0, 0, 0, 0, 0, 0, 0x40,
+		0x09, 0, 0, 0, 0, 0, 0, 0xe0, 0x3f,
+	},
+	"unknown fields": {
+		0x10, 0x96, 0x01, // a varint
+		0x09, 0, 0, 0, 0, 0, 0, 0, 0x40,
+		0x1a, 0x02, 'o', 'k', // some bytes
+		0x25, 1, 2, 3, 4, // a fixed32
+		0x09, 0, 0, 0, 0, 0, 0, 0xe0, 0x3f,
+	},
+}
+
+// a gRPC message of the bytes
+func frame(msg []byte) []byte {
+	b := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	return append(b, msg...)
+}
+
+func TestEncodeRequest(t *testing.T) {
+	if got := encodeRequest(genomes); !bytes.Equal(got, request) {
+		t.Errorf("request is % x, want % x", got, request)
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	for name, msg := range responses {
+		got, err := decodeResponse(msg)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(got) != 2 || got[0] != 2 || got[1] != 0.5 {
+			t.Errorf("%s: fitnesses are %v, want [2 0.5]", name, got)
+		}
+	}
+	for _, msg := range [][]byte{{0x09, 0, 0}, {0x0a, 0x08, 0, 0}, {0x0a, 0x03, 0, 0, 0}, {0x0b}} {
+		if _, err := decodeResponse(msg); err == nil {
+			t.Errorf("decoded % x", msg)
+		}
+	}
+}
+
+// an evaluator that checks it's sent the request over HTTP/2 and answers
+// with the response and the status in the trailers
+func evaluator(t *testing.T, response []byte, status, message string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("request is %s, want HTTP/2", r.Proto)
+		}
+		if r.URL.Path != "/ga.Evaluator/EvaluateBatch" {
+			t.Errorf("request is to %s", r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/grpc") {
+			t.Errorf("content type is %s", ct)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(body, frame(request)) {
+			t.Errorf("body is % x, want % x", body, frame(request))
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.Write(frame(response))
+		w.Header().Set("Grpc-Status", status)
+		w.Header().Set("Grpc-Message", message)
+	})
+}
+
+// a client of the server, which trusts its certificate
+func clientOf(s *httptest.Server) *Client {
+	c := NewClient(s.URL)
+	if s.TLS != nil {
+		roots := x509.NewCertPool()
+		roots.AddCert(s.Certificate())
+		c.client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+	}
+	return c
+}
+
+func TestEvaluateBatch(t *testing.T) {
+	servers := map[string]func(h http.Handler) *httptest.Server{
+		"tls": func(h http.Handler) *httptest.Server {
+			s := httptest.NewUnstartedServer(h)
+			s.EnableHTTP2 = true
+			s.StartTLS()
+			return s
+		},
+		"h2c": func(h http.Handler) *httptest.Server {
+			s := httptest.NewUnstartedServer(h)
+			s.Config.Protocols = new(http.Protocols)
+			s.Config.Protocols.SetUnencryptedHTTP2(true)
+			s.Start()
+			return s
+		},
+	}
+	for name, start := range servers {
+		t.Run(name, func(t *testing.T) {
+			s := start(evaluator(t, responses["packed"], "0", ""))
+			defer s.Close()
+			got, err := clientOf(s).EvaluateBatch(genomes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 2 || got[0] != 2 || got[1] != 0.5 {
+				t.Errorf("fitnesses are %v, want [2 0.5]", got)
+			}
+		})
+	}
+}
+
+func TestEvaluateBatchStatus(t *testing.T) {
+	s := httptest.NewUnstartedServer(evaluator(t, nil, "3", "bad%20genome"))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+	_, err := clientOf(s).EvaluateBatch(genomes)
+	if err == nil || !strings.Contains(err.Error(), "InvalidArgument: bad genome") {
+		t.Errorf("error is %v, want InvalidArgument: bad genome", err)
+	}
+}
+
+// an evaluator that answers with fewer fitnesses than genomes
+func TestEvaluateBatchShort(t *testing.T) {
+	// the packed fitnesses cut after the first
+	response := []byte{0x0a, 0x08, 0, 0, 0, 0, 0, 0, 0, 0x40}
+	s := httptest.NewUnstartedServer(evaluator(t, response, "0", ""))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+	if _, err := clientOf(s).EvaluateBatch(genomes); err == nil {
+		t.Error("no error for 1 fitness for 2 genomes")
+	}
+}
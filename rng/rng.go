@@ -0,0 +1,176 @@
+// Package rng is the source of random numbers for the demos. Unlike the
+// global source of math/rand, which can no longer be seeded, it can be seeded
+// or swapped for another source, so a run can be repeated exactly. The
+// numbers come from a PCG generator, whose state can be saved and restored,
+// so a run resumed from a checkpoint goes on exactly as if it never stopped.
+package rng
+
+import (
+	"errors"
+	"math/rand"
+	randv2 "math/rand/v2"
+	"sync"
+	"time"
+)
+
+var (
+	mu     sync.Mutex
+	pcg    = newPCG(time.Now().UTC().UnixNano())
+	source = rand.New(pcgSource{pcg})
+)
+
+// pcgSource lets math/rand take its numbers from a PCG generator
+type pcgSource struct {
+	*randv2.PCG
+}
+
+// Int63 returns a non-negative 63-bit number
+func (s pcgSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Seed starts the generator again from the seed
+func (s pcgSource) Seed(seed int64) {
+	s.PCG.Seed(uint64(seed), uint64(seed))
+}
+
+// a PCG generator started from the seed
+func newPCG(seed int64) *randv2.PCG {
+	return randv2.NewPCG(uint64(seed), uint64(seed))
+}
+
+// Seed starts the numbers again from the seed, so the same seed always gives
+// the same numbers in the same order
+func Seed(seed int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	pcg = newPCG(seed)
+	source = rand.New(pcgSource{pcg})
+}
+
+// Use takes the numbers from the source from now on. Its state can't be saved
+// unless it's seeded again with Seed
+func Use(s rand.Source) {
+	mu.Lock()
+	defer mu.Unlock()
+	pcg = nil
+	source = rand.New(s)
+}
+
+// State is the state of the numbers, to be saved with a checkpoint
+func State() ([]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if pcg == nil {
+		return nil, errors.New("cannot save the state of a source set with Use")
+	}
+	return pcg.MarshalBinary()
+}
+
+// Restore goes on with the numbers from the state, so they're the same
+// numbers in the same order as those that came after it was saved
+func Restore(state []byte) error {
+	p := new(randv2.PCG)
+	if err := p.UnmarshalBinary(state); err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	pcg = p
+	source = rand.New(pcgSource{pcg})
+	return nil
+}
+
+// Intn returns a number from 0 up to but not including n
+func Intn(n int) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return source.Intn(n)
+}
+
+// Int63 returns a non-negative 63-bit number
+func Int63() int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return source.Int63()
+}
+
+// Float64 returns a number from 0 up to but not including 1
+func Float64() float64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return source.Float64()
+}
+
+// NormFloat64 returns a normally distributed number with a mean of 0 and a
+// standard deviation of 1
+func NormFloat64() float64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return source.NormFloat64()
+}
+
+// Perm returns a random order of the numbers from 0 up to but not including n
+func Perm(n int) []int {
+	mu.Lock()
+	defer mu.Unlock()
+	return source.Perm(n)
+}
+
+// Shuffle puts n things in a random order, using swap to swap 2 of them
+func Shuffle(n int, swap func(i, j int)) {
+	mu.Lock()
+	defer mu.Unlock()
+	source.Shuffle(n, swap)
+}
+
+// Read fills p with random bytes. Unlike the Read of math/rand it keeps no
+// bytes back for the next call, so all the state is in the generator
+func Read(p []byte) (n int, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	for n < len(p) {
+		v := source.Uint64()
+		for i := 0; i < 8 && n < len(p); i++ {
+			p[n] = byte(v)
+			v >>= 8
+			n++
+		}
+	}
+	return
+}
+
+// Rand is a source of numbers of its own, for a single goroutine, so
+// goroutines breeding at the same time don't wait on each other for the lock
+// of the numbers of the package. It isn't safe to share between goroutines
+type Rand struct {
+	*rand.Rand
+	pcg *randv2.PCG
+}
+
+// NewRand is a Rand started from the 2 words of the seed of its PCG. With
+// them drawn from the numbers of the package, a run started with Seed gets
+// the same numbers every time
+func NewRand(seed1, seed2 uint64) *Rand {
+	p := randv2.NewPCG(seed1, seed2)
+	return &Rand{Rand: rand.New(pcgSource{p}), pcg: p}
+}
+
+// Reseed starts the numbers again from the 2 words of the seed. The second
+// word isn't a stream of its own, and seeds a few bits apart give numbers
+// that are alike at first, so to seed a Rand for each thing it's used for,
+// like each child of a generation, mix the number of the thing with
+// SplitMix64 first. Then the numbers of each thing are the same whichever
+// goroutine works it out
+func (r *Rand) Reseed(seed1, seed2 uint64) {
+	r.pcg.Seed(seed1, seed2)
+}
+
+// SplitMix64 scrambles x so that numbers close together, like 0, 1 and 2,
+// come out far apart, for seeding a Rand from each of them
+func SplitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
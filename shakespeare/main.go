@@ -1,10 +1,19 @@
 package main
 
 import (
-	"bytes"
+	"flag"
 	"fmt"
-	"math/rand"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sausheong/ga/rng"
 )
 
 // MutationRate is the rate of mutation
@@ -13,44 +22,241 @@ var MutationRate = 0.005
 // PopSize is the size of the population
 var PopSize = 500
 
+// Alphabet is the set of characters the genes are drawn from
+var Alphabet = asciiAlphabet()
+
+// PartialCredit gives some fitness for characters that are close to or of the
+// same kind as the target character, which smooths the fitness landscape
+var PartialCredit = false
+
+// CloseDistance is how far apart in code points characters can be to be close
+var CloseDistance = 3
+
+// Workers is the number of CPUs the population is bred on
+var Workers = runtime.NumCPU()
+
+// VariableLength lets the organisms be shorter or longer than the target, so
+// the length is evolved too
+var VariableLength = false
+
 func main() {
+	targetText := flag.String("target", "", "target text to evolve")
+	targetFile := flag.String("target-file", "", "file with the target text to evolve")
+	alphabet := flag.String("alphabet", "", "characters the genes are drawn from, by default printable ASCII and newline")
+	infer := flag.Bool("infer-alphabet", false, "draw genes only from the characters in the target, plus any given with -alphabet")
+	words := flag.Bool("words", false, "evolve whole words instead of characters")
+	dictionary := flag.String("dictionary", "", "file with extra words for -words, besides those in the target")
+	top := flag.Int("top", 0, "show the top N organisms and their fitness every generation")
+	flag.BoolVar(&PartialCredit, "partial-credit", PartialCredit, "give some fitness for characters close to or of the same kind as the target")
+	flag.BoolVar(&VariableLength, "variable-length", VariableLength, "evolve the length too, using the edit distance to the target as the fitness")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	memStats := flag.Bool("mem-stats", false, "print how much memory was allocated and how many times it was collected at the end")
+	flag.Parse()
+	text, err := getTarget(*targetText, *targetFile)
+	if err != nil {
+		fmt.Println("Cannot get target:", err)
+		return
+	}
+	target := []rune(text)
+	if len(target) == 0 {
+		fmt.Println("Cannot evolve an empty target")
+		return
+	}
+	// long or multi-line targets don't fit on a single, overwritten line
+	multiline := len(target) > 60 || strings.ContainsRune(text, '\n')
+	switch {
+	case *words:
+		extra, err := readDictionary(*dictionary)
+		if err != nil {
+			fmt.Println("Cannot read dictionary:", err)
+			return
+		}
+		target = useWords(text, extra)
+		fmt.Printf("Using a dictionary of %d words\n", len(Words))
+	case *infer:
+		// a smaller alphabet means far fewer wrong genes to try
+		Alphabet = []rune(*alphabet)
+		Alphabet = append(Alphabet, missingRunes(target, Alphabet)...)
+		fmt.Printf("Using an alphabet of %d characters from the target\n", len(Alphabet))
+	case *alphabet != "":
+		Alphabet = []rune(*alphabet)
+	}
+	// the target can't be evolved if it has characters the genes can't be
+	missing := missingRunes(target, Alphabet)
+	if len(missing) > 0 {
+		fmt.Printf("Adding %q to the alphabet\n", string(missing))
+		Alphabet = append(Alphabet, missing...)
+	}
+
 	start := time.Now()
-	rand.Seed(time.Now().UTC().UnixNano())
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
 
-	target := []byte("To be or not to be")
 	population := createPopulation(target)
+	spare := make([]Organism, len(population))
 
 	found := false
 	generation := 0
+	bestFitness := -1.0
+	width := 0
 	for !found {
 		generation++
 		bestOrganism := getBest(population)
-		fmt.Printf("\r generation: %d | %s | fitness: %2f", generation, string(bestOrganism.DNA), bestOrganism.Fitness)
+		best := express(bestOrganism.DNA)
+		switch {
+		case *top > 0:
+			if !multiline || bestOrganism.Fitness > bestFitness {
+				fmt.Print(topTable(generation, population, *top))
+			}
+		case !multiline:
+			// pad to the longest string so far, as the length can change
+			if n := utf8.RuneCountInString(best); n > width {
+				width = n
+			}
+			fmt.Printf("\r generation: %d | %-*s | fitness: %2f", generation, width, best, bestOrganism.Fitness)
+		case bestOrganism.Fitness > bestFitness:
+			fmt.Printf("generation: %d | fitness: %2f\n%s\n\n", generation, bestOrganism.Fitness, best)
+		}
+		bestFitness = bestOrganism.Fitness
 
-		if bytes.Compare(bestOrganism.DNA, target) == 0 {
+		if best == text {
 			found = true
 		} else {
 			maxFitness := bestOrganism.Fitness
-			pool := createPool(population, target, maxFitness)
-			population = naturalSelection(pool, population, target)
+			pool := createPool(population, maxFitness)
+			// the generation before this one is no longer needed, so the
+			// next is bred into its genes
+			population, spare = naturalSelection(pool, spare, target), population
 		}
 
 	}
 	elapsed := time.Since(start)
 	fmt.Printf("\nTime taken: %s\n", elapsed)
+	if *memStats {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		fmt.Printf("Allocated: %.1f MB in %d allocations, %d garbage collections\n", float64(m.TotalAlloc)/(1<<20), m.Mallocs, m.NumGC)
+	}
+}
+
+// a table of the n fittest organisms, which shows how diverse the population
+// still is
+func topTable(generation int, population []Organism, n int) string {
+	sorted := make([]Organism, len(population))
+	copy(sorted, population)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Fitness > sorted[j].Fitness
+	})
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "generation: %d\n", generation)
+	for i := 0; i < n; i++ {
+		text := strings.ReplaceAll(express(sorted[i].DNA), "\n", `\n`)
+		fmt.Fprintf(&sb, " %3d | %2f | %s\n", i+1, sorted[i].Fitness, text)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// get the target text from the flag, the file or from stdin when it's piped
+// in, in that order, defaulting to the famous quote
+func getTarget(text, filePath string) (string, error) {
+	switch {
+	case text != "":
+		return text, nil
+	case filePath != "":
+		data, err := os.ReadFile(filePath)
+		return strings.TrimRight(string(data), "\r\n"), err
+	}
+	stat, err := os.Stdin.Stat()
+	if err == nil && stat.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil || len(data) > 0 {
+			return strings.TrimRight(string(data), "\r\n"), err
+		}
+	}
+	return "To be or not to be", nil
+}
+
+// the printable ASCII characters and newline, so multi-line targets can be
+// evolved
+func asciiAlphabet() (alphabet []rune) {
+	for r := rune(32); r < 127; r++ {
+		alphabet = append(alphabet, r)
+	}
+	return append(alphabet, '\n')
+}
+
+// the characters in the target that aren't in the alphabet
+func missingRunes(target, alphabet []rune) (missing []rune) {
+	have := make(map[rune]bool)
+	for _, r := range alphabet {
+		have[r] = true
+	}
+	for _, r := range target {
+		if !have[r] {
+			missing = append(missing, r)
+			have[r] = true
+		}
+	}
+	return
+}
+
+// a random gene from the alphabet
+func randomGene(r *rng.Rand) rune {
+	return Alphabet[r.Intn(len(Alphabet))]
 }
 
 // Organism for this genetic algorithm
 type Organism struct {
-	DNA     []byte
+	DNA     []rune
 	Fitness float64
 }
 
+// the credit for a wrong character, more if it's close to the right one than
+// if it's just the same kind of character
+func credit(gene, want rune) float64 {
+	distance := gene - want
+	if distance < 0 {
+		distance = -distance
+	}
+	switch {
+	case int(distance) <= CloseDistance:
+		return 0.5
+	case charClass(gene) == charClass(want):
+		return 0.25
+	}
+	return 0
+}
+
+// the kind of character, letter, digit, space or punctuation and the rest
+func charClass(r rune) int {
+	switch {
+	case unicode.IsLetter(r):
+		return 0
+	case unicode.IsDigit(r):
+		return 1
+	case unicode.IsSpace(r):
+		return 2
+	}
+	return 3
+}
+
 // creates a Organism
-func createOrganism(target []byte) (organism Organism) {
-	ba := make([]byte, len(target))
-	for i := 0; i < len(target); i++ {
-		ba[i] = byte(rand.Intn(95) + 32)
+func createOrganism(r *rng.Rand, target []rune) (organism Organism) {
+	length := len(target)
+	if VariableLength {
+		// anything from a single character to twice the target
+		length = 1 + r.Intn(2*len(target))
+	}
+	ba := make([]rune, length)
+	for i := 0; i < length; i++ {
+		ba[i] = randomGene(r)
 	}
 	organism = Organism{
 		DNA:     ba,
@@ -61,65 +267,120 @@ func createOrganism(target []byte) (organism Organism) {
 }
 
 // creates the initial population
-func createPopulation(target []byte) (population []Organism) {
+func createPopulation(target []rune) (population []Organism) {
 	population = make([]Organism, PopSize)
-	for i := 0; i < PopSize; i++ {
-		population[i] = createOrganism(target)
-	}
+	parallel(PopSize, func(r *rng.Rand, i int) {
+		population[i] = createOrganism(r, target)
+	})
 	return
 }
 
 // calculates the fitness of the Organism
-func (d *Organism) calcFitness(target []byte) {
-	score := 0
+func (d *Organism) calcFitness(target []rune) {
+	if VariableLength {
+		longest := len(target)
+		if len(d.DNA) > longest {
+			longest = len(d.DNA)
+		}
+		d.Fitness = 1 - float64(levenshtein(d.DNA, target))/float64(longest)
+		return
+	}
+	score := 0.0
 	for i := 0; i < len(d.DNA); i++ {
 		if d.DNA[i] == target[i] {
 			score++
+		} else if PartialCredit && Words == nil {
+			score += credit(d.DNA[i], target[i])
 		}
 	}
-	d.Fitness = float64(score) / float64(len(d.DNA))
+	d.Fitness = score / float64(len(d.DNA))
 	return
 }
 
-// create the breeding pool that creates the next generation
-func createPool(population []Organism, target []byte, maxFitness float64) (pool []Organism) {
-	pool = make([]Organism, 0)
-	// create a pool for next generation
+// Pool is the breeding pool, where organisms are picked in proportion to
+// their fitness
+type Pool struct {
+	Organisms []Organism
+	// the running total of the weights, to search for a pick
+	cumulative []float64
+}
+
+// create the breeding pool that creates the next generation, weighting each
+// organism by its fitness instead of adding copies of it
+func createPool(population []Organism, maxFitness float64) (pool Pool) {
+	pool.Organisms = population
+	pool.cumulative = make([]float64, len(population))
+	total := 0.0
 	for i := 0; i < len(population); i++ {
-		population[i].calcFitness(target)
-		num := int((population[i].Fitness / maxFitness) * 100)
-		for n := 0; n < num; n++ {
-			pool = append(pool, population[i])
+		weight := 1.0
+		if maxFitness > 0 {
+			weight = population[i].Fitness / maxFitness
 		}
+		total += weight
+		pool.cumulative[i] = total
 	}
 	return
 }
 
-// perform natural selection to create the next generation
-func naturalSelection(pool []Organism, population []Organism, target []byte) []Organism {
-	next := make([]Organism, len(population))
+// pick an organism from the pool
+func (p Pool) pick(r *rng.Rand) Organism {
+	x := r.Float64() * p.cumulative[len(p.cumulative)-1]
+	i := sort.SearchFloat64s(p.cumulative, x)
+	if i == len(p.Organisms) {
+		i--
+	}
+	return p.Organisms[i]
+}
 
-	for i := 0; i < len(population); i++ {
-		r1, r2 := rand.Intn(len(pool)), rand.Intn(len(pool))
-		a := pool[r1]
-		b := pool[r2]
+// perform natural selection to create the next generation in place of the
+// organisms given, whose genes are reused rather than made anew for each child
+func naturalSelection(pool Pool, next []Organism, target []rune) []Organism {
+	parallel(len(next), func(r *rng.Rand, i int) {
+		a := pool.pick(r)
+		b := pool.pick(r)
 
-		child := crossover(a, b)
-		child.mutate()
+		child := crossover(r, a, b, next[i].DNA)
+		child.mutate(r)
 		child.calcFitness(target)
 
 		next[i] = child
-	}
+	})
 	return next
 }
 
-// crosses over 2 Organisms
-func crossover(d1 Organism, d2 Organism) Organism {
+// run f for 0 to n-1, split across the workers. Each i has random numbers of
+// its own, seeded by i mixed with a seed drawn once for all of them, so the
+// workers don't wait on each other for them and a seeded run is the same on
+// any number of CPUs
+func parallel(n int, f func(r *rng.Rand, i int)) {
+	seed := uint64(rng.Int63())
+	workers := Workers
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rng.NewRand(seed, 0)
+			for i := w; i < n; i += workers {
+				r.Reseed(seed, rng.SplitMix64(seed^uint64(i)))
+				f(r, i)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// crosses over 2 Organisms into the genes given, which are reused if they
+// have room
+func crossover(r *rng.Rand, d1 Organism, d2 Organism, dna []rune) Organism {
+	if len(d1.DNA) != len(d2.DNA) {
+		return crossoverVariable(r, d1, d2, dna)
+	}
 	child := Organism{
-		DNA:     make([]byte, len(d1.DNA)),
+		DNA:     reuse(dna, len(d1.DNA)),
 		Fitness: 0,
 	}
-	mid := rand.Intn(len(d1.DNA))
+	mid := r.Intn(len(d1.DNA))
 	for i := 0; i < len(d1.DNA); i++ {
 		if i > mid {
 			child.DNA[i] = d1.DNA[i]
@@ -131,13 +392,97 @@ func crossover(d1 Organism, d2 Organism) Organism {
 	return child
 }
 
+// the genes cut to the length, or new ones if they're too short
+func reuse(dna []rune, length int) []rune {
+	if cap(dna) < length {
+		return make([]rune, length)
+	}
+	return dna[:length]
+}
+
 // mutate the Organism
-func (d *Organism) mutate() {
+func (d *Organism) mutate(r *rng.Rand) {
+	if VariableLength {
+		d.mutateVariable(r)
+		return
+	}
+	for i := 0; i < len(d.DNA); i++ {
+		if r.Float64() < MutationRate {
+			d.DNA[i] = randomGene(r)
+		}
+	}
+}
+
+// crosses over 2 Organisms of different lengths into the genes given,
+// cutting both at the same relative position so the genes stay roughly aligned
+func crossoverVariable(r *rng.Rand, d1 Organism, d2 Organism, dna []rune) Organism {
+	cut := r.Float64()
+	mid1 := int(cut * float64(len(d1.DNA)))
+	mid2 := int(cut * float64(len(d2.DNA)))
+	dna = reuse(dna, 0)
+	dna = append(dna, d2.DNA[:mid2]...)
+	dna = append(dna, d1.DNA[mid1:]...)
+	if len(dna) == 0 {
+		dna = append(dna, randomGene(r))
+	}
+	return Organism{DNA: dna, Fitness: 0}
+}
+
+// mutate the Organism by substituting, inserting or deleting genes. Most
+// organisms aren't mutated at all, so the genes are only copied once the
+// first gene is
+func (d *Organism) mutateVariable(r *rng.Rand) {
+	var dna []rune
 	for i := 0; i < len(d.DNA); i++ {
-		if rand.Float64() < MutationRate {
-			d.DNA[i] = byte(rand.Intn(95) + 32)
+		if r.Float64() >= MutationRate {
+			if dna != nil {
+				dna = append(dna, d.DNA[i])
+			}
+			continue
+		}
+		if dna == nil {
+			dna = make([]rune, i, len(d.DNA)+1)
+			copy(dna, d.DNA[:i])
+		}
+		switch r.Intn(3) {
+		case 0:
+			dna = append(dna, randomGene(r))
+		case 1:
+			dna = append(dna, randomGene(r), d.DNA[i])
+		case 2:
+			// deleted, by not copying it over
+		}
+	}
+	if dna == nil {
+		return
+	}
+	// an organism always has at least one gene
+	if len(dna) == 0 {
+		dna = append(dna, randomGene(r))
+	}
+	d.DNA = dna
+}
+
+// the number of single character edits to turn a into b
+func levenshtein(a, b []rune) int {
+	// both rows in one allocation, as it's worked out for every child
+	rows := make([]int, 2*(len(b)+1))
+	prev, curr := rows[:len(b)+1], rows[len(b)+1:]
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
 		}
+		prev, curr = curr, prev
 	}
+	return prev[len(b)]
 }
 
 // Get the best organism
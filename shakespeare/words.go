@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Words is the dictionary the genes are drawn from when evolving words
+// instead of characters, each gene being the index of a word
+var Words []string
+
+// words, and the spaces between them, are the tokens of the text
+var tokens = regexp.MustCompile(`\s+|\S+`)
+
+// use words as genes, with a dictionary of the tokens in the target and any
+// extra words, returning the target as genes
+func useWords(text string, extra []string) (target []rune) {
+	index := make(map[string]rune)
+	Words = nil
+	add := func(word string) rune {
+		if i, ok := index[word]; ok {
+			return i
+		}
+		index[word] = rune(len(Words))
+		Words = append(Words, word)
+		return index[word]
+	}
+	for _, token := range tokens.FindAllString(text, -1) {
+		target = append(target, add(token))
+	}
+	for _, word := range extra {
+		add(word)
+	}
+	Alphabet = make([]rune, len(Words))
+	for i := range Words {
+		Alphabet[i] = rune(i)
+	}
+	return
+}
+
+// read the extra words for the dictionary from a file
+func readDictionary(filePath string) ([]string, error) {
+	if filePath == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// the text the genes spell out
+func express(dna []rune) string {
+	if Words == nil {
+		return string(dna)
+	}
+	var sb strings.Builder
+	for _, gene := range dna {
+		sb.WriteString(Words[gene])
+	}
+	return sb.String()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Point is a square on the board
+type Point struct {
+	X int
+	Y int
+}
+
+// the directions the snake can move in, clockwise from up
+var directions = []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+// Game is a game of snake on a square board. The snake dies if it runs into
+// a wall or itself, or if it goes too long without eating
+type Game struct {
+	Size   int
+	Snake  []Point // the head first
+	Dir    int     // index into directions
+	Food   Point
+	Score  int
+	Steps  int
+	Hunger int // steps since the snake last ate
+	Over   bool
+	rng    *rand.Rand
+}
+
+// a new game, with the snake in the middle of the board. The food is placed
+// using the seed, so the same seed always gives the same game for the same
+// moves
+func newGame(size int, seed int64) *Game {
+	mid := size / 2
+	g := &Game{
+		Size:  size,
+		Snake: []Point{{mid, mid}, {mid, mid + 1}, {mid, mid + 2}},
+		Dir:   0,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+	g.placeFood()
+	return g
+}
+
+// put the food on a random empty square
+func (g *Game) placeFood() {
+	for {
+		g.Food = Point{g.rng.Intn(g.Size), g.rng.Intn(g.Size)}
+		if !g.onSnake(g.Food) {
+			return
+		}
+	}
+}
+
+// true if the point is part of the snake
+func (g *Game) onSnake(p Point) bool {
+	for _, s := range g.Snake {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+// true if moving onto the point kills the snake
+func (g *Game) deadly(p Point) bool {
+	if p.X < 0 || p.Y < 0 || p.X >= g.Size || p.Y >= g.Size {
+		return true
+	}
+	// the tail moves out of the way
+	for _, s := range g.Snake[:len(g.Snake)-1] {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+// the square next to the head in the direction
+func (g *Game) ahead(dir int) Point {
+	d := directions[(dir+4)%4]
+	return Point{g.Snake[0].X + d.X, g.Snake[0].Y + d.Y}
+}
+
+// Step turns the snake left (-1), keeps it straight (0) or turns it right
+// (1), and moves it one square
+func (g *Game) Step(turn int) {
+	if g.Over {
+		return
+	}
+	g.Dir = (g.Dir + turn + 4) % 4
+	head := g.ahead(g.Dir)
+	g.Steps++
+	g.Hunger++
+	if g.deadly(head) || g.Hunger > g.Size*g.Size {
+		g.Over = true
+		return
+	}
+	g.Snake = append([]Point{head}, g.Snake...)
+	if head == g.Food {
+		g.Score++
+		g.Hunger = 0
+		if len(g.Snake) == g.Size*g.Size {
+			g.Over = true
+			return
+		}
+		g.placeFood()
+	} else {
+		g.Snake = g.Snake[:len(g.Snake)-1]
+	}
+}
+
+// what the snake sees, relative to where it's heading: whether there is
+// danger to the left, ahead or to the right, and whether the food is to the
+// left, ahead, to the right or behind
+func (g *Game) sense() []float64 {
+	in := make([]float64, 7)
+	for i, turn := range []int{-1, 0, 1} {
+		if g.deadly(g.ahead(g.Dir + turn)) {
+			in[i] = 1
+		}
+	}
+	dx, dy := g.Food.X-g.Snake[0].X, g.Food.Y-g.Snake[0].Y
+	for i, turn := range []int{-1, 0, 1, 2} {
+		d := directions[(g.Dir+turn+4)%4]
+		if d.X*dx+d.Y*dy > 0 {
+			in[3+i] = 1
+		}
+	}
+	return in
+}
+
+// the board drawn with characters
+func (g *Game) render() string {
+	var sb strings.Builder
+	sb.WriteString("+" + strings.Repeat("--", g.Size) + "+\n")
+	for y := 0; y < g.Size; y++ {
+		sb.WriteString("|")
+		for x := 0; x < g.Size; x++ {
+			p := Point{x, y}
+			switch {
+			case p == g.Snake[0]:
+				sb.WriteString("@@")
+			case g.onSnake(p):
+				sb.WriteString("[]")
+			case p == g.Food:
+				sb.WriteString("()")
+			default:
+				sb.WriteString("  ")
+			}
+		}
+		sb.WriteString("|\n")
+	}
+	sb.WriteString("+" + strings.Repeat("--", g.Size) + "+\n")
+	return sb.String()
+}
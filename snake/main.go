@@ -0,0 +1,265 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/nn"
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each weight
+var MutationRate = 0.1
+
+// MutationSize is the standard deviation of the change to a mutated weight
+var MutationSize = 0.5
+
+// PopSize is the size of the population
+var PopSize = 200
+
+// PoolSize is the max size of the pool
+var PoolSize = 20
+
+// Generations is the number of generations to evolve
+var Generations = 100
+
+// BoardSize is the width and height of the board
+var BoardSize = 12
+
+// Games is the number of games each snake plays, the fitness being the
+// average, so a lucky game doesn't count for too much
+var Games = 5
+
+// Random is whether the snakes play new random games every time they are
+// evaluated, instead of the same games, which makes the fitness noisy
+var Random = false
+
+// Reevaluate is how often, in generations, the best snake plays another
+// Games games when the games are random, so a snake that got lucky doesn't
+// stay on top for long
+var Reevaluate = 5
+
+// Hidden is the number of neurons in the hidden layer of the snake's brain
+var Hidden = 8
+
+// the network that picks the move, from what the snake sees to the turns
+var brain nn.Network
+
+func main() {
+	show := flag.Int("show", 50, "show the best snake playing every N generations, 0 to never show it")
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	flag.IntVar(&BoardSize, "size", BoardSize, "width and height of the board")
+	flag.IntVar(&Games, "games", Games, "number of games each snake plays")
+	flag.BoolVar(&Random, "random", Random, "play new random games every time instead of the same games")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	brain = nn.Network{Layers: []int{7, Hidden, 3}}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	population := createPopulation()
+
+	var bestOrganism Organism
+	for generation := 1; generation <= Generations; generation++ {
+		bestOrganism = getBest(population)
+		if Random && generation%Reevaluate == 0 {
+			bestOrganism.play(Games)
+		}
+		if *show > 0 && generation%*show == 0 {
+			replay(bestOrganism, generation)
+		}
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism)
+		if generation%10 == 0 {
+			sofar := time.Since(start)
+			fmt.Printf("Time taken so far: %s | generation: %d | average score: %.2f ± %.2f over %d games | pool size: %d\n", sofar, generation, bestOrganism.Fitness, bestOrganism.stdErr(), bestOrganism.Games, len(pool))
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nBest snake scored %.2f ± %.2f on average over %d games\n", bestOrganism.Fitness, bestOrganism.stdErr(), bestOrganism.Games)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// play a game with the snake, drawing the board after every move
+func replay(o Organism, generation int) {
+	g := newGame(BoardSize, rng.Int63())
+	for !g.Over {
+		g.Step(o.move(g))
+		fmt.Print("\x1b[H\x1b[2J")
+		fmt.Printf("generation: %d | score: %d | steps: %d\n", generation, g.Score, g.Steps)
+		fmt.Print(g.render())
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness > population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	spread := top[0].Fitness - top[PoolSize].Fitness
+	if spread == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with up to 100 copies of the best
+	for i := 0; i < len(top)-1; i++ {
+		num := int(100 * (top[i].Fitness - top[PoolSize].Fitness) / spread)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best snake so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.calcFitness()
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation() (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism()
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness > population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a snake, its DNA being the weights of its brain
+type Organism struct {
+	DNA     []float64
+	Fitness float64
+	// Games is the number of games the snake has played, and the sums of the
+	// scores and their squares are kept to tell how sure the fitness is
+	Games      int
+	sum        float64
+	sumSquares float64
+}
+
+// creates an organism with random weights between -1 and 1
+func createOrganism() (organism Organism) {
+	weights := make([]float64, brain.NumWeights())
+	for i := range weights {
+		weights[i] = rng.Float64()*2 - 1
+	}
+	organism = Organism{
+		DNA:     weights,
+		Fitness: 0,
+	}
+	organism.calcFitness()
+	return
+}
+
+// the turn the snake's brain picks, the one with the largest output
+func (o *Organism) move(g *Game) int {
+	out := brain.Forward(o.DNA, g.sense())
+	best := 0
+	for i := range out {
+		if out[i] > out[best] {
+			best = i
+		}
+	}
+	return best - 1
+}
+
+// calculates the fitness of the Organism, the average score over the same
+// games for every snake, or over random games, with a tiny bonus for staying
+// alive to break ties
+func (o *Organism) calcFitness() {
+	o.Games, o.sum, o.sumSquares = 0, 0, 0
+	o.play(Games)
+}
+
+// play more games, adding their scores to the average
+func (o *Organism) play(games int) {
+	for n := 1; n <= games; n++ {
+		seed := int64(n)
+		if Random {
+			seed = rng.Int63()
+		}
+		g := newGame(BoardSize, seed)
+		for !g.Over {
+			g.Step(o.move(g))
+		}
+		score := float64(g.Score) + float64(g.Steps)/1e6
+		o.sum += score
+		o.sumSquares += score * score
+	}
+	o.Games += games
+	o.Fitness = o.sum / float64(o.Games)
+}
+
+// the standard error of the average score, which is 0 for the same games
+// as they always give the same scores
+func (o *Organism) stdErr() float64 {
+	if !Random || o.Games < 2 {
+		return 0
+	}
+	variance := (o.sumSquares - o.sum*o.sum/float64(o.Games)) / float64(o.Games-1)
+	return math.Sqrt(math.Max(variance, 0) / float64(o.Games))
+}
+
+// crosses over 2 Organisms
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]float64, len(d1.DNA)),
+		Fitness: 0,
+	}
+	mid := rng.Intn(len(d1.DNA))
+	for i := 0; i < len(d1.DNA); i++ {
+		if i > mid {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism by nudging weights by a normally distributed amount
+func (o *Organism) mutate() {
+	for i := 0; i < len(o.DNA); i++ {
+		if rng.Float64() < MutationRate {
+			o.DNA[i] += rng.NormFloat64() * MutationSize
+		}
+	}
+}
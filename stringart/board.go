@@ -0,0 +1,99 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// Board is the circle of nails the thread is wound around, which renders the
+// lines of thread by adding up how dark they make each pixel
+type Board struct {
+	nails []image.Point
+	// the pixels on the line between each pair of nails, worked out the
+	// first time the line is drawn
+	lines map[[2]int][]int
+}
+
+// creates a board with the nails evenly spaced around the circle
+func newBoard() *Board {
+	board := &Board{
+		nails: make([]image.Point, Nails),
+		lines: make(map[[2]int][]int),
+	}
+	r := float64(Size-1) / 2
+	for i := range board.nails {
+		angle := 2 * math.Pi * float64(i) / float64(Nails)
+		board.nails[i] = image.Point{
+			X: int(math.Round(r + r*math.Cos(angle))),
+			Y: int(math.Round(r + r*math.Sin(angle))),
+		}
+	}
+	return board
+}
+
+// the offsets into the image of the pixels on the line between 2 nails
+func (b *Board) line(from, to int) []int {
+	if from > to {
+		from, to = to, from
+	}
+	key := [2]int{from, to}
+	if pixels, ok := b.lines[key]; ok {
+		return pixels
+	}
+	// Bresenham's line algorithm
+	p, q := b.nails[from], b.nails[to]
+	dx, dy := abs(q.X-p.X), -abs(q.Y-p.Y)
+	sx, sy := sign(q.X-p.X), sign(q.Y-p.Y)
+	e := dx + dy
+	pixels := make([]int, 0, dx-dy+1)
+	for x, y := p.X, p.Y; ; {
+		pixels = append(pixels, y*Size+x)
+		if x == q.X && y == q.Y {
+			break
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y += sy
+		}
+	}
+	b.lines[key] = pixels
+	return pixels
+}
+
+// render the thread going from nail to nail, every line making the pixels
+// it crosses darker, on white
+func (b *Board) render(nails []int) *image.Gray {
+	darkness := make([]int, Size*Size)
+	for i := 1; i < len(nails); i++ {
+		if nails[i] == nails[i-1] {
+			continue
+		}
+		for _, pixel := range b.line(nails[i-1], nails[i]) {
+			darkness[pixel] += Darkness
+		}
+	}
+	img := image.NewGray(image.Rect(0, 0, Size, Size))
+	for i, d := range darkness {
+		img.Pix[i] = uint8(255 - min(d, 255))
+	}
+	return img
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func sign(x int) int {
+	if x < 0 {
+		return -1
+	}
+	return 1
+}
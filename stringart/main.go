@@ -0,0 +1,262 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/preview"
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each nail in the thread
+var MutationRate = 0.005
+
+// PopSize is the size of the population
+var PopSize = 50
+
+// PoolSize is the max size of the pool
+var PoolSize = 10
+
+// Generations is the number of generations to evolve
+var Generations = 5000
+
+// Size is the width and height of the picture
+var Size = 150
+
+// Nails is the number of nails around the circle
+var Nails = 120
+
+// Lines is the number of times the thread goes across the circle
+var Lines = 600
+
+// Darkness is how much darker each line of thread makes a pixel, out of 255
+var Darkness = 40
+
+func main() {
+	targetFile := flag.String("target", "./ml.png", "target image")
+	flag.IntVar(&Nails, "nails", Nails, "number of nails around the circle")
+	flag.IntVar(&Lines, "lines", Lines, "number of lines of thread")
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	target := crop(load(*targetFile))
+	preview.Print(target)
+	board := newBoard()
+	population := createPopulation(board, target)
+
+	for generation := 1; generation <= Generations; generation++ {
+		bestOrganism := getBest(population)
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism, board, target)
+		if generation%100 == 0 || generation == Generations {
+			sofar := time.Since(start)
+			fmt.Printf("\nTime taken so far: %s | generation: %d | fitness: %d | pool size: %d", sofar, generation, bestOrganism.Fitness, len(pool))
+			img := board.render(bestOrganism.DNA)
+			save("./evolved.png", img)
+			fmt.Println()
+			preview.Print(img)
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// save the image
+func save(filePath string, img image.Image) {
+	imgFile, err := os.Create(filePath)
+	if err != nil {
+		fmt.Println("Cannot create file:", err)
+		return
+	}
+	defer imgFile.Close()
+	png.Encode(imgFile, img)
+}
+
+// load the image
+func load(filePath string) image.Image {
+	imgFile, err := os.Open(filePath)
+	if err != nil {
+		fmt.Println("Cannot read file:", err)
+		os.Exit(1)
+	}
+	defer imgFile.Close()
+
+	img, _, err := image.Decode(imgFile)
+	if err != nil {
+		fmt.Println("Cannot decode file:", err)
+		os.Exit(1)
+	}
+	return img
+}
+
+// crop the middle square of the image, scale it to Size and convert it to
+// grayscale, leaving the corners outside the circle white as the thread
+// never goes there
+func crop(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x0, y0 := bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2
+	gray := image.NewGray(image.Rect(0, 0, Size, Size))
+	for y := 0; y < Size; y++ {
+		for x := 0; x < Size; x++ {
+			if inCircle(x, y) {
+				gray.Set(x, y, img.At(x0+x*side/Size, y0+y*side/Size))
+			} else {
+				gray.Pix[y*gray.Stride+x] = 255
+			}
+		}
+	}
+	return gray
+}
+
+// whether the pixel is inside the circle of nails
+func inCircle(x, y int) bool {
+	r := float64(Size-1) / 2
+	dx, dy := float64(x)-r, float64(y)-r
+	return dx*dx+dy*dy <= r*r
+}
+
+// difference between 2 images
+func diff(a, b *image.Gray) int64 {
+	var d int64
+	for i := 0; i < len(a.Pix); i++ {
+		x := int64(a.Pix[i]) - int64(b.Pix[i])
+		d += x * x
+	}
+	return int64(math.Sqrt(float64(d)))
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with at most 100 copies of any organism
+	spread := top[PoolSize].Fitness - top[0].Fitness
+	for i := 0; i < len(top)-1; i++ {
+		num := (top[PoolSize].Fitness - top[i].Fitness) * 100 / spread
+		for n := int64(0); n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, board *Board, target *image.Gray) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best picture so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.calcFitness(board, target)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(board *Board, target *image.Gray) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(board, target)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a string art picture, the nails the thread goes to in turn
+type Organism struct {
+	DNA     []int
+	Fitness int64
+}
+
+// creates an organism with the thread going to random nails
+func createOrganism(board *Board, target *image.Gray) (organism Organism) {
+	organism = Organism{
+		DNA:     make([]int, Lines+1),
+		Fitness: 0,
+	}
+	for i := range organism.DNA {
+		organism.DNA[i] = rng.Intn(Nails)
+	}
+	organism.calcFitness(board, target)
+	return
+}
+
+// calculates the fitness of the Organism, comparing its picture with the
+// target
+func (o *Organism) calcFitness(board *Board, target *image.Gray) {
+	o.Fitness = diff(board.render(o.DNA), target)
+}
+
+// crosses over 2 Organisms
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]int, len(d1.DNA)),
+		Fitness: 0,
+	}
+	mid := rng.Intn(len(d1.DNA))
+	for i := 0; i < len(d1.DNA); i++ {
+		if i > mid {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism by taking the thread to other nails, either any nail or
+// one near the nail it went to before so the lines only move a little
+func (o *Organism) mutate() {
+	for i := range o.DNA {
+		if rng.Float64() >= MutationRate {
+			continue
+		}
+		if rng.Intn(2) == 0 {
+			o.DNA[i] = rng.Intn(Nails)
+		} else {
+			o.DNA[i] = (o.DNA[i] + rng.Intn(11) - 5 + Nails) % Nails
+		}
+	}
+}
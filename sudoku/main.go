@@ -0,0 +1,283 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each row
+var MutationRate = 0.1
+
+// PopSize is the size of the population
+var PopSize = 500
+
+// PoolSize is the max size of the pool
+var PoolSize = 50
+
+// Generations is the most generations to evolve before giving up
+var Generations = 100000
+
+// Restart is the number of generations without any improvement before the
+// population is started again from scratch, as it's stuck
+var Restart = 500
+
+// an easy puzzle, to use when none is given
+var defaultPuzzle = "53..7....6..195....98....6.8...6...34..8.3..17...2...6.6....28....419..5....8..79"
+
+func main() {
+	puzzleText := flag.String("puzzle", defaultPuzzle, "puzzle as 81 characters, row by row, with 0 or . for the blanks")
+	flag.IntVar(&Generations, "generations", Generations, "most generations to evolve before giving up")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	puzzle, err := parsePuzzle(*puzzleText)
+	if err != nil {
+		fmt.Println("Cannot read puzzle:", err)
+		return
+	}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	population := createPopulation(puzzle)
+
+	found := false
+	generation := 0
+	lastImproved, bestFitness := 0, -1
+	var bestOrganism Organism
+	for !found && generation < Generations {
+		generation++
+		bestOrganism = getBest(population)
+		if bestOrganism.Fitness == 0 {
+			found = true
+			break
+		}
+		if bestFitness < 0 || bestOrganism.Fitness < bestFitness {
+			lastImproved, bestFitness = generation, bestOrganism.Fitness
+		}
+		if generation-lastImproved > Restart {
+			fmt.Printf("\nStuck at %d conflicts, starting again", bestFitness)
+			population = createPopulation(puzzle)
+			lastImproved, bestFitness = generation, -1
+			continue
+		}
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism, puzzle)
+		if generation%100 == 0 {
+			sofar := time.Since(start)
+			fmt.Printf("\nTime taken so far: %s | generation: %d | conflicts: %d | pool size: %d", sofar, generation, bestOrganism.Fitness, len(pool))
+		}
+	}
+	elapsed := time.Since(start)
+	if found {
+		fmt.Printf("\nSolved in %d generations:\n", generation)
+	} else {
+		fmt.Printf("\nGave up after %d generations, the best has %d conflicts:\n", generation, bestOrganism.Fitness)
+	}
+	fmt.Print(bestOrganism.grid())
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// Puzzle is the sudoku grid, with 0 for the blanks
+type Puzzle [9][9]int
+
+// parse the puzzle from 81 characters, ignoring any spaces and new lines
+func parsePuzzle(text string) (puzzle Puzzle, err error) {
+	text = strings.Join(strings.Fields(text), "")
+	if len(text) != 81 {
+		return puzzle, fmt.Errorf("need 81 characters, not %d", len(text))
+	}
+	for i, c := range text {
+		switch {
+		case c >= '1' && c <= '9':
+			puzzle[i/9][i%9] = int(c - '0')
+		case c == '0' || c == '.':
+		default:
+			return puzzle, fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation
+	for i := 0; i < len(top)-1; i++ {
+		num := (top[PoolSize].Fitness - top[i].Fitness)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, puzzle Puzzle) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best grid so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate(puzzle)
+		child.calcFitness()
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(puzzle Puzzle) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(puzzle)
+	}
+	return
+}
+
+// Get the best organism, the one with the fewest conflicts
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a filled in grid. Every row is a permutation of 1 to 9 that
+// keeps the clues of the puzzle where they are, so only the columns and
+// boxes can have conflicts
+type Organism struct {
+	DNA     Puzzle
+	Fitness int
+}
+
+// creates an organism, filling in the blanks of every row with the numbers
+// missing from it in a random order
+func createOrganism(puzzle Puzzle) (organism Organism) {
+	organism = Organism{
+		DNA:     puzzle,
+		Fitness: 0,
+	}
+	for r := 0; r < 9; r++ {
+		used := [10]bool{}
+		for _, n := range puzzle[r] {
+			used[n] = true
+		}
+		missing := make([]int, 0)
+		for n := 1; n <= 9; n++ {
+			if !used[n] {
+				missing = append(missing, n)
+			}
+		}
+		rng.Shuffle(len(missing), func(i, j int) {
+			missing[i], missing[j] = missing[j], missing[i]
+		})
+		for c := 0; c < 9; c++ {
+			if organism.DNA[r][c] == 0 {
+				organism.DNA[r][c], missing = missing[0], missing[1:]
+			}
+		}
+	}
+	organism.calcFitness()
+	return
+}
+
+// calculates the fitness of the Organism, which is the number of repeated
+// numbers in the columns and the boxes
+func (o *Organism) calcFitness() {
+	conflicts := 0
+	for i := 0; i < 9; i++ {
+		col, box := [10]bool{}, [10]bool{}
+		for j := 0; j < 9; j++ {
+			if n := o.DNA[j][i]; col[n] {
+				conflicts++
+			} else {
+				col[n] = true
+			}
+			if n := o.DNA[i/3*3+j/3][i%3*3+j%3]; box[n] {
+				conflicts++
+			} else {
+				box[n] = true
+			}
+		}
+	}
+	o.Fitness = conflicts
+}
+
+// crosses over 2 Organisms, taking whole rows from either parent
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     d2.DNA,
+		Fitness: 0,
+	}
+	mid := rng.Intn(9)
+	for r := mid + 1; r < 9; r++ {
+		child.DNA[r] = d1.DNA[r]
+	}
+	return child
+}
+
+// mutate the Organism by swapping 2 numbers in a row that aren't clues
+func (o *Organism) mutate(puzzle Puzzle) {
+	for r := 0; r < 9; r++ {
+		if rng.Float64() >= MutationRate {
+			continue
+		}
+		blanks := make([]int, 0)
+		for c := 0; c < 9; c++ {
+			if puzzle[r][c] == 0 {
+				blanks = append(blanks, c)
+			}
+		}
+		if len(blanks) < 2 {
+			continue
+		}
+		i, j := blanks[rng.Intn(len(blanks))], blanks[rng.Intn(len(blanks))]
+		o.DNA[r][i], o.DNA[r][j] = o.DNA[r][j], o.DNA[r][i]
+	}
+}
+
+// the grid, with lines between the boxes
+func (o *Organism) grid() string {
+	var sb strings.Builder
+	for r := 0; r < 9; r++ {
+		if r > 0 && r%3 == 0 {
+			sb.WriteString("------+-------+------\n")
+		}
+		for c := 0; c < 9; c++ {
+			if c > 0 && c%3 == 0 {
+				sb.WriteString("| ")
+			}
+			fmt.Fprintf(&sb, "%d ", o.DNA[r][c])
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
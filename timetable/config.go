@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Room is where a course is held
+type Room struct {
+	Name     string `yaml:"name"`
+	Capacity int    `yaml:"capacity"`
+}
+
+// Course is something to be scheduled in a slot and a room
+type Course struct {
+	Name     string `yaml:"name"`
+	Teacher  string `yaml:"teacher"`
+	Students int    `yaml:"students"`
+	// Groups are the groups of students taking the course, which can't be in
+	// 2 places at once
+	Groups []string `yaml:"groups"`
+	// Avoid are the slots the course would rather not be in
+	Avoid []string `yaml:"avoid"`
+	// Prefer are the slots the course would rather be in, if any
+	Prefer []string `yaml:"prefer"`
+}
+
+// Config is the problem to solve, the slots, rooms and courses
+type Config struct {
+	Slots   []string `yaml:"slots"`
+	Rooms   []Room   `yaml:"rooms"`
+	Courses []Course `yaml:"courses"`
+}
+
+// read the problem from a YAML file
+func readConfig(filePath string) (config Config, err error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return
+	}
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return
+	}
+	if len(config.Slots) == 0 || len(config.Rooms) == 0 || len(config.Courses) < 2 {
+		err = fmt.Errorf("need some slots, rooms and at least 2 courses")
+		return
+	}
+	slots := make(map[string]bool)
+	for _, s := range config.Slots {
+		slots[s] = true
+	}
+	for _, c := range config.Courses {
+		for _, s := range append(c.Avoid, c.Prefer...) {
+			if !slots[s] {
+				err = fmt.Errorf("course %s has unknown slot %q", c.Name, s)
+				return
+			}
+		}
+	}
+	return
+}
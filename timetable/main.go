@@ -0,0 +1,275 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the rate of mutation of each course's slot or room
+var MutationRate = 0.05
+
+// PopSize is the size of the population
+var PopSize = 200
+
+// PoolSize is the max size of the pool
+var PoolSize = 30
+
+// Generations is the number of generations to evolve
+var Generations = 2000
+
+// HardPenalty is how much more breaking a hard constraint (a clash or a room
+// that's too small) costs than breaking a preference
+var HardPenalty = 100
+
+func main() {
+	configFile := flag.String("config", "./timetable.yaml", "YAML file with the slots, rooms and courses")
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	config, err := readConfig(*configFile)
+	if err != nil {
+		fmt.Println("Cannot read config:", err)
+		return
+	}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	population := createPopulation(config)
+
+	var bestOrganism Organism
+	for generation := 1; generation <= Generations; generation++ {
+		bestOrganism = getBest(population)
+		if bestOrganism.Fitness == 0 {
+			break
+		}
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism, config)
+		if generation%100 == 0 {
+			sofar := time.Since(start)
+			hard, soft := bestOrganism.violations(config)
+			fmt.Printf("Time taken so far: %s | generation: %d | fitness: %d | hard: %d | soft: %d\n", sofar, generation, bestOrganism.Fitness, len(hard), len(soft))
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Println()
+	fmt.Print(bestOrganism.timetable(config))
+	hard, soft := bestOrganism.violations(config)
+	if len(hard) == 0 && len(soft) == 0 {
+		fmt.Println("\nNo clashes and every preference met")
+	}
+	for _, v := range hard {
+		fmt.Println("Clash:", v)
+	}
+	for _, v := range soft {
+		fmt.Println("Preference:", v)
+	}
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	if top[len(top)-1].Fitness-top[0].Fitness == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with at most 100 copies of any organism
+	spread := top[PoolSize].Fitness - top[0].Fitness
+	for i := 0; i < len(top)-1; i++ {
+		num := (top[PoolSize].Fitness - top[i].Fitness) * 100 / spread
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, config Config) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best timetable so it's never lost
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate(config)
+		child.calcFitness(config)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(config Config) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(config)
+	}
+	return
+}
+
+// Get the best organism
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Booking is the slot and room a course is given, as indexes into the config
+type Booking struct {
+	Slot int
+	Room int
+}
+
+// Organism is a timetable, with a booking for each course
+type Organism struct {
+	DNA     []Booking
+	Fitness int
+}
+
+// creates an organism with each course in a random slot and room
+func createOrganism(config Config) (organism Organism) {
+	organism = Organism{
+		DNA:     make([]Booking, len(config.Courses)),
+		Fitness: 0,
+	}
+	for i := range organism.DNA {
+		organism.DNA[i] = Booking{rng.Intn(len(config.Slots)), rng.Intn(len(config.Rooms))}
+	}
+	organism.calcFitness(config)
+	return
+}
+
+// calculates the fitness of the Organism, which is the number of broken hard
+// constraints, weighted by HardPenalty, plus the number of broken preferences
+func (o *Organism) calcFitness(config Config) {
+	hard, soft := o.violations(config)
+	o.Fitness = HardPenalty*len(hard) + len(soft)
+}
+
+// the hard constraints and the preferences the timetable breaks
+func (o *Organism) violations(config Config) (hard []string, soft []string) {
+	for i, b := range o.DNA {
+		course := config.Courses[i]
+		slot, room := config.Slots[b.Slot], config.Rooms[b.Room]
+		if course.Students > room.Capacity {
+			hard = append(hard, fmt.Sprintf("%s has %d students but %s only holds %d", course.Name, course.Students, room.Name, room.Capacity))
+		}
+		// check each pair of courses only once
+		for j := i + 1; j < len(o.DNA); j++ {
+			if o.DNA[j].Slot != b.Slot {
+				continue
+			}
+			other := config.Courses[j]
+			if o.DNA[j].Room == b.Room {
+				hard = append(hard, fmt.Sprintf("%s and %s are both in %s at %s", course.Name, other.Name, room.Name, slot))
+			}
+			if course.Teacher != "" && course.Teacher == other.Teacher {
+				hard = append(hard, fmt.Sprintf("%s teaches %s and %s at %s", course.Teacher, course.Name, other.Name, slot))
+			}
+			for _, group := range shared(course.Groups, other.Groups) {
+				hard = append(hard, fmt.Sprintf("%s has %s and %s at %s", group, course.Name, other.Name, slot))
+			}
+		}
+		if contains(course.Avoid, slot) {
+			soft = append(soft, fmt.Sprintf("%s would rather not be at %s", course.Name, slot))
+		}
+		if len(course.Prefer) > 0 && !contains(course.Prefer, slot) {
+			soft = append(soft, fmt.Sprintf("%s would rather be at %s", course.Name, strings.Join(course.Prefer, " or ")))
+		}
+	}
+	return
+}
+
+// crosses over 2 Organisms, taking the bookings of the first courses from one
+// and the rest from the other
+func crossover(d1 Organism, d2 Organism) Organism {
+	child := Organism{
+		DNA:     make([]Booking, len(d1.DNA)),
+		Fitness: 0,
+	}
+	mid := rng.Intn(len(d1.DNA))
+	for i := 0; i < len(d1.DNA); i++ {
+		if i > mid {
+			child.DNA[i] = d1.DNA[i]
+		} else {
+			child.DNA[i] = d2.DNA[i]
+		}
+	}
+	return child
+}
+
+// mutate the Organism by moving courses to another slot or room
+func (o *Organism) mutate(config Config) {
+	for i := range o.DNA {
+		if rng.Float64() < MutationRate {
+			if rng.Intn(2) == 0 {
+				o.DNA[i].Slot = rng.Intn(len(config.Slots))
+			} else {
+				o.DNA[i].Room = rng.Intn(len(config.Rooms))
+			}
+		}
+	}
+}
+
+// the timetable as text, slot by slot
+func (o *Organism) timetable(config Config) string {
+	var sb strings.Builder
+	for s, slot := range config.Slots {
+		fmt.Fprintf(&sb, "%s\n", slot)
+		for r, room := range config.Rooms {
+			for i, b := range o.DNA {
+				if b.Slot == s && b.Room == r {
+					course := config.Courses[i]
+					fmt.Fprintf(&sb, "  %-10s %-20s %s\n", room.Name, course.Name, course.Teacher)
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+// the strings in both lists
+func shared(a, b []string) (both []string) {
+	for _, s := range a {
+		if contains(b, s) {
+			both = append(both, s)
+		}
+	}
+	return
+}
+
+// whether the list has the string
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
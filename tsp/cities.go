@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// read the cities from a TSPLIB .tsp file, using the NODE_COORD_SECTION, or
+// from a file with an x and y on every line, separated by spaces or a comma
+func readCities(filePath string) (cities []City, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	tsplib := strings.HasSuffix(strings.ToLower(filePath), ".tsp")
+	inCoords := !tsplib
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if tsplib && !inCoords {
+			// skip the specification part, like NAME and DIMENSION
+			if strings.HasPrefix(line, "NODE_COORD_SECTION") {
+				inCoords = true
+			}
+			continue
+		}
+		if line == "EOF" || strings.HasSuffix(line, "_SECTION") {
+			break
+		}
+		fields := strings.Fields(strings.ReplaceAll(line, ",", " "))
+		if tsplib && len(fields) > 0 {
+			// the first field is the number of the city
+			fields = fields[1:]
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("cannot parse %q", line)
+		}
+		var city City
+		if city.X, err = strconv.ParseFloat(fields[0], 64); err != nil {
+			return
+		}
+		if city.Y, err = strconv.ParseFloat(fields[1], 64); err != nil {
+			return
+		}
+		cities = append(cities, city)
+	}
+	err = scanner.Err()
+	return
+}
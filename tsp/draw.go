@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/llgcode/draw2d/draw2dkit"
+)
+
+// TourSize is the width and height of the tour image
+var TourSize = 500
+
+// draw the tour, scaled to fit the image
+func drawTour(cities []City, tour []int) *image.RGBA {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, c := range cities {
+		minX, maxX = math.Min(minX, c.X), math.Max(maxX, c.X)
+		minY, maxY = math.Min(minY, c.Y), math.Max(maxY, c.Y)
+	}
+	margin := 10.0
+	scale := (float64(TourSize) - 2*margin) / math.Max(math.Max(maxX-minX, maxY-minY), 1)
+	point := func(c City) (float64, float64) {
+		return margin + (c.X-minX)*scale, margin + (c.Y-minY)*scale
+	}
+
+	dest := image.NewRGBA(image.Rect(0, 0, TourSize, TourSize))
+	gc := draw2dimg.NewGraphicContext(dest)
+	gc.SetFillColor(color.White)
+	draw2dkit.Rectangle(gc, 0, 0, float64(TourSize), float64(TourSize))
+	gc.Fill()
+
+	gc.SetStrokeColor(color.RGBA{0, 0, 255, 255})
+	gc.SetLineWidth(1.5)
+	gc.MoveTo(point(cities[tour[0]]))
+	for _, i := range tour[1:] {
+		gc.LineTo(point(cities[i]))
+	}
+	gc.Close()
+	gc.Stroke()
+
+	gc.SetFillColor(color.RGBA{255, 0, 0, 255})
+	for _, c := range cities {
+		x, y := point(c)
+		draw2dkit.Circle(gc, x, y, 3)
+		gc.Fill()
+	}
+	return dest
+}
+
+// save the image
+func save(filePath string, img image.Image) {
+	imgFile, err := os.Create(filePath)
+	if err != nil {
+		fmt.Println("Cannot create file:", err)
+		return
+	}
+	defer imgFile.Close()
+	png.Encode(imgFile, img)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sausheong/ga/preview"
+	"github.com/sausheong/ga/rng"
+)
+
+// MutationRate is the chance that a tour is mutated
+var MutationRate = 0.3
+
+// PopSize is the size of the population
+var PopSize = 200
+
+// PoolSize is the max size of the pool
+var PoolSize = 40
+
+// Generations is the number of generations to evolve
+var Generations = 5000
+
+// Crossover is the crossover operator, either ox or pmx
+var Crossover = "ox"
+
+func main() {
+	citiesFile := flag.String("cities", "", "file with the city coordinates, either a TSPLIB .tsp file or an x and y on every line")
+	numCities := flag.Int("random", 50, "number of random cities when there is no file")
+	flag.IntVar(&Generations, "generations", Generations, "number of generations to evolve")
+	flag.StringVar(&Crossover, "crossover", Crossover, "crossover operator, ox (order) or pmx (partially mapped)")
+	seed := flag.Int64("seed", 0, "random seed to repeat a run, 0 for one from the time")
+	flag.Parse()
+	if Crossover != "ox" && Crossover != "pmx" {
+		fmt.Println("Cannot evolve tours: unknown crossover", Crossover)
+		return
+	}
+
+	start := time.Now()
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	rng.Seed(*seed)
+	fmt.Println("Seed:", *seed)
+	var cities []City
+	if *citiesFile == "" {
+		cities = randomCities(*numCities)
+	} else {
+		var err error
+		cities, err = readCities(*citiesFile)
+		if err != nil {
+			fmt.Println("Cannot read cities:", err)
+			return
+		}
+	}
+	if len(cities) < 4 {
+		fmt.Println("Cannot evolve tours: need at least 4 cities")
+		return
+	}
+	distances := distanceTable(cities)
+	population := createPopulation(distances)
+
+	for generation := 1; generation <= Generations; generation++ {
+		bestOrganism := getBest(population)
+		pool := createPool(population)
+		population = naturalSelection(pool, population, bestOrganism, distances)
+		if generation%100 == 0 || generation == Generations {
+			sofar := time.Since(start)
+			fmt.Printf("\nTime taken so far: %s | generation: %d | length: %.2f | pool size: %d", sofar, generation, bestOrganism.Fitness, len(pool))
+			img := drawTour(cities, bestOrganism.Tour)
+			save("./tour.png", img)
+			fmt.Println()
+			preview.Print(img)
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("\nTotal time taken: %s\n", elapsed)
+}
+
+// City is a point on the map
+type City struct {
+	X float64
+	Y float64
+}
+
+// random cities on a 1000 by 1000 map
+func randomCities(n int) []City {
+	cities := make([]City, n)
+	for i := range cities {
+		cities[i] = City{X: rng.Float64() * 1000, Y: rng.Float64() * 1000}
+	}
+	return cities
+}
+
+// the distances between every pair of cities, worked out once up front
+func distanceTable(cities []City) [][]float64 {
+	distances := make([][]float64, len(cities))
+	for i := range cities {
+		distances[i] = make([]float64, len(cities))
+		for j := range cities {
+			distances[i][j] = math.Hypot(cities[i].X-cities[j].X, cities[i].Y-cities[j].Y)
+		}
+	}
+	return distances
+}
+
+// create the reproduction pool that creates the next generation
+func createPool(population []Organism) (pool []Organism) {
+	pool = make([]Organism, 0)
+	// get top best fitting organisms
+	sort.SliceStable(population, func(i, j int) bool {
+		return population[i].Fitness < population[j].Fitness
+	})
+	top := population[0 : PoolSize+1]
+	// if there is no difference between the top organisms, the population is stable
+	// so we make the pool equal to the population
+	spread := top[PoolSize].Fitness - top[0].Fitness
+	if spread == 0 {
+		pool = population
+		return
+	}
+	// create a pool for next generation, with up to 100 copies of the shortest tour
+	for i := 0; i < len(top)-1; i++ {
+		num := int(100 * (top[PoolSize].Fitness - top[i].Fitness) / spread)
+		for n := 0; n < num; n++ {
+			pool = append(pool, top[i])
+		}
+	}
+	return
+}
+
+// perform natural selection to create the next generation
+func naturalSelection(pool []Organism, population []Organism, best Organism, distances [][]float64) []Organism {
+	next := make([]Organism, len(population))
+	// keep the best tour, as a good tour is easily lost to mutation
+	next[0] = best
+
+	for i := 1; i < len(population); i++ {
+		r1, r2 := rng.Intn(len(pool)), rng.Intn(len(pool))
+		a := pool[r1]
+		b := pool[r2]
+
+		child := crossover(a, b)
+		child.mutate()
+		child.calcFitness(distances)
+
+		next[i] = child
+	}
+	return next
+}
+
+// creates the initial population
+func createPopulation(distances [][]float64) (population []Organism) {
+	population = make([]Organism, PopSize)
+	for i := 0; i < PopSize; i++ {
+		population[i] = createOrganism(distances)
+	}
+	return
+}
+
+// Get the best organism, the one with the shortest tour
+func getBest(population []Organism) Organism {
+	index := 0
+	for i := 0; i < len(population); i++ {
+		if population[i].Fitness < population[index].Fitness {
+			index = i
+		}
+	}
+	return population[index]
+}
+
+// Organism is a tour, the order in which the cities are visited before
+// returning to the first one
+type Organism struct {
+	Tour    []int
+	Fitness float64
+}
+
+// creates an organism with a random tour
+func createOrganism(distances [][]float64) (organism Organism) {
+	organism = Organism{
+		Tour:    rng.Perm(len(distances)),
+		Fitness: 0,
+	}
+	organism.calcFitness(distances)
+	return
+}
+
+// calculates the fitness of the Organism, which is the length of the tour
+func (o *Organism) calcFitness(distances [][]float64) {
+	length := 0.0
+	for i := 0; i < len(o.Tour); i++ {
+		length += distances[o.Tour[i]][o.Tour[(i+1)%len(o.Tour)]]
+	}
+	o.Fitness = length
+}
+
+// crosses over 2 Organisms, keeping the tour a permutation of the cities
+func crossover(d1 Organism, d2 Organism) Organism {
+	// pick the slice of the first parent that is kept as it is
+	lo, hi := rng.Intn(len(d1.Tour)), rng.Intn(len(d1.Tour))
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	if Crossover == "pmx" {
+		return Organism{Tour: pmx(d1.Tour, d2.Tour, lo, hi)}
+	}
+	return Organism{Tour: ox(d1.Tour, d2.Tour, lo, hi)}
+}
+
+// order crossover, the cities outside the slice from the first parent are
+// filled in the order they come in the second parent
+func ox(p1, p2 []int, lo, hi int) []int {
+	child := make([]int, len(p1))
+	used := make([]bool, len(p1))
+	for i := lo; i <= hi; i++ {
+		child[i] = p1[i]
+		used[p1[i]] = true
+	}
+	j := (hi + 1) % len(p1)
+	for k := 0; k < len(p2); k++ {
+		city := p2[(hi+1+k)%len(p2)]
+		if !used[city] {
+			child[j] = city
+			j = (j + 1) % len(p1)
+		}
+	}
+	return child
+}
+
+// partially mapped crossover, the cities outside the slice from the first
+// parent come from the second parent, mapped through the slice when they
+// are already in it
+func pmx(p1, p2 []int, lo, hi int) []int {
+	child := make([]int, len(p1))
+	// where each city is in the first parent's slice
+	inSlice := make([]int, len(p1))
+	for i := range inSlice {
+		inSlice[i] = -1
+	}
+	for i := lo; i <= hi; i++ {
+		child[i] = p1[i]
+		inSlice[p1[i]] = i
+	}
+	for i := 0; i < len(p2); i++ {
+		if i >= lo && i <= hi {
+			continue
+		}
+		city := p2[i]
+		for inSlice[city] >= 0 {
+			city = p2[inSlice[city]]
+		}
+		child[i] = city
+	}
+	return child
+}
+
+// mutate the Organism by swapping 2 cities or reversing part of the tour
+func (o *Organism) mutate() {
+	if rng.Float64() >= MutationRate {
+		return
+	}
+	i, j := rng.Intn(len(o.Tour)), rng.Intn(len(o.Tour))
+	if rng.Intn(2) == 0 {
+		o.Tour[i], o.Tour[j] = o.Tour[j], o.Tour[i]
+		return
+	}
+	if i > j {
+		i, j = j, i
+	}
+	for ; i < j; i, j = i+1, j-1 {
+		o.Tour[i], o.Tour[j] = o.Tour[j], o.Tour[i]
+	}
+}
@@ -0,0 +1,274 @@
+// Package web serves a page for watching a run from a browser, showing the
+// current best image, a chart of the fitness and the parameters of the run.
+package web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// ThumbnailSize is the largest width or height of the thumbnails sent over
+// the WebSocket
+var ThumbnailSize = 100
+
+// MaxHistory is the most samples of the fitness kept for the chart. Once
+// there are more, every other one is dropped, so a run of days keeps the
+// whole of its curve, the older part in less detail, in a fixed amount of
+// memory and sends no more than this to each new browser
+var MaxHistory = 2000
+
+// Sample is the best fitness at a generation
+type Sample struct {
+	Generation int   `json:"generation"`
+	Fitness    int64 `json:"fitness"`
+}
+
+// Event is sent to subscribers for every generation, with type "generation",
+// and for every new best image, with type "image"
+type Event struct {
+	Type string `json:"type"`
+	Sample
+	Image string `json:"image,omitempty"`
+}
+
+// Monitor keeps the latest state of a run and pushes it to connected browsers
+// and WebSocket clients
+type Monitor struct {
+	mu          sync.Mutex
+	params      map[string]interface{}
+	history     []Sample
+	best        []byte
+	subscribers map[chan []byte]bool
+	sockets     map[chan []byte]bool
+}
+
+// NewMonitor creates a monitor for a run with the given parameters
+func NewMonitor(params map[string]interface{}) *Monitor {
+	return &Monitor{
+		params:      params,
+		subscribers: make(map[chan []byte]bool),
+		sockets:     make(map[chan []byte]bool),
+	}
+}
+
+// Record adds the best fitness of a generation
+func (m *Monitor) Record(generation int, fitness int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.record(Sample{Generation: generation, Fitness: fitness})
+}
+
+// Update records the best organism of a generation and sends its image out
+func (m *Monitor) Update(generation int, fitness int64, img image.Image) {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	var thumb bytes.Buffer
+	png.Encode(&thumb, thumbnail(img, ThumbnailSize))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sample := Sample{Generation: generation, Fitness: fitness}
+	m.record(sample)
+	m.best = buf.Bytes()
+	event, _ := json.Marshal(Event{"image", sample, base64.StdEncoding.EncodeToString(m.best)})
+	broadcast(m.subscribers, event)
+	event, _ = json.Marshal(Event{"image", sample, base64.StdEncoding.EncodeToString(thumb.Bytes())})
+	broadcast(m.sockets, event)
+}
+
+// add the sample to the history, unless the generation is already recorded,
+// and send it out
+func (m *Monitor) record(sample Sample) {
+	if n := len(m.history); n > 0 && m.history[n-1].Generation == sample.Generation {
+		return
+	}
+	m.history = append(m.history, sample)
+	if len(m.history) > MaxHistory {
+		m.history = thin(m.history)
+	}
+	event, _ := json.Marshal(Event{Type: "generation", Sample: sample})
+	broadcast(m.subscribers, event)
+	broadcast(m.sockets, event)
+}
+
+// every other sample, and always the latest
+func thin(samples []Sample) []Sample {
+	kept := samples[:0]
+	for i, s := range samples {
+		if i%2 == 0 || i == len(samples)-1 {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// send the event to each subscriber, dropping it for those too slow to keep up
+func broadcast(subscribers map[chan []byte]bool, event []byte) {
+	for sub := range subscribers {
+		select {
+		case sub <- event:
+		default:
+		}
+	}
+}
+
+// Handler returns the handler serving the page and its data
+func (m *Monitor) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", m.page)
+	mux.HandleFunc("/events", m.events)
+	mux.HandleFunc("/ws", m.socket)
+	mux.HandleFunc("/stats", m.stats)
+	mux.HandleFunc("/best.png", m.bestImage)
+	return mux
+}
+
+// Serve starts serving the page at the address in the background
+func (m *Monitor) Serve(addr string) {
+	go func() {
+		err := http.ListenAndServe(addr, m.Handler())
+		if err != nil {
+			fmt.Println("Cannot serve web UI:", err)
+		}
+	}()
+}
+
+// the page showing the run
+func (m *Monitor) page(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, page)
+}
+
+// the parameters, the fitness history so far and the most samples of it kept
+func (m *Monitor) stats(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Params     map[string]interface{} `json:"params"`
+		History    []Sample               `json:"history"`
+		MaxHistory int                    `json:"max_history"`
+	}{m.params, m.history, MaxHistory})
+}
+
+// the current best image
+func (m *Monitor) bestImage(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	best := m.best
+	m.mu.Unlock()
+	if best == nil {
+		http.Error(w, "no image yet", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(best)
+}
+
+// stream updates to the browser as server-sent events
+func (m *Monitor) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	sub := m.subscribe(m.subscribers)
+	defer m.unsubscribe(m.subscribers, sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+	for {
+		select {
+		case event := <-sub:
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+var upgrader = websocket.Upgrader{
+	// dashboards and notebooks subscribing to the run are served from elsewhere
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// stream events to a WebSocket client
+func (m *Monitor) socket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	sub := m.subscribe(m.sockets)
+	defer m.unsubscribe(m.sockets, sub)
+
+	// the client doesn't send anything, but reading notices when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case event := <-sub:
+			if err := conn.WriteMessage(websocket.TextMessage, event); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// add a subscriber for events
+func (m *Monitor) subscribe(subscribers map[chan []byte]bool) chan []byte {
+	sub := make(chan []byte, 64)
+	m.mu.Lock()
+	subscribers[sub] = true
+	m.mu.Unlock()
+	return sub
+}
+
+// remove a subscriber
+func (m *Monitor) unsubscribe(subscribers map[chan []byte]bool, sub chan []byte) {
+	m.mu.Lock()
+	delete(subscribers, sub)
+	m.mu.Unlock()
+}
+
+// scale the image down so it fits in a size x size square
+func thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	thumb := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		for x := 0; x < tw; x++ {
+			thumb.Set(x, y, img.At(bounds.Min.X+x*w/tw, bounds.Min.Y+y*h/th))
+		}
+	}
+	return thumb
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"time"
+)
+
+// NotifyTimeout is how long to wait for a webhook to take a message
+var NotifyTimeout = 10 * time.Second
+
+// Message is posted as JSON to a webhook. It has a text field so it can be
+// posted straight to a Slack incoming webhook, which ignores the rest
+type Message struct {
+	Text       string `json:"text"`
+	Generation int    `json:"generation"`
+	Fitness    int64  `json:"fitness"`
+	// Image is the best image as a base64 encoded PNG
+	Image string `json:"image,omitempty"`
+}
+
+// Notify posts the message to the webhook, with the image attached if there
+// is one
+func Notify(url string, msg Message, img image.Image) error {
+	if img != nil {
+		var buf bytes.Buffer
+		png.Encode(&buf, img)
+		msg.Image = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: NotifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package web
+
+// the page showing the evolving image, fitness chart and parameters
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Genetic algorithm</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+#best { image-rendering: pixelated; min-width: 200px; border: 1px solid #ccc; }
+.row { display: flex; gap: 2em; align-items: flex-start; }
+td { padding: 2px 8px; }
+</style>
+</head>
+<body>
+<h1>Genetic algorithm</h1>
+<p id="status">waiting for the first generation...</p>
+<div class="row">
+  <img id="best" src="/best.png" alt="best organism">
+  <canvas id="chart" width="600" height="300"></canvas>
+  <table id="params"></table>
+</div>
+<script>
+var samples = [];
+var maxSamples = 2000;
+
+function draw() {
+  var c = document.getElementById("chart"), ctx = c.getContext("2d");
+  ctx.clearRect(0, 0, c.width, c.height);
+  if (samples.length < 2) return;
+  var minG = samples[0].generation, maxG = samples[samples.length-1].generation;
+  var minF = Infinity, maxF = -Infinity;
+  samples.forEach(function(s) { minF = Math.min(minF, s.fitness); maxF = Math.max(maxF, s.fitness); });
+  if (maxF == minF) maxF = minF + 1;
+  ctx.beginPath();
+  samples.forEach(function(s, i) {
+    var x = (s.generation - minG) / (maxG - minG || 1) * (c.width - 1);
+    var y = (1 - (s.fitness - minF) / (maxF - minF)) * (c.height - 20) + 10;
+    if (i == 0) ctx.moveTo(x, y); else ctx.lineTo(x, y);
+  });
+  ctx.strokeStyle = "#c33";
+  ctx.stroke();
+  ctx.fillText(maxF, 2, 10);
+  ctx.fillText(minF, 2, c.height - 2);
+}
+
+function status(s) {
+  document.getElementById("status").textContent = "generation: " + s.generation + " | fitness: " + s.fitness;
+}
+
+fetch("/stats").then(function(r) { return r.json(); }).then(function(stats) {
+  var table = document.getElementById("params");
+  Object.keys(stats.params || {}).forEach(function(k) {
+    var row = table.insertRow();
+    row.insertCell().textContent = k;
+    row.insertCell().textContent = stats.params[k];
+  });
+  maxSamples = stats.max_history || maxSamples;
+  samples = (stats.history || []).concat(samples);
+  if (samples.length > 0) status(samples[samples.length-1]);
+  draw();
+});
+
+new EventSource("/events").onmessage = function(e) {
+  var s = JSON.parse(e.data);
+  if (s.type == "image") {
+    document.getElementById("best").src = "data:image/png;base64," + s.image;
+    return;
+  }
+  samples.push({generation: s.generation, fitness: s.fitness});
+  // thinned like the history of the monitor, so a long run doesn't slow the page
+  if (samples.length > maxSamples) {
+    samples = samples.filter(function(s, i) { return i % 2 == 0 || i == samples.length-1; });
+  }
+  status(s);
+  draw();
+};
+</script>
+</body>
+</html>
+`